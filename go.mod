@@ -6,17 +6,20 @@ require (
 	github.com/Bedrock-OSS/go-burrito v1.0.3
 	github.com/alessio/shellescape v1.4.1
 	github.com/arexon/fsnotify v0.0.0-20240929211932-1ebdc44d4bc2
+	github.com/coder/websocket v1.8.14
 	github.com/fatih/color v1.14.1
 	github.com/google/go-github/v39 v39.2.0
-	github.com/muhammadmuzzammil1998/jsonc v1.0.0
 	github.com/nightlyone/lockfile v1.0.0
 	github.com/otiai10/copy v1.7.0
 	github.com/paul-mannino/go-fuzzywuzzy v0.0.0-20200127021948-54652b135d0e
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
 	github.com/spf13/cobra v1.6.1
 	github.com/stirante/go-simple-eval v0.0.0-20230131075324-9ed520afbec1
+	github.com/tetratelabs/wazero v1.12.0
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
 	go.uber.org/zap v1.23.0
-	golang.org/x/mod v0.6.0
-	golang.org/x/sys v0.24.0
+	golang.org/x/mod v0.8.0
+	golang.org/x/sys v0.44.0
 )
 
 replace github.com/hashicorp/go-getter => github.com/arikkfir/go-getter v1.6.3-0.20220803164326-281b7670b734
@@ -38,5 +41,5 @@ require (
 	golang.org/x/crypto v0.1.0 // indirect
 	golang.org/x/exp v0.0.0-20230131013936-aae9b4e6329d // indirect
 	golang.org/x/sync v0.20.0 // indirect
-	golang.org/x/text v0.6.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -29,12 +29,56 @@ const regolithRunDesc = `
 This command runs Regolith using the profile specified in arguments. The profile must be defined in
 the "config.json" file of the project. If the profile name is not specified, Regolith uses "default"
 profile.
+
+The "--dry-run" flag runs the filters without exporting their results. Instead, Regolith prints the
+list of files that would be added, modified or deleted in each export target and in the data folder,
+and the files of the servers that would be changed to register the packs of the "server" export
+targets. Add the "--diff" flag to also print the unified diffs of the changed text files.
+
+The "--report" flag writes a JSON report of the run to the given path. The report contains the status
+of the run and of each filter, the durations of the filters and of the export, and the files added,
+modified and removed by each filter. The "--trace" flag writes the timings of the run in the Chrome
+trace event format, which can be opened in trace viewers like Perfetto or chrome://tracing. The
+filters that run in parallel (asyncFilters) are shown on separate tracks.
+
+Before exporting, Regolith checks if the files in the export targets were created by Regolith and
+weren't modified since the last export. Otherwise, it stops, so that the manual changes aren't lost.
+The "--backup" flag copies the modified files to the "backups" folder in the ".regolith" directory
+and continues with the export. The "--unsafe" flag skips the check and overwrites the files.
+
+In the projects with the format version 1.9.0 or newer, the filter settings and arguments, the export
+paths, the preShell and postShell commands and the manifest version can use "${expression}"
+templates, for example "${variables.version}". The values come from the "variables" of the project,
+which the profiles can override. Use "$${" to write a "${" that isn't evaluated, for example
+"$${HOME}" for a shell variable.
 `
 const regolithWatchDesc = `
 This command starts Regolith in the watch mode. This mode will trigger the "regolith run" command
 every time a change in files of the project's RP, BP, or data folders is detected. "regolith watch"
 uses the same syntax as "regolith run". You can use "regolith help run" to learn more about the
 command.
+
+The "--serve" flag starts a local HTTP server on the given address (for example ":8080"), so that
+other tools can react to the builds. The "/events" endpoint is a WebSocket stream of the build events
+in the JSON format (started, filterStarted, filterFinished, exported, finished and failed). The
+"/status" endpoint returns the result of the last finished build. If the address doesn't specify the
+host, the server only listens on localhost. The server only answers the requests sent to localhost
+or to the host of the given address, and the "/events" endpoint accepts the connections from web
+pages only if they're served from localhost.
+`
+const regolithPackageDesc = `
+This command runs Regolith using the profile specified in arguments and packs the created resource
+pack and behavior pack into archives that can be imported into Minecraft, instead of exporting them
+to the export targets of the profile. By default, both packs are stored in a single ".mcaddon" file.
+Use "--format mcpack" to create a separate ".mcpack" file for each pack.
+
+The "--output" flag sets the path template of the archive. The template can use the {name} (project
+name), {bpName}, {rpName} and {packName} (name of the pack stored in a ".mcpack" file) placeholders.
+The default paths are "build/{name}.mcaddon" and "build/{packName}.mcpack". The archives are
+reproducible - packing the same files always produces the same archive.
+
+The same archives can be created with "regolith run" by using the "mcaddon" or "mcpack" export
+targets. The template is then set with the "path" property of the export target.
 `
 const regolithApplyFilter = `
 This command runs single selected filter and applies its changes to the project source files. Running
@@ -48,6 +92,21 @@ The "regolith apply-filter" command runs on a copy of the project's files and co
 project only if the filter is successful. This means that if the filter fails, the project's files
 aren't modified.
 `
+const regolithTestFilterDesc = `
+This command tests a filter by running it on prepared input files and comparing the results with the
+expected output. The filter must be defined in the "filterDefinitions" section of the "config.json"
+file. The tests don't use or modify the project's RP, BP and data folders.
+
+The test cases are stored in the "filter_tests/<filter_name>" directory of the project, or in the
+directory set with the "--path" flag. Every subdirectory is a single test case with this layout:
+  - "input/RP", "input/BP", "input/data" - the files passed to the filter
+  - "expected/RP", "expected/BP", "expected/data" - the expected output of the filter
+  - "settings.json" - the optional settings of the filter
+
+Missing directories are treated as empty. The differences between the expected and the actual output
+are printed as unified diffs. Use the "--update" flag to replace the expected output of the failing
+test cases with the actual output of the filter. Additional arguments are passed to the filter.
+`
 const regolithInstallDesc = `
 Downloads and installs Regolith filters from the internet, and adds them to the "filterDefinitions"
 list of the project's "config.json" file. This command accepts multiple arguments, each of which
@@ -105,6 +164,31 @@ to make sure that your local copies of the filters is up to date.
 By default, the filters that are already installed with a correct version are ignored. You can
 change that by using the "--force" flag. "regolith install-all --force" forcefully reinstalls every
 filter on the project.
+
+The installed remote filters are recorded in the "regolith.lock" file, which stores the git commit
+and the hash of the files of every filter. Use the "--frozen" flag to install exactly the commits
+from the lock file instead of resolving the versions from the "config.json" file. In this mode the
+lock file is not modified and the command fails if the files of a filter don't match the lock file.
+This is useful on CI servers and for sharing the project with other people.
+`
+const regolithOutdatedDesc = `
+Prints the versions of the remote filters from the "filterDefinitions" list of the "config.json"
+file. For every filter it shows the installed version, the version from the "config.json" file and
+the latest version tag found on the repository of the filter. The command doesn't change the
+project. Use "regolith update" to update the filters.
+`
+const regolithUpdateDesc = `
+Updates the remote filters to the newest version tags found on their repositories. The command
+changes the "version" properties of the filters in the "filterDefinitions" list of the
+"config.json" file and reinstalls the updated filters. If no filters are specified, all of the
+remote filters of the project are updated.
+
+The "--minor" flag limits the updates to the versions with the same major version, and the
+"--patch" flag limits them to the versions with the same major and minor version. For example, a
+filter with version "1.2.3" can be updated to "1.2.5" with "--patch", to "1.4.0" with "--minor"
+and to "2.0.0" without any of the flags.
+
+The filters that use the "HEAD" or "latest" versions or a commit SHA are skipped.
 `
 const regolithInitDesc = `
 Initializes a new Regolith project in the current directory. The folder used for a new project must
@@ -160,6 +244,34 @@ The printing commands can take the --full flag to print configuration with the d
 included (if they're not defined in the config file). Without the flag, the undefined properties
 will be printed as null or empty list.
 `
+const regolithValidateDesc = `
+Validates the configuration files of the project against the JSON schemas bundled with Regolith and
+prints all of the problems at once, with the file, line and column of every invalid value. Normally,
+Regolith stops at the first problem. The command checks:
+- the "config.json" file of the project
+- the "filter.json" files of the installed remote filters
+- the user configuration
+- the settings of the remote filters that ship a JSON schema of their settings in a
+  "settings.schema.json" file next to their "filter.json" file
+
+The command returns an error if any problems are found.
+`
+
+const regolithDoctorDesc = `
+Checks the environment used by Regolith and prints a table with the results of the checks. Normally,
+Regolith performs these checks only when they're needed, so the problems are reported one at a time.
+The checks include:
+- the installation of Git and of a shell for running the shell filters
+- the runners of the filters (Python, Node.js, npm, Deno, Bun, Java, .NET, Nim and Nimble)
+- the com.mojang directories of the standard, preview and education builds of Minecraft
+- the location of the project
+- the permissions to write to the ".regolith" and the tmp directories
+- the state of the session lock of the project
+
+Every check has one of three results: "pass", "warn" or "fail". The warnings are used for the things
+that are required only by some projects, like the runners of the filters. The command returns an
+error if any of the checks fails. Use the "--json" flag to print the results as JSON.
+`
 const regolithUpdateResolversDesc = `
 Updates every resolver repository in the "resolvers" list in the user configuration. This command 
 is particularly useful if you are adding a new filter to the resolver file and want to ensure that 
@@ -247,7 +359,7 @@ func main() {
 	forceFilterRefreshDesc := "Force filter cache refresh."
 
 	// regolith install
-	var update, resolverRefresh, filterRefresh bool
+	var update, resolverRefresh, filterRefresh, frozen bool
 	cmdInstall := &cobra.Command{
 		Use:   "install [filters...]",
 		Short: "Downloads and installs filters from the internet and adds them to the filterDefinitions list",
@@ -283,7 +395,7 @@ func main() {
 		Long:  regolithInstallAllDesc,
 		Run: func(cmd *cobra.Command, _ []string) {
 			env, _ := cmd.Flags().GetString("env")
-			err = regolith.InstallAll(force, update, burrito.PrintStackTrace, filterRefresh, env)
+			err = regolith.InstallAll(force, update, burrito.PrintStackTrace, filterRefresh, frozen, env)
 		},
 	}
 	cmdInstallAll.Flags().BoolVarP(
@@ -292,10 +404,42 @@ func main() {
 		&update, "update", "u", false, "Updates the remote filters to the latest stable version available.")
 	cmdInstallAll.Flags().BoolVar(
 		&filterRefresh, "force-filter-refresh", false, forceFilterRefreshDesc)
+	cmdInstallAll.Flags().BoolVar(
+		&frozen, "frozen", false, "Installs the remote filters from the commits pinned in the \"regolith.lock\" file.")
 	subcommands = append(subcommands, cmdInstallAll)
 
+	// regolith outdated
+	cmdOutdated := &cobra.Command{
+		Use:   "outdated",
+		Short: "Lists the installed, configured and latest versions of the remote filters",
+		Long:  regolithOutdatedDesc,
+		Run: func(cmd *cobra.Command, _ []string) {
+			env, _ := cmd.Flags().GetString("env")
+			err = regolith.Outdated(burrito.PrintStackTrace, env)
+		},
+	}
+	subcommands = append(subcommands, cmdOutdated)
+
+	// regolith update
+	var patchOnly, minorOnly bool
+	cmdUpdate := &cobra.Command{
+		Use:   "update [filters...]",
+		Short: "Updates the remote filters to newer versions and changes their versions in config.json",
+		Long:  regolithUpdateDesc,
+		Run: func(cmd *cobra.Command, filters []string) {
+			env, _ := cmd.Flags().GetString("env")
+			err = regolith.Update(filters, patchOnly, minorOnly, burrito.PrintStackTrace, env)
+		},
+	}
+	cmdUpdate.Flags().BoolVar(
+		&patchOnly, "patch", false, "Updates only to the versions with the same major and minor version.")
+	cmdUpdate.Flags().BoolVar(
+		&minorOnly, "minor", false, "Updates only to the versions with the same major version.")
+	subcommands = append(subcommands, cmdUpdate)
+
 	// Messages for common flags in 'regolith run' and 'regolith watch'
 	unsafeDesc := "Disables file protection safety checks for faster exports."
+	backupDesc := "Backs up the exported files modified outside of Regolith instead of stopping the export."
 	symlinkExportDesc := "Creates links from the tmp directory to the export target so that files written to tmp are immediately reflected in the export location."
 	disableSizeTimeCheckDesc := "Disables the size and modification time check optimization for file exporting."
 
@@ -316,10 +460,24 @@ func main() {
 			unsafe, _ := cmd.Flags().GetBool("unsafe")
 			symlink, _ := cmd.Flags().GetBool("symlink-export")
 			disableStc, _ := cmd.Flags().GetBool("disable-size-time-check")
-			err = regolith.Run(profile, extraFilterArgs, burrito.PrintStackTrace, env, unsafe, symlink, disableStc)
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			diff, _ := cmd.Flags().GetBool("diff")
+			backup, _ := cmd.Flags().GetBool("backup")
+			report, _ := cmd.Flags().GetString("report")
+			trace, _ := cmd.Flags().GetString("trace")
+			if dryRun {
+				err = regolith.DryRun(profile, extraFilterArgs, burrito.PrintStackTrace, env, unsafe, disableStc, diff)
+				return
+			}
+			err = regolith.RunWithReport(profile, extraFilterArgs, burrito.PrintStackTrace, env, unsafe, symlink, disableStc, backup, report, trace)
 		},
 	}
 	cmdRun.Flags().Bool("unsafe", false, unsafeDesc)
+	cmdRun.Flags().Bool("backup", false, backupDesc)
+	cmdRun.Flags().Bool("dry-run", false, "Shows the changes that the export would make without exporting the files")
+	cmdRun.Flags().Bool("diff", false, "Shows the diffs of the changed text files in the dry run mode")
+	cmdRun.Flags().String("report", "", "Writes the JSON report of the run with the timings and the changed files of the filters to the path")
+	cmdRun.Flags().String("trace", "", "Writes the timings of the run to the path in the Chrome trace event format")
 	cmdRun.Flags().BoolVar(&symlinkExport, "symlink-export", false, symlinkExportDesc)
 	cmdRun.Flags().BoolVar(&disableSizeTimeCheck, "disable-size-time-check", false, disableSizeTimeCheckDesc)
 	subcommands = append(subcommands, cmdRun)
@@ -340,14 +498,38 @@ func main() {
 			unsafe, _ := cmd.Flags().GetBool("unsafe")
 			symlink, _ := cmd.Flags().GetBool("symlink-export")
 			disableStc, _ := cmd.Flags().GetBool("disable-size-time-check")
-			err = regolith.Watch(profile, extraFilterArgs, burrito.PrintStackTrace, env, unsafe, symlink, disableStc)
+			backup, _ := cmd.Flags().GetBool("backup")
+			serve, _ := cmd.Flags().GetString("serve")
+			err = regolith.Watch(profile, extraFilterArgs, burrito.PrintStackTrace, env, unsafe, symlink, disableStc, backup, serve)
 		},
 	}
 	cmdWatch.Flags().Bool("unsafe", false, unsafeDesc)
+	cmdWatch.Flags().Bool("backup", false, backupDesc)
+	cmdWatch.Flags().String("serve", "", "Serves the build events on the address, for example \":8080\"")
 	cmdWatch.Flags().BoolVar(&symlinkExport, "symlink-export", false, symlinkExportDesc)
 	cmdWatch.Flags().BoolVar(&disableSizeTimeCheck, "disable-size-time-check", false, disableSizeTimeCheckDesc)
 	subcommands = append(subcommands, cmdWatch)
 
+	// regolith package
+	cmdPackage := &cobra.Command{
+		Use:   "package [profile_name]",
+		Short: "Runs Regolith using specified profile and packs the result into .mcaddon or .mcpack files",
+		Long:  regolithPackageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			var profile string
+			if len(args) != 0 {
+				profile = args[0]
+			}
+			env, _ := cmd.Flags().GetString("env")
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+			err = regolith.Package(profile, format, output, burrito.PrintStackTrace, env)
+		},
+	}
+	cmdPackage.Flags().String("format", "mcaddon", "The format of the archives. Valid values are: mcaddon, mcpack.")
+	cmdPackage.Flags().StringP("output", "o", "", "The path template of the created archives.")
+	subcommands = append(subcommands, cmdPackage)
+
 	// regolith apply-filter
 	cmdApplyFilter := &cobra.Command{
 		Use:   "apply-filter <filter_name> [filter_args...]",
@@ -366,6 +548,28 @@ func main() {
 	}
 	subcommands = append(subcommands, cmdApplyFilter)
 
+	// regolith test-filter
+	cmdTestFilter := &cobra.Command{
+		Use:   "test-filter <filter_name> [filter_args...]",
+		Short: "Runs the test cases of selected filter and compares the results with the expected output",
+		Long:  regolithTestFilterDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				cmd.Help()
+				return
+			}
+			filter := args[0]
+			filterArgs := args[1:] // First arg is the filter name
+			env, _ := cmd.Flags().GetString("env")
+			path, _ := cmd.Flags().GetString("path")
+			update, _ := cmd.Flags().GetBool("update")
+			err = regolith.TestFilter(filter, filterArgs, path, update, burrito.PrintStackTrace, env)
+		},
+	}
+	cmdTestFilter.Flags().String("path", "", "Path to the directory with the test cases of the filter")
+	cmdTestFilter.Flags().Bool("update", false, "Replaces the expected output of the failing test cases with the actual output")
+	subcommands = append(subcommands, cmdTestFilter)
+
 	// regolith config
 	cmdConfig := &cobra.Command{
 		Use:   "config [key] [value]",
@@ -405,6 +609,33 @@ func main() {
 			"the current project")
 	subcommands = append(subcommands, cmdClean)
 
+	// regolith validate
+	cmdValidate := &cobra.Command{
+		Use:   "validate",
+		Short: "Validates the configuration files of the project",
+		Long:  regolithValidateDesc,
+		Run: func(cmd *cobra.Command, _ []string) {
+			env, _ := cmd.Flags().GetString("env")
+			err = regolith.Validate(burrito.PrintStackTrace, env)
+		},
+	}
+	subcommands = append(subcommands, cmdValidate)
+
+	// regolith doctor
+	var jsonOutput bool
+	cmdDoctor := &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the environment used by Regolith",
+		Long:  regolithDoctorDesc,
+		Run: func(cmd *cobra.Command, _ []string) {
+			env, _ := cmd.Flags().GetString("env")
+			err = regolith.Doctor(jsonOutput, burrito.PrintStackTrace, env)
+		},
+	}
+	cmdDoctor.Flags().BoolVar(
+		&jsonOutput, "json", false, "Prints the results of the checks as JSON.")
+	subcommands = append(subcommands, cmdDoctor)
+
 	// regolith update-resolvers
 	cmdUpdateResolvers := &cobra.Command{
 		Use:   "update-resolvers",
@@ -5,6 +5,8 @@ package regolith
 
 import (
 	"os"
+	"os/exec"
+	"syscall"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
 )
@@ -89,3 +91,19 @@ func FindEducationDir() (string, error) {
 func CheckSuspiciousLocation() error {
 	return nil
 }
+
+// prepareProcessTree configures the command to start the process in a new
+// process group, so that it can be killed with all of its child processes
+// using killProcessTree.
+func prepareProcessTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessTree kills the process started by the command and all of its
+// child processes. The command must be prepared with prepareProcessTree.
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -5,7 +5,9 @@ package regolith
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
@@ -244,3 +246,23 @@ func isPathWithinDirectory(path string, dir string) bool {
 	}
 	return !strings.HasPrefix(rel, "..")
 }
+
+// prepareProcessTree configures the command, so that it can be killed with
+// all of its child processes using killProcessTree. On Windows, the process
+// tree is found by taskkill, so no preparation is needed.
+func prepareProcessTree(cmd *exec.Cmd) {}
+
+// killProcessTree kills the process started by the command and all of its
+// child processes.
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := exec.Command(
+		"taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	if err != nil {
+		// Fall back to killing only the main process
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -8,7 +8,13 @@ import (
 	"golang.org/x/mod/semver"
 )
 
-const latestCompatibleVersion = "1.8.0"
+const latestCompatibleVersion = "1.9.0"
+
+// templatesFormatVersion is the first format version in which the
+// "${expression}" templates are evaluated. The older projects can use "${"
+// in their filter arguments, settings and shell commands for other purposes
+// (e.g. "${VAR}" in bash), so their values are used unchanged.
+const templatesFormatVersion = "1.9.0"
 
 const StandardLibraryUrl = "github.com/Bedrock-OSS/regolith-filters"
 const ConfigFilePath = "config.json"
@@ -27,15 +33,17 @@ type Config struct {
 // for a profile, which denotes where compiled files will go.
 // When editing, adjust ExportTargetFromObject function as well.
 type ExportTarget struct {
-	Target    string `json:"target,omitempty"` // The mode of exporting. "develop" or "exact"
-	RpPath    string `json:"rpPath,omitempty"` // Relative or absolute path to resource pack for "exact" export target
-	BpPath    string `json:"bpPath,omitempty"` // Relative or absolute path to resource pack for "exact" export target
-	RpName    string `json:"rpName,omitempty"`
-	BpName    string `json:"bpName,omitempty"`
-	WorldName string `json:"worldName,omitempty"`
-	WorldPath string `json:"worldPath,omitempty"`
-	ReadOnly  bool   `json:"readOnly"`        // Whether the exported files should be read-only
-	Build     string `json:"build,omitempty"` // The type of Minecraft build for the 'develop'
+	Target     string `json:"target,omitempty"` // The mode of exporting. "develop" or "exact"
+	RpPath     string `json:"rpPath,omitempty"` // Relative or absolute path to resource pack for "exact" export target
+	BpPath     string `json:"bpPath,omitempty"` // Relative or absolute path to resource pack for "exact" export target
+	RpName     string `json:"rpName,omitempty"`
+	BpName     string `json:"bpName,omitempty"`
+	WorldName  string `json:"worldName,omitempty"`
+	WorldPath  string `json:"worldPath,omitempty"`
+	ServerPath string `json:"serverPath,omitempty"` // Path to the Bedrock dedicated server for "server" export target
+	ReadOnly   bool   `json:"readOnly"`             // Whether the exported files should be read-only
+	Build      string `json:"build,omitempty"`      // The type of Minecraft build for the 'develop'
+	Path       string `json:"path,omitempty"`       // Path template of the archive for "mcpack" and "mcaddon" export targets
 }
 
 // ExportTargets is the config representation of a profile's "export" value.
@@ -84,9 +92,25 @@ type RegolithProject struct {
 	DataPath          string                     `json:"dataPath,omitempty"`
 	WatchPaths        []string                   `json:"watchPaths,omitempty"`
 	FormatVersion     string                     `json:"formatVersion,omitempty"`
+	// Variables are the values available in the expressions as the
+	// "variables" object. The profiles can override them.
+	Variables map[string]any `json:"variables,omitempty"`
+	// Watch is the configuration of the watch mode.
+	Watch WatchConfig `json:"watch,omitzero"`
 }
 
-// ConfigFromObject creates a "Config" object from map[string]interface{}
+// configFromFileObject creates a "Config" object from the map loaded from
+// the config file at the path (see LoadConfigAsMap). The errors are extended
+// with the location of the invalid value in the file.
+func configFromFileObject(obj map[string]any, path string) (*Config, error) {
+	result, err := ConfigFromObject(obj)
+	if err != nil {
+		return nil, locateJsonError(err, path)
+	}
+	return result, nil
+}
+
+// ConfigFromObject creates a "Config" object from map[string]interface{}.
 func ConfigFromObject(obj map[string]any) (*Config, error) {
 	result := &Config{}
 	// Name
@@ -197,6 +221,29 @@ func RegolithProjectFromObject(
 			}
 		}
 	}
+	// Watch
+	if watch, ok := obj["watch"]; ok {
+		watch, ok := watch.(map[string]any)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPropertyTypeError, "watch", "object")
+		}
+		watchConfig, err := WatchConfigFromObject(watch)
+		if err != nil {
+			return result, burrito.WrapErrorf(
+				err, jsonPropertyParseError, "watch")
+		}
+		result.Watch = watchConfig
+	}
+	// Variables
+	if variables, ok := obj["variables"]; ok {
+		variables, ok := variables.(map[string]any)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPropertyTypeError, "variables", "object")
+		}
+		result.Variables = variables
+	}
 	// Filter definitions
 	filterDefinitions, ok := obj["filterDefinitions"].(map[string]any)
 	if ok { // filter definitions are optional
@@ -204,14 +251,15 @@ func RegolithProjectFromObject(
 			filterDefinitionMap, ok := filterDefinition.(map[string]any)
 			if !ok {
 				return result, burrito.WrappedErrorf(
-					jsonPropertyTypeError, "filterDefinitions",
-					"object")
+					jsonPropertyTypeError,
+					"filterDefinitions->"+filterDefinitionName, "object")
 			}
 			filterInstaller, err := FilterInstallerFromObject(
 				filterDefinitionName, filterDefinitionName, filterDefinitionMap)
 			if err != nil {
 				return result, burrito.WrapErrorf(
-					err, jsonPropertyParseError, "filterDefinitions")
+					err, jsonPropertyParseError,
+					"filterDefinitions->"+filterDefinitionName)
 			}
 			result.FilterDefinitions[filterDefinitionName] = filterInstaller
 		}
@@ -229,7 +277,7 @@ func RegolithProjectFromObject(
 				"profiles->"+profileName, "object")
 		}
 		profileValue, err := ProfileFromObject(
-			profileMap, result.FilterDefinitions)
+			profileMap, result.FilterDefinitions, profiles)
 		if err != nil {
 			return result, burrito.WrapErrorf(
 				err, jsonPropertyParseError, "profiles->"+profileName)
@@ -308,11 +356,17 @@ func ExportTargetFromObject(obj map[string]any) (ExportTarget, error) {
 	// WorldPath - can be empty
 	worldPath, _ := obj["worldPath"].(string)
 	result.WorldPath = worldPath
+	// ServerPath - can be empty
+	serverPath, _ := obj["serverPath"].(string)
+	result.ServerPath = serverPath
 	// ReadOnly - can be empty
 	readOnly, _ := obj["readOnly"].(bool)
 	result.ReadOnly = readOnly
 	// Build - can be empty
 	build, _ := obj["build"].(string)
 	result.Build = build
+	// Path - can be empty
+	path, _ := obj["path"].(string)
+	result.Path = path
 	return result, nil
 }
@@ -4,8 +4,6 @@ import (
 	"os"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
-
-	"github.com/muhammadmuzzammil1998/jsonc"
 )
 
 // Functions for accessing information from the config file without parsing it
@@ -14,7 +12,8 @@ import (
 // "regolith install" and for accessing the config information when the file
 // might have some errors.
 
-// LoadConfigAsMap loads the config.json file as map[string]interface{}
+// LoadConfigAsMap loads the config.json file as map[string]interface{}. The
+// file can contain comments and trailing commas.
 func LoadConfigAsMap() (map[string]any, error) {
 	err := CheckSuspiciousLocation()
 	if err != nil {
@@ -28,9 +27,9 @@ func LoadConfigAsMap() (map[string]any, error) {
 				"If you want to create new Regolith project here, use \"regolith init\".")
 	}
 	var configJson map[string]any
-	err = jsonc.Unmarshal(file, &configJson)
+	err = unmarshalJSONC(file, &configJson, ConfigFilePath)
 	if err != nil {
-		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, ConfigFilePath)
+		return nil, burrito.PassError(err)
 	}
 	return configJson, nil
 }
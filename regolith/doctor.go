@@ -0,0 +1,229 @@
+package regolith
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/fatih/color"
+	"github.com/nightlyone/lockfile"
+)
+
+// Statuses of the checks performed by the "regolith doctor" command.
+const (
+	DoctorPass = "pass"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// DoctorCheck is a result of a single check of the "regolith doctor"
+// command.
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// doctorRunners is a list of the runners checked by the "regolith doctor"
+// command with their default commands.
+var doctorRunners = [][2]string{
+	{"node", "node"},
+	{"npm", "npm"},
+	{"deno", "deno"},
+	{"bun", "bun"},
+	{"java", "java"},
+	{"dotnet", "dotnet"},
+	{"nim", "nim"},
+	{"nimble", "nimble"},
+}
+
+// errorSummary returns the messages of the error joined into a single line.
+func errorSummary(err error) string {
+	messages := burrito.GetAllMessages(err)
+	for i := range messages {
+		messages[i] = strings.ReplaceAll(
+			strings.TrimSpace(messages[i]), "\n", " ")
+	}
+	return strings.Join(messages, " ")
+}
+
+// RunDoctorChecks runs all of the checks of the environment that Regolith
+// otherwise does only when they're needed. The checks never stop at the
+// first failure.
+func RunDoctorChecks() []DoctorCheck {
+	var result []DoctorCheck
+	add := func(name, status, message string) {
+		result = append(result, DoctorCheck{
+			Name: name, Status: status, Message: message})
+	}
+
+	// Git
+	if hasGit() {
+		add("git", DoctorPass, "Git is installed.")
+	} else {
+		add("git", DoctorFail, strings.ReplaceAll(gitNotInstalledWarning, "\n", ""))
+	}
+
+	// Shell
+	if shell, _, err := findShell(); err != nil {
+		add("shell", DoctorFail, errorSummary(err))
+	} else {
+		add("shell", DoctorPass, fmt.Sprintf("Using %q.", shell))
+	}
+
+	// Runners of the filters. Missing runners are only warnings, because
+	// they're required only by the filters that use them.
+	if python, err := findPython(); err != nil {
+		add("runner: python", DoctorWarn, errorSummary(err))
+	} else {
+		add("runner: python", DoctorPass, fmt.Sprintf("Using %q.", python))
+	}
+	for _, runner := range doctorRunners {
+		name := "runner: " + runner[0]
+		command, err := getRunner(runner[0], runner[1])
+		if err != nil {
+			add(name, DoctorFail, errorSummary(err))
+			continue
+		}
+		path, err := exec.LookPath(command)
+		if err != nil {
+			add(name, DoctorWarn, fmt.Sprintf("%q not found.", command))
+		} else {
+			add(name, DoctorPass, fmt.Sprintf("Using %q.", path))
+		}
+	}
+
+	// The com.mojang directories are required only by the exports to the
+	// game, so they can't fail.
+	for _, build := range []string{"standard", "preview", "education"} {
+		name := "com.mojang: " + build
+		path, err := FindMojangDir(build, PacksPath)
+		if err != nil {
+			add(name, DoctorWarn, errorSummary(err))
+		} else if _, err := os.Stat(path); err != nil {
+			add(name, DoctorWarn, fmt.Sprintf("%q doesn't exist.", path))
+		} else {
+			add(name, DoctorPass, path)
+		}
+	}
+
+	// Project location
+	if err := CheckSuspiciousLocation(); err != nil {
+		add("project location", DoctorFail, errorSummary(err))
+	} else {
+		add("project location", DoctorPass, "The project location is valid.")
+	}
+
+	// Writable directories
+	dotRegolithPath, err := GetDotRegolith(".")
+	if err != nil {
+		add(".regolith", DoctorFail, errorSummary(err))
+		return result
+	}
+	if err := checkDirectoryWritable(dotRegolithPath); err != nil {
+		add(".regolith", DoctorFail, errorSummary(err))
+	} else {
+		add(".regolith", DoctorPass, dotRegolithPath)
+	}
+	tmpPath, err := GetAbsoluteWorkingDirectory(dotRegolithPath)
+	if err != nil {
+		add("tmp", DoctorFail, errorSummary(err))
+	} else if err := checkDirectoryWritable(tmpPath); err != nil {
+		add("tmp", DoctorFail, errorSummary(err))
+	} else {
+		add("tmp", DoctorPass, tmpPath)
+	}
+
+	// Session lock
+	status, message := checkSessionLock(dotRegolithPath)
+	add("session lock", status, message)
+	return result
+}
+
+// checkDirectoryWritable checks if files can be created in the directory. If
+// the directory doesn't exist, the closest existing parent directory is
+// checked instead. The check doesn't leave any files behind.
+func checkDirectoryWritable(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return burrito.WrapErrorf(err, filepathAbsError, path)
+	}
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return burrito.WrappedErrorf(osStatErrorIsNotExist, path)
+		}
+		path = parent
+	}
+	f, err := os.CreateTemp(path, ".regolith_doctor_*")
+	if err != nil {
+		return burrito.WrapErrorf(err, directoryNotWritableError, path)
+	}
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return burrito.WrapErrorf(err, osRemoveError, f.Name())
+	}
+	return nil
+}
+
+// checkSessionLock checks if the session lock of the project is taken by
+// another instance of Regolith.
+func checkSessionLock(dotRegolithPath string) (string, string) {
+	sessionLockPath, err := filepath.Abs(
+		filepath.Join(dotRegolithPath, "session_lock"))
+	if err != nil {
+		return DoctorFail, errorSummary(err)
+	}
+	sessionLock, err := lockfile.New(sessionLockPath)
+	if err != nil {
+		return DoctorFail, errorSummary(err)
+	}
+	owner, err := sessionLock.GetOwner()
+	switch {
+	case err == nil:
+		return DoctorWarn, fmt.Sprintf(
+			"Locked by another instance of Regolith (PID %d).", owner.Pid)
+	case os.IsNotExist(err):
+		return DoctorPass, "Not locked."
+	case err == lockfile.ErrDeadOwner || err == lockfile.ErrInvalidPid:
+		return DoctorPass, "Stale lock, it will be removed on the next run."
+	default:
+		return DoctorFail, errorSummary(err)
+	}
+}
+
+// printDoctorChecks prints the results of the checks as a table.
+func printDoctorChecks(checks []DoctorCheck) {
+	w := tabwriter.NewWriter(color.Output, 0, 0, 2, ' ', 0)
+	for _, check := range checks {
+		var status string
+		switch check.Status {
+		case DoctorPass:
+			status = color.GreenString("PASS")
+		case DoctorWarn:
+			status = color.YellowString("WARN")
+		default:
+			status = color.RedString("FAIL")
+		}
+		fmt.Fprintf(w, "[%s]\t%s\t%s\n", status, check.Name, check.Message)
+	}
+	w.Flush()
+}
+
+// printDoctorChecksJson prints the results of the checks as JSON.
+func printDoctorChecksJson(checks []DoctorCheck) error {
+	result, err := json.MarshalIndent(checks, "", "\t")
+	if err != nil { // This should never happen.
+		return burrito.WrapError(err, "Failed to marshal the results JSON.")
+	}
+	fmt.Println(string(result))
+	return nil
+}
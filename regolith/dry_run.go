@@ -0,0 +1,395 @@
+package regolith
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// diffContextLines is the number of unchanged lines shown around the changes
+// in the unified diffs.
+const diffContextLines = 3
+
+// maxDiffCells limits the size of the table used for finding the longest
+// common subsequence of the lines of two files. Larger changes are shown as
+// replacing all of the changed lines.
+const maxDiffCells = 4 * 1024 * 1024
+
+// exportPlanSection is a single directory that would be changed by the
+// export.
+type exportPlanSection struct {
+	// title describes the directory, for example "Export target 1 (local),
+	// behavior pack".
+	title string
+	// source is the directory in the tmp directory.
+	source string
+	// destination is the directory that would be updated by the export.
+	destination string
+	// changes are the differences between the source and the destination.
+	changes []FileChange
+}
+
+// PrintExportPlan is used instead of ExportProject by "regolith run
+// --dry-run". It compares the files created by the filters with the files
+// of the export targets and the data folder and prints the summary of the
+// changes that the export would make, including the registration of the
+// packs of the "server" export targets. It doesn't modify the export targets
+// or the data folder.
+func PrintExportPlan(ctx RunContext, showDiff bool) error {
+	profile, err := ctx.GetProfile()
+	if err != nil {
+		return burrito.WrapError(err, runContextGetProfileError)
+	}
+	absWorkingDir, err := GetAbsoluteWorkingDirectory(ctx.DotRegolithPath)
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	activeTargets, err := resolveActiveExportTargets(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	var sections []exportPlanSection
+	var archives []string
+	editedFiles := LoadEditedFiles(ctx.DotRegolithPath)
+	for i, exportTarget := range activeTargets {
+		label := fmt.Sprintf("Export target %d (%s)", i+1, exportTarget.target.Target)
+		if isArchiveExportTarget(exportTarget.target.Target) {
+			archives = append(archives, exportTarget.bpPath)
+			if exportTarget.rpPath != exportTarget.bpPath {
+				archives = append(archives, exportTarget.rpPath)
+			}
+			continue
+		}
+		if !ctx.UnsafeMode {
+			err := editedFiles.CheckDeletionSafety(exportTarget.rpPath, exportTarget.bpPath)
+			if err != nil {
+				Logger.Warnf(
+					"Exporting to the export target %d would fail.\n\t%s",
+					i+1, errorSummary(err))
+			}
+		}
+		sections = append(sections,
+			exportPlanSection{
+				title:       label + ", behavior pack",
+				source:      filepath.Join(absWorkingDir, "BP"),
+				destination: exportTarget.bpPath,
+			},
+			exportPlanSection{
+				title:       label + ", resource pack",
+				source:      filepath.Join(absWorkingDir, "RP"),
+				destination: exportTarget.rpPath,
+			})
+	}
+	exportedFilterNames, err := dataExportFilterNames(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	for _, name := range exportedFilterNames {
+		sections = append(sections, exportPlanSection{
+			title:       fmt.Sprintf("Data of the %q filter", name),
+			source:      filepath.Join(absWorkingDir, "data", name),
+			destination: filepath.Join(ctx.Config.DataPath, name),
+		})
+	}
+	for i := range sections {
+		sections[i].changes, err = CompareDirectories(
+			sections[i].source, sections[i].destination)
+		if err != nil {
+			return burrito.WrapErrorf(
+				err, "Failed to compare the files of %s.",
+				strings.ToLower(sections[i].title))
+		}
+	}
+	serverFiles := &serverJsonFiles{}
+	err = registerAllServerPacks(
+		activeTargets, absWorkingDir, readPreviousServerManifests(activeTargets),
+		serverFiles)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	printExportPlan(os.Stdout, sections, serverFiles, archives, showDiff)
+	return nil
+}
+
+// printExportPlan prints the changes found by PrintExportPlan.
+func printExportPlan(
+	w io.Writer, sections []exportPlanSection, serverFiles *serverJsonFiles,
+	archives []string, showDiff bool,
+) {
+	fmt.Fprintln(w, "Dry run. The following changes would be made by the export:")
+	printFileChanges(w, sections, showDiff)
+	printServerFileChanges(w, serverFiles, showDiff)
+	for _, archive := range archives {
+		fmt.Fprintf(w, "\nArchive: %s\n  The archive would be replaced\n", archive)
+	}
+}
+
+// printFileChanges prints the list of the changes of each section, and
+// optionally the unified diffs of the changed text files.
+func printFileChanges(w io.Writer, sections []exportPlanSection, showDiff bool) {
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s: %s\n", section.title, section.destination)
+		if len(section.changes) == 0 {
+			fmt.Fprintln(w, "  No changes")
+			continue
+		}
+		counts := map[string]int{}
+		for _, change := range section.changes {
+			counts[change.Kind]++
+			fmt.Fprintf(w, "  %s %s\n", changeSymbol(change.Kind), change.Path)
+		}
+		fmt.Fprintf(
+			w, "  %d added, %d modified, %d deleted\n",
+			counts[FileAdded], counts[FileModified], counts[FileDeleted])
+		if !showDiff {
+			continue
+		}
+		for _, change := range section.changes {
+			diff, err := fileChangeDiff(section, change)
+			if err != nil {
+				Logger.Warnf(
+					"Unable to show the diff of %q.\n\t%s",
+					change.Path, errorSummary(err))
+				continue
+			}
+			if diff != "" {
+				fmt.Fprintln(w)
+				fmt.Fprint(w, diff)
+			}
+		}
+	}
+}
+
+// printServerFileChanges prints the changes of the JSON files of the servers
+// that would be made by registering the server packs, and optionally their
+// unified diffs.
+func printServerFileChanges(w io.Writer, serverFiles *serverJsonFiles, showDiff bool) {
+	for _, path := range serverFiles.plannedPaths {
+		newContent := serverFiles.planned[path]
+		oldName := "a/" + filepath.Base(path)
+		oldContent, err := os.ReadFile(path)
+		fmt.Fprintf(w, "\nServer pack registration: %s\n", path)
+		switch {
+		case os.IsNotExist(err):
+			oldName = "/dev/null"
+			fmt.Fprintln(w, "  The file would be created")
+		case err != nil:
+			Logger.Warnf(
+				"Unable to read the server file %q.\n\t%s", path, err.Error())
+			fmt.Fprintln(w, "  The file would be updated")
+			continue
+		case bytes.Equal(oldContent, newContent):
+			fmt.Fprintln(w, "  No changes")
+			continue
+		default:
+			fmt.Fprintln(w, "  The file would be updated")
+		}
+		if showDiff && isTextContent(oldContent) {
+			fmt.Fprintln(w)
+			fmt.Fprint(w, unifiedDiff(
+				oldName, "b/"+filepath.Base(path), oldContent, newContent))
+		}
+	}
+}
+
+// changeSymbol returns the symbol used for the kind of the change in the
+// summary of the export plan.
+func changeSymbol(kind string) string {
+	switch kind {
+	case FileAdded:
+		return "+"
+	case FileDeleted:
+		return "-"
+	}
+	return "~"
+}
+
+// fileChangeDiff returns the unified diff of the changed file. It returns an
+// empty string if the file isn't a text file.
+func fileChangeDiff(section exportPlanSection, change FileChange) (string, error) {
+	var oldContent, newContent []byte
+	oldName, newName := "a/"+change.Path, "b/"+change.Path
+	if change.Kind != FileAdded {
+		path := filepath.Join(section.destination, filepath.FromSlash(change.Path))
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", burrito.WrapErrorf(err, fileReadError, path)
+		}
+		oldContent = content
+	} else {
+		oldName = "/dev/null"
+	}
+	if change.Kind != FileDeleted {
+		path := filepath.Join(section.source, filepath.FromSlash(change.Path))
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", burrito.WrapErrorf(err, fileReadError, path)
+		}
+		newContent = content
+	} else {
+		newName = "/dev/null"
+	}
+	if !isTextContent(oldContent) || !isTextContent(newContent) {
+		return "", nil
+	}
+	return unifiedDiff(oldName, newName, oldContent, newContent), nil
+}
+
+// isTextContent returns true if the content looks like a text file (for
+// example a JSON file) that can be shown in a diff.
+func isTextContent(content []byte) bool {
+	return utf8.Valid(content) && !bytes.Contains(content, []byte{0})
+}
+
+// diffLine is a single line of a diff. The kind is ' ' for unchanged lines,
+// '-' for removed lines and '+' for added lines.
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// splitLines splits the text into lines. The lines keep their line endings,
+// so the last line doesn't end with "\n" if the text doesn't end with a new
+// line.
+func splitLines(text []byte) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the list of changes that turn the lines a into the
+// lines b.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	result := make([]diffLine, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		result = append(result, diffLine{' ', line})
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(am), len(bm)
+	i, j := 0, 0
+	if n*m <= maxDiffCells {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// am[i:] and bm[j:]
+		lcs := make([][]int32, n+1)
+		for k := range lcs {
+			lcs[k] = make([]int32, m+1)
+		}
+		for x := n - 1; x >= 0; x-- {
+			for y := m - 1; y >= 0; y-- {
+				if am[x] == bm[y] {
+					lcs[x][y] = lcs[x+1][y+1] + 1
+				} else {
+					lcs[x][y] = max(lcs[x+1][y], lcs[x][y+1])
+				}
+			}
+		}
+		for i < n && j < m {
+			if am[i] == bm[j] {
+				result = append(result, diffLine{' ', am[i]})
+				i++
+				j++
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				result = append(result, diffLine{'-', am[i]})
+				i++
+			} else {
+				result = append(result, diffLine{'+', bm[j]})
+				j++
+			}
+		}
+	}
+	for ; i < n; i++ {
+		result = append(result, diffLine{'-', am[i]})
+	}
+	for ; j < m; j++ {
+		result = append(result, diffLine{'+', bm[j]})
+	}
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, diffLine{' ', line})
+	}
+	return result
+}
+
+// unifiedDiff returns the diff of two texts in the unified format. It returns
+// an empty string if the texts are equal.
+func unifiedDiff(oldName, newName string, oldText, newText []byte) string {
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	// Number of the old and new lines before each line of the diff
+	oldLineNumbers := make([]int, len(lines)+1)
+	newLineNumbers := make([]int, len(lines)+1)
+	var changes []int
+	for i, line := range lines {
+		oldLineNumbers[i+1] = oldLineNumbers[i]
+		newLineNumbers[i+1] = newLineNumbers[i]
+		if line.kind != '+' {
+			oldLineNumbers[i+1]++
+		}
+		if line.kind != '-' {
+			newLineNumbers[i+1]++
+		}
+		if line.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	var result strings.Builder
+	fmt.Fprintf(&result, "--- %s\n+++ %s\n", oldName, newName)
+	for first := 0; first < len(changes); {
+		// Merge the changes that are close to each other into one hunk
+		last := first
+		for last+1 < len(changes) &&
+			changes[last+1]-changes[last] <= 2*diffContextLines {
+			last++
+		}
+		start := max(0, changes[first]-diffContextLines)
+		end := min(len(lines), changes[last]+diffContextLines+1)
+		fmt.Fprintf(
+			&result, "@@ -%s +%s @@\n",
+			hunkRange(oldLineNumbers[start], oldLineNumbers[end]),
+			hunkRange(newLineNumbers[start], newLineNumbers[end]))
+		for _, line := range lines[start:end] {
+			result.WriteByte(line.kind)
+			result.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				result.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		first = last + 1
+	}
+	return result.String()
+}
+
+// hunkRange formats the range of the lines of a hunk of the unified diff. The
+// start is the number of the lines before the hunk and the end is the number
+// of lines before the end of the hunk.
+func hunkRange(start, end int) string {
+	count := end - start
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
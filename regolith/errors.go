@@ -282,4 +282,222 @@ const (
 	userSettingIncorrectKeyUseError = "Cannot use <key> with non-map property."
 
 	getRunnerError = "Failed to get the path to filter runner."
+
+	// filterCacheSerializeError is used when the configuration of a filter
+	// can't be converted to JSON to create its cache key.
+	filterCacheSerializeError = "Failed to serialize the filter configuration.\nFilter: %s"
+
+	// loadLockFileError is used when the "regolith.lock" file can't be loaded.
+	loadLockFileError = "Failed to load the \"regolith.lock\" file."
+
+	// dumpLockFileError is used when the "regolith.lock" file can't be saved.
+	dumpLockFileError = "Failed to save the \"regolith.lock\" file."
+
+	// directoryNotWritableError is used when Regolith can't create files in
+	// a directory.
+	directoryNotWritableError = "Directory is not writable.\nPath: %s"
+
+	// doctorChecksFailedError is used when some of the checks of the
+	// "regolith doctor" command fail.
+	doctorChecksFailedError = "Some of the checks failed: %s"
+
+	// invalidTimeoutError is used when the "timeout" property of a filter
+	// is not a valid positive duration.
+	invalidTimeoutError = "Invalid timeout value. The timeout must be a positive " +
+		"duration, for example \"30s\" or \"5m\".\nValue: %s"
+
+	// filterTimeoutError is used when a filter runs longer than its timeout.
+	filterTimeoutError = "The filter didn't finish in %s and was stopped."
+
+	// subProcessCancelledError is used when the process of a filter is
+	// killed because the filter was cancelled.
+	subProcessCancelledError = "The process was stopped because the filter was cancelled."
+
+	// subProcessStoppedByUserError is used when the process of a filter is
+	// killed because Regolith received an interrupt signal.
+	subProcessStoppedByUserError = "The process was stopped by the user."
+
+	// scriptPathOutsideSandboxError is used when the script of an embedded
+	// filter tries to access a file outside of the RP, BP and data
+	// directories.
+	scriptPathOutsideSandboxError = "The path is outside of the RP, BP and data directories.\nPath: %s"
+
+	// wasmCompileError is used when the WebAssembly module of a filter can't
+	// be compiled.
+	wasmCompileError = "Failed to compile the WebAssembly module."
+
+	// wasmRunError is used when the WebAssembly module of a filter fails
+	// without an exit code.
+	wasmRunError = "Failed to run the WebAssembly module."
+
+	// wasmExitCodeError is used when the WebAssembly module of a filter exits
+	// with a non-zero exit code.
+	wasmExitCodeError = "The WebAssembly module exited with code %d."
+
+	// filterTestsNotFoundError is used when the directory with the test
+	// cases of a filter doesn't contain any test cases.
+	filterTestsNotFoundError = "The directory doesn't contain any test cases of the filter.\n" +
+		"Path: %s"
+
+	// filterTestCaseError is used when a test case of a filter can't be run.
+	filterTestCaseError = "Failed to run the test case.\nTest case: %s"
+
+	// filterTestsFailedError is used when the output of a filter doesn't
+	// match the expected output of some of its test cases.
+	filterTestsFailedError = "%d of %d test cases failed: %s\n" +
+		"Use the \"--update\" flag to replace the expected output with the actual output."
+
+	// asyncFilterConflictError is used when multiple subfilters of an
+	// isolated async filter change the same files differently.
+	asyncFilterConflictError = "Multiple async subfilters changed the same files differently.\n" +
+		"Conflicting files (and the subfilters that changed them):\n%s"
+
+	// asyncFilterUnknownDependencyError is used when a subfilter of an async
+	// filter lists an unknown subfilter in its "after" property.
+	asyncFilterUnknownDependencyError = "The async subfilter depends on an unknown subfilter.\n" +
+		"Subfilter: %s\nUnknown subfilter ID: %s"
+
+	// asyncFilterCycleError is used when the "after" properties of the
+	// subfilters of an async filter form a cycle.
+	asyncFilterCycleError = "The \"after\" dependencies of the async subfilters form a cycle.\n" +
+		"Cycle: %s"
+
+	// jsonLocationError is used to add the location of an invalid value to
+	// the errors of parsing the JSON files.
+	jsonLocationError = "Invalid value in the JSON file.\nLocation: %s"
+
+	// jsonSchemaCompileError is used when a JSON schema can't be compiled.
+	jsonSchemaCompileError = "Failed to compile the JSON schema.\nSchema: %s"
+
+	// validationFailedError is used when "regolith validate" finds problems
+	// in the configuration files.
+	validationFailedError = "The validation found %d problems."
+
+	// profileExtendsError is used when the profile extended by another
+	// profile can't be resolved.
+	profileExtendsError = "Failed to resolve the extended profile.\nProfile: %s"
+
+	// profileExtendsMissingError is used when a profile extends a profile
+	// that doesn't exist.
+	profileExtendsMissingError = "The extended profile doesn't exist.\nProfile: %s"
+
+	// profileExtendsCycleError is used when the "extends" properties of the
+	// profiles form a cycle.
+	profileExtendsCycleError = "The \"extends\" properties of the profiles form a cycle.\n" +
+		"Cycle: %s"
+
+	// profileExtendsFiltersKeyError is used when the "filters" object of a
+	// profile that extends another profile has an unknown property.
+	profileExtendsFiltersKeyError = "Unknown property of the \"filters\" object.\n" +
+		"Property: %s\n" +
+		"Valid properties: prepend, append, replace, override"
+
+	// profileExtendsUnknownFilterError is used when the "replace" or
+	// "override" property refers to a filter that the extended profile
+	// doesn't have.
+	profileExtendsUnknownFilterError = "The extended profile doesn't have the filter.\n" +
+		"Filter: %s\nJSON Path: %s"
+
+	// unterminatedTemplateError is used when a "${" template in the
+	// configuration isn't closed with "}".
+	unterminatedTemplateError = "The template isn't closed.\nTemplate: %s"
+
+	// templateEvaluationError is used when the expression of a "${" template
+	// can't be evaluated.
+	templateEvaluationError = "Failed to evaluate the template.\nExpression: %s"
+
+	// invalidGlobError is used when a glob pattern in the configuration has
+	// an invalid syntax.
+	invalidGlobError = "Invalid glob pattern.\nPattern: %s\nJSON Path: %s"
+
+	// watchSnapshotError is used when the snapshot of the tmp directory
+	// used for restarting the profile in the watch mode can't be saved or
+	// restored.
+	watchSnapshotError = "Failed to update the snapshot of the tmp directory.\n" +
+		"Snapshot: %s"
+
+	// invalidDurationError is used when a duration in the configuration
+	// can't be parsed or is out of range.
+	invalidDurationError = "Invalid duration.\nValue: %s\nProperty: %s"
+
+	// invalidServeAddressError is used when the address of the build event
+	// server can't be parsed.
+	invalidServeAddressError = "Invalid address of the build event server.\n" +
+		"Address: %s"
+
+	// buildEventServerError is used when the build event server can't be
+	// started.
+	buildEventServerError = "Failed to start the build event server.\nAddress: %s"
+
+	// writeRunReportError is used when the report or the trace of the run
+	// can't be saved.
+	writeRunReportError = "Failed to save the report of the run.\nPath: %s"
+
+	// transactionalExportError is used when the staged files of the
+	// transactional export can't be swapped with the files of an export path.
+	transactionalExportError = "Failed to replace the exported files with the staged files.\n" +
+		"Path: %s"
+
+	// modifiedExportedFilesError is used when the files exported by Regolith
+	// were modified outside of Regolith since the export.
+	modifiedExportedFilesError = "Some of the exported files were modified " +
+		"outside of Regolith since the last export.\n" +
+		"Run Regolith with the --backup flag to back them up before exporting, " +
+		"or with the --unsafe flag to overwrite them.\n" +
+		"Files:\n%s"
+
+	// backupModifiedFilesError is used when the files modified outside of
+	// Regolith can't be backed up.
+	backupModifiedFilesError = "Failed to back up the modified exported files.\n" +
+		"Backup path: %s"
+
+	// invalidPackManifestError is used when the manifest.json file of a pack
+	// doesn't have the UUID or the version of the pack.
+	invalidPackManifestError = "The manifest of the pack doesn't have the " +
+		"\"header.uuid\" or \"header.version\" property.\nPath: %s"
+
+	// registerServerPackError is used when a pack exported by the "server"
+	// export target can't be registered in the files of the server.
+	registerServerPackError = "Failed to register the pack in the dedicated server.\n" +
+		"Pack: %s\nFile: %s"
+
+	// manifestVersionConflictError is used when the manifest options of a
+	// profile set the version of the packs in two different ways.
+	manifestVersionConflictError = "The \"version\" and \"gitTagVersion\" properties " +
+		"of the manifest options can't be used together."
+
+	// invalidManifestVersionError is used when the version of the packs set
+	// by the manifest options isn't in the "major.minor.patch" format.
+	invalidManifestVersionError = "The version of the packs must be in the " +
+		"\"major.minor.patch\" format.\nVersion: %s"
+
+	// updateManifestError is used when the manifest.json file of a pack
+	// can't be updated based on the manifest options of the profile.
+	updateManifestError = "Failed to update the manifest of the pack.\nPath: %s"
+
+	// filterDefinitionMissingError is used when the filter isn't on the
+	// "filterDefinitions" list of the config file.
+	filterDefinitionMissingError = "Unable to find the filter on the \"filterDefinitions\" " +
+		"list of the \"config.json\" file.\nFilter name: %s"
+
+	// filterNotRemoteError is used when a command that works only with the
+	// remote filters is used with a different type of filter.
+	filterNotRemoteError = "The filter isn't a remote filter.\nFilter name: %s"
+
+	// lockFileOutdatedError is used when the URL or the version of a remote
+	// filter in the config file doesn't match its entry in the lock file
+	// during a frozen installation.
+	lockFileOutdatedError = "The lock file is out of date.\n" +
+		"Filter: %s\nURL: %s\nVersion: %s\nLocked URL: %s\nLocked version: %s\n" +
+		"Run \"regolith install-all\" without the \"--frozen\" flag to update the lock file."
+
+	// mcpackPathCollisionError is used when the "mcpack" export target would
+	// write both packs to the same archive.
+	mcpackPathCollisionError = "The behavior pack and the resource pack would be " +
+		"exported to the same \".mcpack\" file. Use different \"bpName\" and " +
+		"\"rpName\" properties for the export target.\nPath: %s"
+
+	// filterCacheUnsupportedError is used when the "cache" property is used
+	// with a filter that can't be cached.
+	filterCacheUnsupportedError = "The \"cache\" property can't be used with %s."
 )
@@ -1,13 +1,17 @@
 package regolith
 
 import (
+	"maps"
 	"os"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
 	"github.com/stirante/go-simple-eval/eval"
 	"github.com/stirante/go-simple-eval/eval/utils"
+	"golang.org/x/mod/semver"
 )
 
 // EvalCondition evaluates a condition expression with the given context.
@@ -73,5 +77,168 @@ func prepareScope(ctx RunContext) map[string]any {
 		"nested":         ctx.Parent != nil,
 		"initial":        ctx.Initial,
 		"env":            envVars,
+		"variables":      scopeVariables(ctx),
 	}
 }
+
+// scopeVariables returns the variables available in the expressions. The
+// "variables" of the project are overridden by the "variables" of the
+// profiles of the context, starting from the outermost profile, so the
+// profile that runs a nested profile can change its variables.
+func scopeVariables(ctx RunContext) map[string]any {
+	result := maps.Clone(ctx.Config.Variables)
+	if result == nil {
+		result = map[string]any{}
+	}
+	var profiles []string
+	for c := &ctx; c != nil; c = c.Parent {
+		profiles = append(profiles, c.Profile)
+	}
+	for _, profile := range slices.Backward(profiles) {
+		maps.Copy(result, ctx.Config.Profiles[profile].Variables)
+	}
+	return result
+}
+
+// templatesEnabled returns true if the format version of the project is at
+// least templatesFormatVersion, so the templates should be evaluated.
+func templatesEnabled(ctx RunContext) bool {
+	return ctx.Config != nil && semver.Compare(
+		"v"+ctx.Config.FormatVersion, "v"+templatesFormatVersion) >= 0
+}
+
+// InterpolateString replaces the "${expression}" templates in the text with
+// the results of the expressions evaluated with the given context. The "$${"
+// sequence is an escaped "${" which isn't evaluated. The text is returned
+// unchanged if the project doesn't use templates (see templatesEnabled).
+func InterpolateString(text string, ctx RunContext) (string, error) {
+	if !templatesEnabled(ctx) || !strings.Contains(text, "${") {
+		return text, nil
+	}
+	result, err := interpolateString(text, prepareScope(ctx), false)
+	if err != nil {
+		return "", burrito.PassError(err)
+	}
+	return result.(string), nil
+}
+
+// InterpolateSettings returns a copy of the filter settings with the
+// "${expression}" templates in all of the strings replaced using
+// InterpolateString. The strings that consist of a single template are
+// replaced with the value of the expression without converting it to a
+// string, so the settings can contain numbers, booleans, arrays and objects
+// taken from the variables. The settings are returned unchanged if the
+// project doesn't use templates (see templatesEnabled).
+func InterpolateSettings(settings map[string]any, ctx RunContext) (map[string]any, error) {
+	if settings == nil || !templatesEnabled(ctx) {
+		return settings, nil
+	}
+	result, err := interpolateValue(settings, prepareScope(ctx))
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	return result.(map[string]any), nil
+}
+
+// interpolateValue returns a copy of the JSON value with the templates of all
+// of its strings replaced.
+func interpolateValue(value any, scope map[string]any) (any, error) {
+	switch value := value.(type) {
+	case string:
+		return interpolateString(value, scope, true)
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			v, err := interpolateValue(v, scope)
+			if err != nil {
+				return nil, burrito.WrapErrorf(err, jsonPropertyParseError, k)
+			}
+			result[k] = v
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(value))
+		for i, v := range value {
+			v, err := interpolateValue(v, scope)
+			if err != nil {
+				return nil, burrito.WrapErrorf(err, jsonPathParseError, strconv.Itoa(i))
+			}
+			result[i] = v
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// interpolateString replaces the templates of the text using the scope. If
+// keepType is true and the text is a single template, the result is the
+// value of the expression instead of its string representation.
+func interpolateString(text string, scope map[string]any, keepType bool) (any, error) {
+	var builder strings.Builder
+	for {
+		start := strings.Index(text, "${")
+		if start == -1 {
+			builder.WriteString(text)
+			break
+		}
+		if start > 0 && text[start-1] == '$' {
+			// Escaped template
+			builder.WriteString(text[:start-1])
+			builder.WriteString("${")
+			text = text[start+2:]
+			continue
+		}
+		end := templateEnd(text, start+2)
+		if end == -1 {
+			return nil, burrito.WrappedErrorf(unterminatedTemplateError, text[start:])
+		}
+		expression := text[start+2 : end]
+		value, err := eval.Eval(expression, scope)
+		if err == nil {
+			// The failed evaluation of some of the expressions is
+			// returned as a value instead of an error
+			err, _ = value.(error)
+		}
+		if err != nil {
+			return nil, burrito.WrapErrorf(err, templateEvaluationError, expression)
+		}
+		value = utils.UnwrapContainers(value)
+		if keepType && start == 0 && end == len(text)-1 && builder.Len() == 0 {
+			return value, nil
+		}
+		builder.WriteString(text[:start])
+		builder.WriteString(utils.ToString(value))
+		text = text[end+1:]
+	}
+	return builder.String(), nil
+}
+
+// templateEnd returns the index of the "}" that closes the template with the
+// expression starting at the given index. The braces inside the string
+// literals and the nested braces are skipped. It returns -1 if the template
+// isn't closed.
+func templateEnd(text string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
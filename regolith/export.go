@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
 	"github.com/otiai10/copy"
@@ -19,6 +22,10 @@ import (
 func GetExportPaths(
 	exportTarget ExportTarget, ctx RunContext,
 ) (bpPath string, rpPath string, err error) {
+	exportTarget, err = interpolateExportTarget(exportTarget, ctx)
+	if err != nil {
+		return "", "", burrito.PassError(err)
+	}
 	bpName, rpName, err := GetExportNames(exportTarget, ctx)
 	if err != nil {
 		return "", "", burrito.WrapError(
@@ -28,10 +35,10 @@ func GetExportPaths(
 
 	if semver.Compare(vFormatVersion, "v1.4.0") < 0 {
 		bpPath, rpPath, err = getExportPathsV1_2_0(
-			exportTarget, bpName, rpName)
-	} else if semver.Compare(vFormatVersion, "v1.8.0") <= 0 {
+			exportTarget, ctx.Config.Name, bpName, rpName)
+	} else if semver.Compare(vFormatVersion, "v1.9.0") <= 0 {
 		bpPath, rpPath, err = getExportPathsV1_4_0(
-			exportTarget, bpName, rpName)
+			exportTarget, ctx.Config.Name, bpName, rpName)
 	} else {
 		err = burrito.WrappedErrorf(
 			incompatibleFormatVersionError,
@@ -40,6 +47,33 @@ func GetExportPaths(
 	return
 }
 
+// interpolateExportTarget returns a copy of the export target with the
+// "${expression}" templates in its paths replaced using InterpolateString.
+func interpolateExportTarget(
+	exportTarget ExportTarget, ctx RunContext,
+) (ExportTarget, error) {
+	properties := []struct {
+		name  string
+		value *string
+	}{
+		{"bpPath", &exportTarget.BpPath},
+		{"rpPath", &exportTarget.RpPath},
+		{"worldName", &exportTarget.WorldName},
+		{"worldPath", &exportTarget.WorldPath},
+		{"serverPath", &exportTarget.ServerPath},
+		{"path", &exportTarget.Path},
+	}
+	for _, property := range properties {
+		value, err := InterpolateString(*property.value, ctx)
+		if err != nil {
+			return exportTarget, burrito.WrapErrorf(
+				err, jsonPropertyParseError, property.name)
+		}
+		*property.value = value
+	}
+	return exportTarget, nil
+}
+
 func FindMojangDir(build string, pathType ComMojangPathType) (string, error) {
 	switch build {
 	case "standard":
@@ -62,7 +96,7 @@ func FindMojangDir(build string, pathType ComMojangPathType) (string, error) {
 // getExportPathsV1_2_0 handles GetExportPaths for Regolith format versions
 // below 1.4.0.
 func getExportPathsV1_2_0(
-	exportTarget ExportTarget, bpName string, rpName string,
+	exportTarget ExportTarget, projectName, bpName, rpName string,
 ) (bpPath string, rpPath string, err error) {
 	switch exportTarget.Target {
 	case "development":
@@ -90,6 +124,10 @@ func getExportPathsV1_2_0(
 	case "local":
 		bpPath = "build/" + bpName + "/"
 		rpPath = "build/" + rpName + "/"
+	case "mcpack", "mcaddon":
+		return getArchiveExportPaths(exportTarget, projectName, bpName, rpName)
+	case "server":
+		return getServerExportPaths(exportTarget, bpName, rpName)
 	case "none":
 		bpPath = ""
 		rpPath = ""
@@ -101,9 +139,10 @@ func getExportPathsV1_2_0(
 }
 
 // getExportPathsV1_4_0 handles GetExportPaths for Regolith format version
-// 1.4.0.
+// 1.4.0. For the "mcpack" and "mcaddon" targets, the returned paths are the
+// paths of the archive files.
 func getExportPathsV1_4_0(
-	exportTarget ExportTarget, bpName string, rpName string,
+	exportTarget ExportTarget, projectName, bpName, rpName string,
 ) (bpPath string, rpPath string, err error) {
 	switch exportTarget.Target {
 	case "development":
@@ -123,6 +162,10 @@ func getExportPathsV1_4_0(
 	case "local":
 		bpPath = "build/" + bpName + "/"
 		rpPath = "build/" + rpName + "/"
+	case "mcpack", "mcaddon":
+		return getArchiveExportPaths(exportTarget, projectName, bpName, rpName)
+	case "server":
+		return getServerExportPaths(exportTarget, bpName, rpName)
 	case "none":
 		bpPath = ""
 		rpPath = ""
@@ -278,30 +321,32 @@ func checkExportPathCollision(seen map[string]string, path, label string) error
 	return nil
 }
 
-// ExportProject copies files from the tmp paths (tmp/BP and tmp/RP) into
-// the project's export targets. The paths are generated with GetExportPaths.
-func ExportProject(ctx RunContext) error {
-	MeasureStart("Export - GetExportPaths")
-	profile, err := ctx.GetProfile()
-	if err != nil {
-		return burrito.WrapError(err, runContextGetProfileError)
-	}
-	// Resolve all non-"none" targets before modifying any export path. This
-	// keeps failure atomic when a later target has an invalid path or unsafe
-	// existing files.
+// resolveActiveExportTargets returns the paths of all export targets of the
+// profile that aren't set to "none". It returns an error if the paths of the
+// targets collide with each other.
+func resolveActiveExportTargets(
+	profile Profile, ctx RunContext,
+) ([]resolvedExportTarget, error) {
 	var activeTargets []resolvedExportTarget
 	seenExportPaths := make(map[string]string)
 	for i, exportTarget := range profile.activeExportTargets() {
 		bpPath, rpPath, err := GetExportPaths(exportTarget, ctx)
 		if err != nil {
-			return burrito.WrapError(err, getExportPathsError)
+			return nil, burrito.WrapError(err, getExportPathsError)
 		}
 		targetLabel := fmt.Sprintf("export target %d (%s)", i+1, exportTarget.Target)
-		if err := checkExportPathCollision(seenExportPaths, bpPath, targetLabel+" behavior pack: "+bpPath); err != nil {
-			return burrito.PassError(err)
-		}
-		if err := checkExportPathCollision(seenExportPaths, rpPath, targetLabel+" resource pack: "+rpPath); err != nil {
-			return burrito.PassError(err)
+		if exportTarget.Target == "mcaddon" {
+			// Both packs are stored in the same archive
+			if err := checkExportPathCollision(seenExportPaths, bpPath, targetLabel+" addon: "+bpPath); err != nil {
+				return nil, burrito.PassError(err)
+			}
+		} else {
+			if err := checkExportPathCollision(seenExportPaths, bpPath, targetLabel+" behavior pack: "+bpPath); err != nil {
+				return nil, burrito.PassError(err)
+			}
+			if err := checkExportPathCollision(seenExportPaths, rpPath, targetLabel+" resource pack: "+rpPath); err != nil {
+				return nil, burrito.PassError(err)
+			}
 		}
 		activeTargets = append(activeTargets, resolvedExportTarget{
 			target: exportTarget,
@@ -309,49 +354,79 @@ func ExportProject(ctx RunContext) error {
 			rpPath: rpPath,
 		})
 	}
+	return activeTargets, nil
+}
+
+// ExportProject copies files from the tmp paths (tmp/BP and tmp/RP) into
+// the project's export targets. The paths are generated with GetExportPaths.
+func ExportProject(ctx RunContext) error {
+	measure := MeasureStart("Export - GetExportPaths")
+	profile, err := ctx.GetProfile()
+	if err != nil {
+		return burrito.WrapError(err, runContextGetProfileError)
+	}
+	// Resolve all non-"none" targets before modifying any export path. This
+	// keeps failure atomic when a later target has an invalid path or unsafe
+	// existing files.
+	activeTargets, err := resolveActiveExportTargets(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
+	}
 	if len(activeTargets) == 0 {
 		Logger.Debugf("All export targets are set to \"none\". Skipping export.")
 		return nil
 	}
 	dotRegolithPath := ctx.DotRegolithPath
-	useSymlink := ctx.SymlinkExport && len(activeTargets) == 1
+	useSymlink := ctx.SymlinkExport && len(activeTargets) == 1 &&
+		!isArchiveExportTarget(activeTargets[0].target.Target)
 	editedFiles := LoadEditedFiles(dotRegolithPath)
+	measure.End()
 	if !useSymlink && !ctx.UnsafeMode {
-		MeasureStart("Export - CheckDeletionSafety")
-		for _, exportTarget := range activeTargets {
-			err = editedFiles.CheckDeletionSafety(exportTarget.rpPath, exportTarget.bpPath)
-			if err != nil {
-				return burrito.WrapErrorf(
-					err, checkDeletionSafetyError, exportTarget.rpPath, exportTarget.bpPath)
+		measure = MeasureStart("Export - CheckDeletionSafety")
+		backup := &modifiedFilesBackup{dotRegolithPath: dotRegolithPath}
+		for i, exportTarget := range activeTargets {
+			// Archives are always replaced as a whole
+			if isArchiveExportTarget(exportTarget.target.Target) {
+				continue
 			}
-		}
-	}
-
-	for i, exportTarget := range activeTargets {
-		// Symlink export already placed files for the only active target.
-		if useSymlink && i == 0 {
-			Logger.Debugf("Symlink export is enabled. Skipping RP and BP export.")
-		} else {
-			// Move is only safe when there is exactly one active target
-			// and symlink export is off, since tmp/ is the sole source and
-			// moving from a symlinked tmp would destroy the first target.
-			canMove := len(activeTargets) == 1 && !useSymlink
-			err = exportProjectRpAndBp(
-				exportTarget.target, exportTarget.rpPath, exportTarget.bpPath,
-				ctx, canMove)
+			err = checkExportPathsSafety(
+				ctx, editedFiles, exportTarget.rpPath, exportTarget.bpPath,
+				backup, i+1)
 			if err != nil {
 				return burrito.PassError(err)
 			}
 		}
+		measure.End()
 	}
-	// Export data once (not per target)
-	MeasureStart("Export - ExportData")
-	err = exportProjectData(profile, ctx)
-	if err != nil {
-		return burrito.PassError(err)
+
+	// The previous manifests are used for removing the stale entries of the
+	// server packs, so they must be read before replacing the packs.
+	previousManifests := readPreviousServerManifests(activeTargets)
+	if profile.TransactionalExport {
+		measure = MeasureStart("Export - Transactional")
+		err = exportProjectTransactional(
+			profile, activeTargets, previousManifests, ctx, useSymlink)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		measure.End()
+	} else {
+		err = exportProjectToTargets(profile, activeTargets, ctx, useSymlink)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		measure = MeasureStart("Export - RegisterServerPacks")
+		err = registerServerPacksRevertibly(activeTargets, previousManifests, ctx)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		measure.End()
 	}
-	MeasureStart("Export - EditedFiles.UpdateFromPaths")
+	measure = MeasureStart("Export - EditedFiles.UpdateFromPaths")
 	for _, exportTarget := range activeTargets {
+		if isArchiveExportTarget(exportTarget.target.Target) {
+			continue
+		}
 		err = editedFiles.UpdateFromPaths(exportTarget.rpPath, exportTarget.bpPath)
 		if err != nil {
 			return burrito.WrapError(
@@ -363,9 +438,10 @@ func ExportProject(ctx RunContext) error {
 	if err != nil {
 		return burrito.WrapError(err, updatedFilesDumpError)
 	}
-	MeasureStart("Export - Remove Empty Export Paths")
+	measure.End()
+	measure = MeasureStart("Export - Remove Empty Export Paths")
 	for i, exportTarget := range activeTargets {
-		if useSymlink && i == 0 {
+		if (useSymlink && i == 0) || isArchiveExportTarget(exportTarget.target.Target) {
 			continue
 		}
 		for _, packPath := range []string{exportTarget.rpPath, exportTarget.bpPath} {
@@ -380,7 +456,116 @@ func ExportProject(ctx RunContext) error {
 			}
 		}
 	}
-	MeasureEnd()
+	measure.End()
+	return nil
+}
+
+// modifiedFilesBackup is a directory in the .regolith directory for backing
+// up the files of the export targets modified outside of Regolith. The
+// directory is created when the first file is backed up.
+type modifiedFilesBackup struct {
+	dotRegolithPath string
+	path            string
+}
+
+// targetPath returns the path for the backup of the export target with the
+// given number. On the first call, it creates a new backup directory named
+// after the current time, with a unique suffix so that the exports started
+// in the same second don't share it.
+func (b *modifiedFilesBackup) targetPath(target int) (string, error) {
+	if b.path == "" {
+		backupsPath := filepath.Join(b.dotRegolithPath, "backups")
+		if err := os.MkdirAll(backupsPath, 0755); err != nil {
+			return "", burrito.WrapErrorf(err, osMkdirError, backupsPath)
+		}
+		path, err := os.MkdirTemp(
+			backupsPath, time.Now().Format("2006-01-02_15-04-05")+"_")
+		if err != nil {
+			return "", burrito.WrapErrorf(err, osMkdirError, backupsPath)
+		}
+		b.path = path
+	}
+	return filepath.Join(b.path, strconv.Itoa(target)), nil
+}
+
+// checkExportPathsSafety checks whether it's safe to delete the files from
+// rpPath and bpPath. If the BackupModifiedFiles option of the context is
+// enabled, the files modified outside of Regolith since the last export are
+// copied to the backup of the export target with the given number instead of
+// failing the check.
+func checkExportPathsSafety(
+	ctx RunContext, editedFiles EditedFiles, rpPath, bpPath string,
+	backup *modifiedFilesBackup, target int,
+) error {
+	if !ctx.BackupModifiedFiles {
+		err := editedFiles.CheckDeletionSafety(rpPath, bpPath)
+		if err != nil {
+			return burrito.WrapErrorf(err, checkDeletionSafetyError, rpPath, bpPath)
+		}
+		return nil
+	}
+	rpModified, bpModified, err := editedFiles.FindModifiedFiles(rpPath, bpPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, checkDeletionSafetyError, rpPath, bpPath)
+	}
+	if len(rpModified) == 0 && len(bpModified) == 0 {
+		return nil
+	}
+	backupPath, err := backup.targetPath(target)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	err = BackupModifiedFiles(rpPath, rpModified, bpPath, bpModified, backupPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, backupModifiedFilesError, backupPath)
+	}
+	Logger.Warnf(
+		"Backed up the exported files modified outside of Regolith.\n"+
+			"Backup path: %s\nFiles:\n%s",
+		backupPath, formatModifiedFiles(rpPath, rpModified, bpPath, bpModified))
+	return nil
+}
+
+// exportProjectToTargets is a helper function for ExportProject. It exports
+// the packs to each of the export targets one after another and then exports
+// the data of the filters.
+func exportProjectToTargets(
+	profile Profile, activeTargets []resolvedExportTarget, ctx RunContext,
+	useSymlink bool,
+) error {
+	var err error
+	for i, exportTarget := range activeTargets {
+		// Symlink export already placed files for the only active target.
+		if useSymlink && i == 0 {
+			Logger.Debugf("Symlink export is enabled. Skipping RP and BP export.")
+		} else if isArchiveExportTarget(exportTarget.target.Target) {
+			measure := MeasureStart("Export - Archive")
+			err = exportProjectArchive(
+				exportTarget.target, exportTarget.rpPath, exportTarget.bpPath, ctx)
+			if err != nil {
+				return burrito.PassError(err)
+			}
+			measure.End()
+		} else {
+			// Move is only safe when there is exactly one active target
+			// and symlink export is off, since tmp/ is the sole source and
+			// moving from a symlinked tmp would destroy the first target.
+			canMove := len(activeTargets) == 1 && !useSymlink
+			err = exportProjectRpAndBp(
+				exportTarget.target, exportTarget.rpPath, exportTarget.bpPath,
+				ctx, canMove)
+			if err != nil {
+				return burrito.PassError(err)
+			}
+		}
+	}
+	// Export data once (not per target)
+	measure := MeasureStart("Export - ExportData")
+	err = exportProjectData(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	measure.End()
 	return nil
 }
 
@@ -393,7 +578,7 @@ func exportProjectRpAndBp(exportTarget ExportTarget, rpPath, bpPath string, ctx
 
 	var err error
 	if ctx.DisableSizeTimeCheck {
-		MeasureStart("Export - Clean")
+		measure := MeasureStart("Export - Clean")
 		if err := removeJunctionSafe(bpPath); err != nil {
 			return burrito.WrapErrorf(
 				err, "Failed to clear behavior pack from build path %q.\n"+
@@ -404,8 +589,9 @@ func exportProjectRpAndBp(exportTarget ExportTarget, rpPath, bpPath string, ctx
 				err, "Failed to clear resource pack from build path %q.\n"+
 					"Are user permissions correct?", rpPath)
 		}
+		measure.End()
 	}
-	MeasureStart("Export - MoveOrCopy")
+	measure := MeasureStart("Export - MoveOrCopy")
 	absWorkingDir, err := GetAbsoluteWorkingDirectory(dotRegolithPath)
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
@@ -441,6 +627,7 @@ func exportProjectRpAndBp(exportTarget ExportTarget, rpPath, bpPath string, ctx
 	}
 
 	wg.Wait()
+	measure.End()
 	close(errChan)
 	for e := range errChan {
 		if e != nil {
@@ -471,11 +658,10 @@ func copyExportPath(source, destination string, makeReadOnly bool) error {
 	return nil
 }
 
-// exportProjectData is a helper function for ExportProject. It exports the 'data'
-// folder back to the project's source files for the filters that opted-in for
-// that with exportProjectData option.
-func exportProjectData(profile Profile, ctx RunContext) error {
-	dataPath := ctx.Config.DataPath
+// dataExportFilterNames returns the names of the filters of the profile that
+// opt-in to the data export process. The data of these filters is exported
+// back to the project's source files.
+func dataExportFilterNames(profile Profile, ctx RunContext) ([]string, error) {
 	dotRegolithPath := ctx.DotRegolithPath
 	// List the names of the filters that opt-in to the data export process
 	var exportedFilterNames []string
@@ -505,11 +691,49 @@ func exportProjectData(profile Profile, ctx RunContext) error {
 		return nil
 	}, true)
 	if err != nil {
-		return burrito.WrapError(err, "Failed to walk the list of the filters.")
+		return nil, burrito.WrapError(err, "Failed to walk the list of the filters.")
+	}
+	return exportedFilterNames, nil
+}
+
+// exportProjectData is a helper function for ExportProject. It exports the 'data'
+// folder back to the project's source files for the filters that opted-in for
+// that with exportProjectData option.
+func exportProjectData(profile Profile, ctx RunContext) error {
+	exportedFilterNames, err := prepareDataExport(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
 	}
 	if len(exportedFilterNames) == 0 {
 		return nil
 	}
+	// Create revertible operations object
+	backupPath := filepath.Join(ctx.DotRegolithPath, ".dataBackup")
+	revertibleOps, err := NewRevertibleFsOperations(backupPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, newRevertibleFsOperationsError, backupPath)
+	}
+	err = exportDataOfFilters(exportedFilterNames, ctx, revertibleOps)
+	if err != nil {
+		return revertFsOperations(revertibleOps, err)
+	}
+	if err := revertibleOps.Close(); err != nil {
+		return burrito.PassError(err)
+	}
+	return nil
+}
+
+// prepareDataExport returns the names of the filters that export their data
+// and makes sure that the data path exists if there are any.
+func prepareDataExport(profile Profile, ctx RunContext) ([]string, error) {
+	dataPath := ctx.Config.DataPath
+	exportedFilterNames, err := dataExportFilterNames(profile, ctx)
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	if len(exportedFilterNames) == 0 {
+		return nil, nil
+	}
 	// The root of the data path cannot be deleted because the
 	// "regolith watch" function would stop watching the file changes
 	// (due to Windows API limitation).
@@ -520,17 +744,20 @@ func exportProjectData(profile Profile, ctx RunContext) error {
 			err1 = os.MkdirAll(dataPath, 0755)
 		}
 		if err1 != nil {
-			return burrito.WrapErrorf(err, osReadDirError, dataPath)
+			return nil, burrito.WrapErrorf(err, osReadDirError, dataPath)
 		}
 	}
-	// Create revertible operations object
-	backupPath := filepath.Join(dotRegolithPath, ".dataBackup")
-	revertibleOps, err := NewRevertibleFsOperations(backupPath)
-	if err != nil {
-		return burrito.WrapErrorf(err, newRevertibleFsOperationsError, backupPath)
-	}
-	// Export data
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(dotRegolithPath)
+	return exportedFilterNames, nil
+}
+
+// exportDataOfFilters exports the data of the filters from the tmp
+// directory to the data path using the revertible operations. The caller is
+// responsible for reverting the operations if it fails.
+func exportDataOfFilters(
+	exportedFilterNames []string, ctx RunContext, revertibleOps *revertibleFsOperations,
+) error {
+	dataPath := ctx.Config.DataPath
+	absWorkingDir, err := GetAbsoluteWorkingDirectory(ctx.DotRegolithPath)
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -540,15 +767,7 @@ func exportProjectData(profile Profile, ctx RunContext) error {
 		if _, err := os.Stat(targetPath); err == nil {
 			err = revertibleOps.Delete(targetPath)
 			if err != nil {
-				handlerError := revertibleOps.Undo()
-				mainError := burrito.WrapErrorf(err, updateSourceFilesError, targetPath)
-				if handlerError != nil {
-					return burrito.GroupErrors(mainError, burrito.WrapError(handlerError, fsUndoError))
-				}
-				if handlerError := revertibleOps.Close(); handlerError != nil {
-					return burrito.GroupErrors(mainError, handlerError)
-				}
-				return mainError
+				return burrito.WrapErrorf(err, updateSourceFilesError, targetPath)
 			}
 		} else if os.IsNotExist(err) {
 			err = os.MkdirAll(targetPath, 0755)
@@ -566,17 +785,169 @@ func exportProjectData(profile Profile, ctx RunContext) error {
 		// Copy data
 		err = revertibleOps.MoveOrCopyDir(sourcePath, targetPath)
 		if err != nil {
-			handlerError := revertibleOps.Undo()
-			mainError := burrito.WrapErrorf(err, moveOrCopyError, sourcePath, targetPath)
-			if handlerError != nil {
-				return burrito.GroupErrors(mainError, burrito.WrapError(handlerError, fsUndoError))
+			return burrito.WrapErrorf(err, moveOrCopyError, sourcePath, targetPath)
+		}
+	}
+	return nil
+}
+
+// revertFsOperations undoes the revertible operations after the error and
+// closes them. It returns the error grouped with the errors of reverting the
+// operations.
+func revertFsOperations(revertibleOps *revertibleFsOperations, err error) error {
+	if handlerError := revertibleOps.Undo(); handlerError != nil {
+		return burrito.GroupErrors(err, burrito.WrapError(handlerError, fsUndoError))
+	}
+	if handlerError := revertibleOps.Close(); handlerError != nil {
+		return burrito.GroupErrors(err, handlerError)
+	}
+	return err
+}
+
+// stagedExportPath is a path of the transactional export, staged in the
+// .regolith directory.
+type stagedExportPath struct {
+	staged string
+	target string
+}
+
+// getStagingPath returns the directory in the .regolith directory, to which
+// the transactional export writes the files before swapping them in.
+func getStagingPath(dotRegolithPath string) string {
+	return filepath.Join(dotRegolithPath, ".exportStaging")
+}
+
+// exportProjectTransactional is a helper function for ExportProject used
+// when the "transactionalExport" property of the profile is enabled. It
+// exports the packs to all export targets and the data of the filters as a
+// single transaction. The packs are staged in the .regolith directory first,
+// and then swapped in. If any part of the export fails, all of the export
+// paths and the data are restored.
+func exportProjectTransactional(
+	profile Profile, activeTargets []resolvedExportTarget,
+	previousManifests map[string]*packManifest, ctx RunContext,
+	useSymlink bool,
+) error {
+	exportedFilterNames, err := prepareDataExport(profile, ctx)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	absWorkingDir, err := GetAbsoluteWorkingDirectory(ctx.DotRegolithPath)
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	// Stage the packs of all export targets. The staged files can be left
+	// by an interrupted export.
+	stagingPath := getStagingPath(ctx.DotRegolithPath)
+	if err := removeJunctionSafe(stagingPath); err != nil {
+		return burrito.PassError(err)
+	}
+	defer func() {
+		// The staged files only remain if the export failed
+		if err := removeJunctionSafe(stagingPath); err != nil {
+			Logger.Warnf("Failed to remove the staged export files.\n"+
+				"Path: %s\nError: %s", stagingPath, err.Error())
+		}
+	}()
+	var stagedPaths []stagedExportPath
+	stage := func(target string) string {
+		staged := filepath.Join(stagingPath, strconv.Itoa(len(stagedPaths)))
+		stagedPaths = append(stagedPaths, stagedExportPath{staged, target})
+		return staged
+	}
+	for i, exportTarget := range activeTargets {
+		// Symlink export already placed files for the only active target.
+		if useSymlink && i == 0 {
+			Logger.Debugf("Symlink export is enabled. Skipping RP and BP export.")
+			continue
+		}
+		if isArchiveExportTarget(exportTarget.target.Target) {
+			bpStaged := stage(exportTarget.bpPath)
+			rpStaged := ""
+			if exportTarget.target.Target != "mcaddon" {
+				rpStaged = stage(exportTarget.rpPath)
 			}
-			if handlerError := revertibleOps.Close(); handlerError != nil {
-				return burrito.GroupErrors(mainError, handlerError)
+			err = exportProjectArchive(exportTarget.target, rpStaged, bpStaged, ctx)
+			if err != nil {
+				return burrito.PassError(err)
+			}
+			continue
+		}
+		for _, pack := range []struct{ path, subpathInTmp, packType string }{
+			{exportTarget.bpPath, "BP", "behavior"},
+			{exportTarget.rpPath, "RP", "resource"},
+		} {
+			staged := stage(pack.path)
+			Logger.Infof("Staging %s pack for \"%s\".", pack.packType, pack.path)
+			err = copyExportPath(
+				filepath.Join(absWorkingDir, pack.subpathInTmp), staged,
+				exportTarget.target.ReadOnly)
+			if err != nil {
+				return burrito.WrapErrorf(err, "Failed to export %s pack.", pack.packType)
 			}
-			return mainError
 		}
 	}
+	// Swap the staged packs in and export the data
+	backupPath := filepath.Join(ctx.DotRegolithPath, ".exportBackup")
+	revertibleOps, err := NewRevertibleFsOperations(backupPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, newRevertibleFsOperationsError, backupPath)
+	}
+	for _, stagedPath := range stagedPaths {
+		// The archives of the empty packs aren't created
+		if _, err := os.Stat(stagedPath.staged); os.IsNotExist(err) {
+			continue
+		}
+		err = revertibleOps.Swap(stagedPath.staged, stagedPath.target)
+		if err != nil {
+			Logger.Warnf("Reverting changes...")
+			return revertFsOperations(revertibleOps, burrito.WrapErrorf(
+				err, transactionalExportError, stagedPath.target))
+		}
+	}
+	err = exportDataOfFilters(exportedFilterNames, ctx, revertibleOps)
+	if err != nil {
+		Logger.Warnf("Reverting changes...")
+		return revertFsOperations(revertibleOps, err)
+	}
+	err = registerAllServerPacks(
+		activeTargets, "", previousManifests,
+		&serverJsonFiles{revertibleOps: revertibleOps})
+	if err != nil {
+		Logger.Warnf("Reverting changes...")
+		return revertFsOperations(revertibleOps, err)
+	}
+	if err := revertibleOps.Close(); err != nil {
+		return burrito.PassError(err)
+	}
+	return nil
+}
+
+// registerServerPacksRevertibly is a helper function for ExportProject used
+// when the export isn't transactional. It registers the packs of the
+// "server" export targets, and restores all of the changed server files if
+// any of the registrations fails.
+func registerServerPacksRevertibly(
+	activeTargets []resolvedExportTarget,
+	previousManifests map[string]*packManifest, ctx RunContext,
+) error {
+	if !slices.ContainsFunc(activeTargets, func(t resolvedExportTarget) bool {
+		return t.target.Target == "server"
+	}) {
+		return nil
+	}
+	backupPath := filepath.Join(ctx.DotRegolithPath, ".serverBackup")
+	revertibleOps, err := NewRevertibleFsOperations(backupPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, newRevertibleFsOperationsError, backupPath)
+	}
+	err = registerAllServerPacks(
+		activeTargets, "", previousManifests,
+		&serverJsonFiles{revertibleOps: revertibleOps})
+	if err != nil {
+		Logger.Warnf("Reverting changes...")
+		return revertFsOperations(revertibleOps, err)
+	}
 	if err := revertibleOps.Close(); err != nil {
 		return burrito.PassError(err)
 	}
@@ -0,0 +1,279 @@
+package regolith
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// archiveModTime is the modification time stored for every entry of the
+// archives created by the "mcpack" and "mcaddon" export targets. Using a
+// constant value makes the archives reproducible.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	// defaultMcpackPath is the default path template used by the "mcpack"
+	// export target.
+	defaultMcpackPath = "build/{packName}.mcpack"
+
+	// defaultMcaddonPath is the default path template used by the "mcaddon"
+	// export target.
+	defaultMcaddonPath = "build/{name}.mcaddon"
+)
+
+// isArchiveExportTarget returns true if the export target creates archive
+// files instead of exporting the packs into directories.
+func isArchiveExportTarget(target string) bool {
+	return target == "mcpack" || target == "mcaddon"
+}
+
+// getArchiveExportPaths returns the paths of the archives created by the
+// "mcpack" and "mcaddon" export targets. The paths are created from the
+// "path" property of the export target which can use the following
+// placeholders: {name} (project name), {bpName}, {rpName} and {packName}
+// (the name of the pack stored in the archive, only for "mcpack"). For the
+// "mcaddon" target, both returned paths point to the same archive. For the
+// "mcpack" target, the paths must be different, so that one pack doesn't
+// overwrite the other.
+func getArchiveExportPaths(
+	exportTarget ExportTarget, projectName, bpName, rpName string,
+) (bpPath string, rpPath string, err error) {
+	template := exportTarget.Path
+	switch exportTarget.Target {
+	case "mcpack":
+		if template == "" {
+			template = defaultMcpackPath
+		}
+		if !strings.Contains(template, "{packName}") {
+			return "", "", burrito.WrappedErrorf(
+				"The \"path\" of the \"mcpack\" export target must contain "+
+					"the {packName} placeholder.\n"+
+					"Path: %s", template)
+		}
+	case "mcaddon":
+		if template == "" {
+			template = defaultMcaddonPath
+		}
+	default:
+		return "", "", burrito.WrappedErrorf(
+			"Export target %q is not an archive target", exportTarget.Target)
+	}
+	expand := func(packName string) (string, error) {
+		path := strings.NewReplacer(
+			"{name}", projectName,
+			"{bpName}", bpName,
+			"{rpName}", rpName,
+			"{packName}", packName,
+		).Replace(template)
+		path, err := ResolvePath(path)
+		if err != nil {
+			return "", burrito.WrapErrorf(
+				err, "Failed to resolve archive path.\nPath: %s", path)
+		}
+		return path, nil
+	}
+	bpPath, err = expand(bpName)
+	if err != nil {
+		return "", "", burrito.PassError(err)
+	}
+	rpPath, err = expand(rpName)
+	if err != nil {
+		return "", "", burrito.PassError(err)
+	}
+	if exportTarget.Target == "mcpack" && strings.EqualFold(bpPath, rpPath) {
+		return "", "", burrito.WrappedErrorf(mcpackPathCollisionError, bpPath)
+	}
+	return bpPath, rpPath, nil
+}
+
+// exportProjectArchive is a helper function for ExportProject. It packs the
+// 'RP' and 'BP' folders from the tmp directory into ".mcpack" files or into a
+// single ".mcaddon" file, depending on the export target. Empty packs are
+// skipped.
+func exportProjectArchive(
+	exportTarget ExportTarget, rpPath, bpPath string, ctx RunContext,
+) error {
+	absWorkingDir, err := GetAbsoluteWorkingDirectory(ctx.DotRegolithPath)
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	bpName, rpName, err := GetExportNames(exportTarget, ctx)
+	if err != nil {
+		return burrito.WrapError(err, "Failed to get the export names.")
+	}
+	if exportTarget.Target == "mcaddon" {
+		bpName, rpName = mcaddonFolderNames(bpName, rpName)
+	}
+	packs := []archivePack{
+		{filepath.Join(absWorkingDir, "BP"), bpName},
+		{filepath.Join(absWorkingDir, "RP"), rpName},
+	}
+	// Remove the empty packs
+	nonEmptyPacks := make([]archivePack, 0, len(packs))
+	for _, pack := range packs {
+		if _, err := os.Stat(pack.source); os.IsNotExist(err) {
+			continue
+		}
+		empty, err := IsDirEmpty(pack.source)
+		if err != nil {
+			return burrito.WrapErrorf(err, isDirEmptyError, pack.source)
+		}
+		if !empty {
+			nonEmptyPacks = append(nonEmptyPacks, pack)
+		}
+	}
+	if exportTarget.Target == "mcaddon" {
+		if len(nonEmptyPacks) == 0 {
+			Logger.Warnf("Both packs are empty. Skipping creation of %q.", bpPath)
+			return nil
+		}
+		Logger.Infof("Exporting packs to \"%s\".", bpPath)
+		err = writeArchive(bpPath, nonEmptyPacks)
+		if err != nil {
+			return burrito.WrapErrorf(err, "Failed to create the addon archive.\nPath: %s", bpPath)
+		}
+		return nil
+	}
+	archivePaths := map[string]string{
+		packs[0].source: bpPath,
+		packs[1].source: rpPath,
+	}
+	for _, pack := range nonEmptyPacks {
+		path := archivePaths[pack.source]
+		Logger.Infof("Exporting %q pack to \"%s\".", pack.name, path)
+		// The pack files are stored directly in the root of the .mcpack file
+		err = writeArchive(path, []archivePack{{pack.source, ""}})
+		if err != nil {
+			return burrito.WrapErrorf(err, "Failed to create the pack archive.\nPath: %s", path)
+		}
+	}
+	return nil
+}
+
+// mcaddonFolderNames returns the names of the folders of the packs in the
+// ".mcaddon" file. If the packs have the same name, the "_bp" and "_rp"
+// suffixes are added, so that the files of the packs don't end up in the
+// same folder. The names are compared without case, because the archive can
+// be extracted on a case-insensitive file system.
+func mcaddonFolderNames(bpName, rpName string) (string, string) {
+	if strings.EqualFold(bpName, rpName) {
+		return bpName + "_bp", rpName + "_rp"
+	}
+	return bpName, rpName
+}
+
+// archivePack is a directory that should be stored in an archive under the
+// given name. An empty name stores the files in the root of the archive.
+type archivePack struct {
+	source string
+	name   string
+}
+
+// writeArchive creates a zip archive at the target path with the contents of
+// the packs. The entries are sorted and use a fixed modification time and
+// file mode, so the same input always produces the same archive. The archive
+// is written to a temporary file first and then moved to the target path.
+func writeArchive(target string, packs []archivePack) error {
+	type archiveEntry struct {
+		name   string
+		source string
+		isDir  bool
+	}
+	var entries []archiveEntry
+	for _, pack := range packs {
+		err := filepath.WalkDir(pack.source, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(pack.source, path)
+			if err != nil {
+				return burrito.WrapErrorf(err, filepathRelError, pack.source, path)
+			}
+			name := filepath.ToSlash(relPath)
+			if name == "." {
+				if pack.name == "" {
+					return nil
+				}
+				name = pack.name
+			} else if pack.name != "" {
+				name = pack.name + "/" + name
+			}
+			if d.IsDir() {
+				name += "/"
+			}
+			entries = append(entries, archiveEntry{name, path, d.IsDir()})
+			return nil
+		})
+		if err != nil {
+			return burrito.WrapErrorf(err, osWalkError, pack.source)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, filepath.Dir(target))
+	}
+	tmpTarget := target + ".tmp"
+	file, err := os.Create(tmpTarget)
+	if err != nil {
+		return burrito.WrapErrorf(err, osCreateError, tmpTarget)
+	}
+	writeEntries := func() error {
+		zipWriter := zip.NewWriter(file)
+		for _, entry := range entries {
+			header := &zip.FileHeader{
+				Name:     entry.name,
+				Method:   zip.Deflate,
+				Modified: archiveModTime,
+			}
+			if entry.isDir {
+				header.Method = zip.Store
+				header.SetMode(fs.ModeDir | 0755)
+			} else {
+				header.SetMode(0644)
+			}
+			writer, err := zipWriter.CreateHeader(header)
+			if err != nil {
+				return burrito.WrapErrorf(err, fileWriteError, tmpTarget)
+			}
+			if entry.isDir {
+				continue
+			}
+			source, err := os.Open(entry.source)
+			if err != nil {
+				return burrito.WrapErrorf(err, osOpenError, entry.source)
+			}
+			_, err = io.Copy(writer, source)
+			source.Close()
+			if err != nil {
+				return burrito.WrapErrorf(err, osCopyError, entry.source, tmpTarget)
+			}
+		}
+		if err := zipWriter.Close(); err != nil {
+			return burrito.WrapErrorf(err, fileWriteError, tmpTarget)
+		}
+		return nil
+	}
+	err = writeEntries()
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = burrito.WrapErrorf(closeErr, fileWriteError, tmpTarget)
+	}
+	if err != nil {
+		os.Remove(tmpTarget)
+		return burrito.PassError(err)
+	}
+	if err := os.Rename(tmpTarget, target); err != nil {
+		os.Remove(tmpTarget)
+		return burrito.WrapErrorf(err, osRenameError, tmpTarget, target)
+	}
+	return nil
+}
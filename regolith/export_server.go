@@ -0,0 +1,431 @@
+package regolith
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// The "server" export target exports the packs to a Bedrock dedicated server
+// and registers them in a world of the server, so that the server loads them
+// without editing its files by hand. The packs are registered with the UUIDs
+// and the versions from their exported manifest.json files.
+
+const (
+	// defaultServerLevelName is the name of the world used by the dedicated
+	// server if its server.properties file doesn't set the "level-name".
+	defaultServerLevelName = "Bedrock level"
+
+	// validKnownPacksFileVersion is the version of the valid_known_packs.json
+	// file written to the new files.
+	validKnownPacksFileVersion = 2
+)
+
+// getServerExportPaths returns the paths of the packs exported by the
+// "server" export target. The packs are exported to the directories of the
+// world, if the export target has a "worldName", or to the development pack
+// directories of the server otherwise.
+func getServerExportPaths(
+	exportTarget ExportTarget, bpName, rpName string,
+) (bpPath string, rpPath string, err error) {
+	if exportTarget.ServerPath == "" {
+		return "", "", burrito.WrappedError(
+			"The \"server\" export target requires the \"serverPath\" property.")
+	}
+	serverPath, err := ResolvePath(exportTarget.ServerPath)
+	if err != nil {
+		return "", "", burrito.WrapError(err, "Failed to resolve server path.")
+	}
+	if stat, err := os.Stat(serverPath); err != nil {
+		return "", "", burrito.WrapErrorf(err, osStatErrorAny, serverPath)
+	} else if !stat.IsDir() {
+		return "", "", burrito.WrappedErrorf(isDirNotADirError, serverPath)
+	}
+	if exportTarget.WorldName != "" {
+		worldPath := filepath.Join(serverPath, "worlds", exportTarget.WorldName)
+		bpPath = filepath.Join(worldPath, "behavior_packs", bpName)
+		rpPath = filepath.Join(worldPath, "resource_packs", rpName)
+		return bpPath, rpPath, nil
+	}
+	bpPath = filepath.Join(serverPath, "development_behavior_packs", bpName)
+	rpPath = filepath.Join(serverPath, "development_resource_packs", rpName)
+	return bpPath, rpPath, nil
+}
+
+// getServerLevelName returns the name of the world used by the dedicated
+// server from the "level-name" property of its server.properties file.
+func getServerLevelName(serverPath string) string {
+	path := filepath.Join(serverPath, "server.properties")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		Logger.Warnf(
+			"Unable to read %q. Using the default world name %q.",
+			path, defaultServerLevelName)
+		return defaultServerLevelName
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && strings.TrimSpace(key) == "level-name" {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return defaultServerLevelName
+}
+
+// packManifest is the part of the manifest.json file of a pack used for
+// registering the pack in a world.
+type packManifest struct {
+	Header struct {
+		UUID    string          `json:"uuid"`
+		Version json.RawMessage `json:"version"`
+	} `json:"header"`
+}
+
+// readPackManifest reads the manifest.json file of the pack. It returns nil
+// if the pack doesn't have a manifest.
+func readPackManifest(packPath string) (*packManifest, error) {
+	path := filepath.Join(packPath, "manifest.json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, path)
+	}
+	var result packManifest
+	if err := unmarshalJSONC(data, &result, path); err != nil {
+		return nil, burrito.PassError(err)
+	}
+	if result.Header.UUID == "" || len(result.Header.Version) == 0 {
+		return nil, burrito.WrappedErrorf(invalidPackManifestError, path)
+	}
+	return &result, nil
+}
+
+// versionArray returns the version of the pack in the array form used by the
+// world_behavior_packs.json and world_resource_packs.json files. The
+// versions written as "major.minor.patch" strings are converted to arrays.
+// Other versions are returned unchanged.
+func (m *packManifest) versionArray() json.RawMessage {
+	var version string
+	if json.Unmarshal(m.Header.Version, &version) != nil {
+		return m.Header.Version
+	}
+	// Ignore the prerelease and the build metadata
+	version, _, _ = strings.Cut(version, "-")
+	version, _, _ = strings.Cut(version, "+")
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return m.Header.Version
+	}
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return m.Header.Version
+		}
+		numbers[i] = number
+	}
+	result, _ := json.Marshal(numbers)
+	return result
+}
+
+// versionString returns the version of the pack in the "major.minor.patch"
+// form used by the valid_known_packs.json file.
+func (m *packManifest) versionString() string {
+	var version string
+	if json.Unmarshal(m.Header.Version, &version) == nil {
+		return version
+	}
+	var numbers []int
+	if json.Unmarshal(m.Header.Version, &numbers) != nil {
+		return string(m.Header.Version)
+	}
+	parts := make([]string, len(numbers))
+	for i, number := range numbers {
+		parts[i] = strconv.Itoa(number)
+	}
+	return strings.Join(parts, ".")
+}
+
+// readPreviousServerManifests is a helper function for ExportProject. It
+// reads the manifests of the packs exported to the "server" export targets
+// by the previous export, before they are replaced. The result maps the
+// export paths of the packs to their manifests. The packs without a valid
+// manifest are skipped.
+func readPreviousServerManifests(
+	activeTargets []resolvedExportTarget,
+) map[string]*packManifest {
+	result := map[string]*packManifest{}
+	for _, exportTarget := range activeTargets {
+		if exportTarget.target.Target != "server" {
+			continue
+		}
+		for _, packPath := range []string{exportTarget.bpPath, exportTarget.rpPath} {
+			manifest, err := readPackManifest(packPath)
+			if err != nil {
+				Logger.Debugf(
+					"Unable to read the manifest of the previously exported "+
+						"pack.\n%s", err.Error())
+				continue
+			}
+			if manifest != nil {
+				result[packPath] = manifest
+			}
+		}
+	}
+	return result
+}
+
+// serverJsonFiles reads and writes the JSON files of the dedicated servers
+// and their worlds. The files are written with the revertible operations, so
+// that they can be restored if the export fails. In the dry runs, the
+// revertible operations are nil and the files aren't written. Their new
+// content is stored instead and returned by the following reads, so the
+// later changes of the same file are based on the earlier ones.
+type serverJsonFiles struct {
+	revertibleOps *revertibleFsOperations
+
+	// planned maps the paths of the files to their new content in the dry
+	// runs.
+	planned map[string][]byte
+
+	// plannedPaths are the keys of planned in the order of the first writes.
+	plannedPaths []string
+}
+
+// dryRun returns true if the files aren't written.
+func (f *serverJsonFiles) dryRun() bool {
+	return f.revertibleOps == nil
+}
+
+// readFile reads the file, or returns its planned content in the dry runs.
+func (f *serverJsonFiles) readFile(path string) ([]byte, error) {
+	if data, ok := f.planned[path]; ok {
+		return data, nil
+	}
+	return os.ReadFile(path)
+}
+
+// writeFile writes the file with the revertible operations, or stores its
+// planned content in the dry runs.
+func (f *serverJsonFiles) writeFile(path string, data []byte) error {
+	if !f.dryRun() {
+		return f.revertibleOps.WriteFile(path, data)
+	}
+	if f.planned == nil {
+		f.planned = map[string][]byte{}
+	}
+	if _, ok := f.planned[path]; !ok {
+		f.plannedPaths = append(f.plannedPaths, path)
+	}
+	f.planned[path] = data
+	return nil
+}
+
+// registerAllServerPacks registers the packs of all "server" export targets
+// (see registerServerPacks).
+func registerAllServerPacks(
+	activeTargets []resolvedExportTarget, sourcePath string,
+	previousManifests map[string]*packManifest, files *serverJsonFiles,
+) error {
+	for _, exportTarget := range activeTargets {
+		if exportTarget.target.Target != "server" {
+			continue
+		}
+		err := registerServerPacks(
+			exportTarget, sourcePath, previousManifests, files)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+	}
+	return nil
+}
+
+// registerServerPacks is a helper function for ExportProject. It registers
+// the packs exported by the "server" export target in the world of the
+// server. The packs exported to the development pack directories are also
+// added to the valid_known_packs.json file of the server. The entries of
+// the previous versions of the packs (see readPreviousServerManifests) are
+// removed. The manifests of the registered packs are read from the exported
+// packs, or from the BP and RP directories of the sourcePath if it isn't
+// empty (in the dry runs, when the packs aren't exported yet).
+func registerServerPacks(
+	exportTarget resolvedExportTarget, sourcePath string,
+	previousManifests map[string]*packManifest, files *serverJsonFiles,
+) error {
+	// The paths are taken from the resolved pack paths (see
+	// getServerExportPaths), because the properties of the target can use
+	// templates.
+	isWorldTarget := exportTarget.target.WorldName != ""
+	var serverPath, worldPath string
+	if isWorldTarget {
+		// <server>/worlds/<world>/behavior_packs/<pack>
+		worldPath = filepath.Dir(filepath.Dir(exportTarget.bpPath))
+		serverPath = filepath.Dir(filepath.Dir(worldPath))
+	} else {
+		// <server>/development_behavior_packs/<pack>
+		serverPath = filepath.Dir(filepath.Dir(exportTarget.bpPath))
+		worldPath = filepath.Join(serverPath, "worlds", getServerLevelName(serverPath))
+	}
+	packs := []struct {
+		path      string
+		source    string
+		worldFile string
+	}{
+		{exportTarget.bpPath, exportTarget.bpPath, "world_behavior_packs.json"},
+		{exportTarget.rpPath, exportTarget.rpPath, "world_resource_packs.json"},
+	}
+	if sourcePath != "" {
+		packs[0].source = filepath.Join(sourcePath, "BP")
+		packs[1].source = filepath.Join(sourcePath, "RP")
+	}
+	for _, pack := range packs {
+		manifest, err := readPackManifest(pack.source)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		if manifest == nil {
+			Logger.Debugf("Pack %q has no manifest. Skipping its registration.", pack.path)
+			continue
+		}
+		previous := previousManifests[pack.path]
+		worldFile := filepath.Join(worldPath, pack.worldFile)
+		err = updateWorldPacksFile(worldFile, manifest, previous, files)
+		if err != nil {
+			return burrito.WrapErrorf(err, registerServerPackError, pack.path, worldFile)
+		}
+		if !files.dryRun() {
+			Logger.Infof("Registered pack %q in %q.", filepath.Base(pack.path), worldFile)
+		}
+		if isWorldTarget {
+			continue // The world packs don't need to be in valid_known_packs.json
+		}
+		knownPacksFile := filepath.Join(serverPath, "valid_known_packs.json")
+		relPath, err := filepath.Rel(serverPath, pack.path)
+		if err != nil {
+			return burrito.WrapErrorf(err, filepathRelError, serverPath, pack.path)
+		}
+		err = updateValidKnownPacksFile(
+			knownPacksFile, filepath.ToSlash(relPath), manifest, previous, files)
+		if err != nil {
+			return burrito.WrapErrorf(err, registerServerPackError, pack.path, knownPacksFile)
+		}
+	}
+	return nil
+}
+
+// updateWorldPacksFile adds the pack to the world_behavior_packs.json or the
+// world_resource_packs.json file of a world, or updates its version if the
+// file already lists the pack. The entry of the previous version of the pack
+// is removed if the pack got a new UUID. The previous manifest can be nil.
+// The file is created if it doesn't exist.
+func updateWorldPacksFile(
+	path string, manifest, previous *packManifest, files *serverJsonFiles,
+) error {
+	var entries []map[string]any
+	if err := readServerJsonFile(path, &entries, files); err != nil {
+		return burrito.PassError(err)
+	}
+	var version any
+	if err := json.Unmarshal(manifest.versionArray(), &version); err != nil {
+		return burrito.WrapErrorf(err, jsonUnmarshalError, path)
+	}
+	entry := map[string]any{"pack_id": manifest.Header.UUID, "version": version}
+	entries = replaceServerPackEntries(entries, entry, func(existing map[string]any) bool {
+		id, _ := existing["pack_id"].(string)
+		return id == manifest.Header.UUID ||
+			(previous != nil && id == previous.Header.UUID)
+	})
+	return writeServerJsonFile(path, entries, files)
+}
+
+// updateValidKnownPacksFile adds the pack to the valid_known_packs.json file
+// of the server, or updates its entry if the file already lists the pack.
+// The entries of the previous version of the pack and the other entries
+// with the same path are removed. The previous manifest can be nil. The file
+// is created if it doesn't exist.
+func updateValidKnownPacksFile(
+	path, packPath string, manifest, previous *packManifest,
+	files *serverJsonFiles,
+) error {
+	var entries []map[string]any
+	if err := readServerJsonFile(path, &entries, files); err != nil {
+		return burrito.PassError(err)
+	}
+	if len(entries) == 0 {
+		entries = append(entries, map[string]any{"file_version": validKnownPacksFileVersion})
+	}
+	entry := map[string]any{
+		"file_system": "RawPath",
+		"path":        packPath,
+		"uuid":        manifest.Header.UUID,
+		"version":     manifest.versionString(),
+	}
+	entries = replaceServerPackEntries(entries, entry, func(existing map[string]any) bool {
+		id, _ := existing["uuid"].(string)
+		existingPath, _ := existing["path"].(string)
+		return id == manifest.Header.UUID || existingPath == packPath ||
+			(previous != nil && id == previous.Header.UUID)
+	})
+	return writeServerJsonFile(path, entries, files)
+}
+
+// replaceServerPackEntries replaces the first entry of the list that matches
+// the pack with the new entry and removes the other matching entries. If
+// there is no such entry, the new entry is appended to the list.
+func replaceServerPackEntries(
+	entries []map[string]any, entry map[string]any,
+	matches func(map[string]any) bool,
+) []map[string]any {
+	result := make([]map[string]any, 0, len(entries)+1)
+	replaced := false
+	for _, existing := range entries {
+		if !matches(existing) {
+			result = append(result, existing)
+		} else if !replaced {
+			result = append(result, entry)
+			replaced = true
+		}
+	}
+	if !replaced {
+		result = append(result, entry)
+	}
+	return result
+}
+
+// readServerJsonFile reads the JSON file of the dedicated server or of its
+// world. The missing file is treated as an empty list.
+func readServerJsonFile(path string, v any, files *serverJsonFiles) error {
+	data, err := files.readFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return burrito.WrapErrorf(err, fileReadError, path)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil
+	}
+	if err := unmarshalJSONC(data, v, path); err != nil {
+		return burrito.PassError(err)
+	}
+	return nil
+}
+
+// writeServerJsonFile writes the JSON file of the dedicated server or of its
+// world, creating its parent directory if necessary.
+func writeServerJsonFile(path string, v any, files *serverJsonFiles) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil { // This should never happen.
+		return burrito.WrapError(err, "Failed to marshal the server JSON file.")
+	}
+	if err := files.writeFile(path, data); err != nil {
+		return burrito.PassError(err)
+	}
+	return nil
+}
@@ -1,19 +1,52 @@
 package regolith
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
 )
 
 const EditedFilesPath = "cache/edited_files.json"
 
-// PathList is an alias for []string. It's used to store a list of file paths.
-type filesList = []string
+// exportedFile is the state of a file exported by Regolith. It's used to
+// detect the files modified outside of Regolith after the export.
+type exportedFile struct {
+	Hash    string    `json:"hash"`
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+}
+
+// filesList maps the paths of the exported files, relative to the export
+// path, to their states.
+type filesList map[string]exportedFile
+
+// UnmarshalJSON reads the filesList. It also accepts the lists of file names
+// stored by the older versions of Regolith. The files from these lists have
+// no hash, so they can't be checked for modifications.
+func (l *filesList) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*l = make(filesList, len(names))
+		for _, name := range names {
+			(*l)[strings.ReplaceAll(name, "\\", "/")] = exportedFile{}
+		}
+		return nil
+	}
+	var files map[string]exportedFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return err
+	}
+	*l = files
+	return nil
+}
 
 // EditedFiles is used to load edited_files.json from cache in order
 // to check if the files are safe to delete.
@@ -58,37 +91,50 @@ func (f *EditedFiles) Dump(dotRegolithPath string) error {
 }
 
 // CheckDeletionSafety checks whether it's safe to delete files from rpPath and
-// bpPath based on the lists of removable files from EditedFiles object.
+// bpPath based on the lists of removable files from EditedFiles object. The
+// files created by Regolith but modified outside of Regolith since the
+// export aren't safe to delete.
 func (f *EditedFiles) CheckDeletionSafety(rpPath string, bpPath string) error {
-	files, ok := f.Rp[rpPath]
-	if !ok {
-		files = make([]string, 0)
+	rpModified, bpModified, err := f.FindModifiedFiles(rpPath, bpPath)
+	if err != nil {
+		return burrito.PassError(err)
 	}
-	err := checkDeletionSafety(rpPath, files)
+	if len(rpModified) > 0 || len(bpModified) > 0 {
+		return burrito.WrappedErrorf(
+			modifiedExportedFilesError,
+			formatModifiedFiles(rpPath, rpModified, bpPath, bpModified))
+	}
+	return nil
+}
+
+// FindModifiedFiles returns the paths of the files from rpPath and bpPath,
+// which were created by Regolith, but modified outside of Regolith since the
+// export. The paths are relative to rpPath and bpPath. It returns an error if
+// there are files that weren't created by Regolith.
+func (f *EditedFiles) FindModifiedFiles(
+	rpPath string, bpPath string,
+) (rpModified []string, bpModified []string, err error) {
+	rpModified, err = checkDeletionSafety(rpPath, f.Rp[rpPath])
 	if err != nil {
-		return burrito.WrapError(
+		return nil, nil, burrito.WrapError(
 			err, "Deletion safety check for resource pack failed.")
 	}
-	files, ok = f.Bp[bpPath]
-	if !ok {
-		files = make([]string, 0)
-	}
-	err = checkDeletionSafety(bpPath, files)
+	bpModified, err = checkDeletionSafety(bpPath, f.Bp[bpPath])
 	if err != nil {
-		return burrito.WrapError(
+		return nil, nil, burrito.WrapError(
 			err, "Deletion safety check for behavior pack failed.")
 	}
-	return nil
+	return rpModified, bpModified, nil
 }
 
 // UpdateFromPaths updates the edited files data based on the paths to the
 // resource pack and behavior pack.
 func (f *EditedFiles) UpdateFromPaths(rpPath string, bpPath string) error {
-	rpFiles, err := listFiles(rpPath)
+	rpFiles, err := listFiles(rpPath, f.Rp[rpPath])
 	if err != nil {
 		return burrito.WrapError(err, "Failed to list resource pack files.")
 	}
-	bpFiles, err := listFiles(bpPath)
+	bpFiles, err := listFiles(bpPath, f.Bp[bpPath])
 	if err != nil {
 		return burrito.WrapError(err, "Failed to list behavior pack files.")
 	}
@@ -97,6 +143,47 @@ func (f *EditedFiles) UpdateFromPaths(rpPath string, bpPath string) error {
 	return nil
 }
 
+// BackupModifiedFiles copies the files modified outside of Regolith, found
+// with FindModifiedFiles, to the "RP" and "BP" subdirectories of the
+// backupPath.
+func BackupModifiedFiles(
+	rpPath string, rpModified []string, bpPath string, bpModified []string,
+	backupPath string,
+) error {
+	for _, pack := range []struct {
+		path     string
+		modified []string
+		subpath  string
+	}{
+		{rpPath, rpModified, "RP"},
+		{bpPath, bpModified, "BP"},
+	} {
+		for _, file := range pack.modified {
+			source := filepath.Join(pack.path, file)
+			target := filepath.Join(backupPath, pack.subpath, file)
+			if err := CopyFile(source, target); err != nil {
+				return burrito.PassError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// formatModifiedFiles returns the list of the modified files for the error
+// messages.
+func formatModifiedFiles(
+	rpPath string, rpModified []string, bpPath string, bpModified []string,
+) string {
+	var lines []string
+	for _, file := range rpModified {
+		lines = append(lines, filepath.Join(rpPath, file))
+	}
+	for _, file := range bpModified {
+		lines = append(lines, filepath.Join(bpPath, file))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // NewEditedFiles creates new EditedFiles object with lists of the files from
 // rpPath and bpPath.
 func NewEditedFiles() EditedFiles {
@@ -106,56 +193,80 @@ func NewEditedFiles() EditedFiles {
 	return result
 }
 
-// listFiles returns a slice of strings with paths to all the files
-// starting from "path"
-func listFiles(path string) ([]string, error) {
+// listFiles returns the states of all the files starting from "path". The
+// hashes of the files with the same size and modification time as in the
+// previous list are reused.
+func listFiles(path string, previous filesList) (filesList, error) {
+	result := make(filesList)
 	if _, err := os.Stat(path); err != nil {
 		if os.IsNotExist(err) {
-			return make([]string, 0), nil
+			return result, nil
 		}
 		return nil, burrito.WrapErrorf(err, osStatErrorAny, path)
 	}
-	// 150 is just an arbitrary number I chose to avoid constant memory
-	// allocation while expanding the slice capacity
-	result := make([]string, 0, 150)
 	err := filepath.WalkDir(path,
 		func(s string, d fs.DirEntry, e error) error {
 			if e != nil {
 				return burrito.PassError(e)
 			}
-			if !d.IsDir() {
-				relpath, err := filepath.Rel(path, s)
-				if err != nil {
-					return burrito.WrapErrorf(err, osRelError, path, s)
-				}
-				normalizedRelPath := strings.ReplaceAll(relpath, "\\", "/")
-				result = append(result, normalizedRelPath)
+			if d.IsDir() {
+				return nil
+			}
+			relpath, err := filepath.Rel(path, s)
+			if err != nil {
+				return burrito.WrapErrorf(err, osRelError, path, s)
+			}
+			normalizedRelPath := strings.ReplaceAll(relpath, "\\", "/")
+			info, err := d.Info()
+			if err != nil {
+				return burrito.WrapErrorf(err, osStatErrorAny, s)
 			}
+			state := exportedFile{ModTime: info.ModTime(), Size: info.Size()}
+			if old, ok := previous[normalizedRelPath]; ok && old.Hash != "" &&
+				old.Size == state.Size && old.ModTime.Equal(state.ModTime) {
+				state.Hash = old.Hash
+			} else if state.Hash, err = hashExportedFile(s); err != nil {
+				return burrito.PassError(err)
+			}
+			result[normalizedRelPath] = state
 			return nil
 		})
 	if err != nil {
-		return make([]string, 0), burrito.WrapErrorf(err, osWalkError, path)
+		return make(filesList), burrito.WrapErrorf(err, osWalkError, path)
 	}
 	return result, nil
 }
 
-// checkDeletionSafety checks whether it's safe to delete files from given path
-// based on the list of removable files. It returns nil value when it's safe to
-// delete the files or an error in opposite case.
-func checkDeletionSafety(path string, removableFiles []string) error {
-	removableSet := make(map[string]struct{}, len(removableFiles))
-	for _, f := range removableFiles {
-		normalized := strings.ReplaceAll(f, "\\", "/")
-		removableSet[normalized] = struct{}{}
+// hashExportedFile returns the SHA-256 hash of the content of the file.
+func hashExportedFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", burrito.WrapErrorf(err, osOpenError, path)
 	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", burrito.WrapErrorf(err, fileReadError, path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkDeletionSafety checks whether it's safe to delete files from given path
+// based on the list of removable files. It returns an error if there are
+// files that aren't on the list. Otherwise, it returns the paths of the files
+// modified since they were added to the list, relative to the given path.
+// The modification time and the size of the files are checked first and the
+// hashes are compared only if they're different.
+func checkDeletionSafety(path string, removableFiles filesList) ([]string, error) {
+	var modified []string
 	stats, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil // directory doesn't exist there is nothing to check
+			return nil, nil // directory doesn't exist there is nothing to check
 		}
-		return burrito.WrapErrorf(err, osStatErrorAny, path)
+		return nil, burrito.WrapErrorf(err, osStatErrorAny, path)
 	} else if !stats.IsDir() {
-		return burrito.WrappedErrorf(isDirNotADirError, path)
+		return nil, burrito.WrappedErrorf(isDirNotADirError, path)
 	}
 	err = filepath.WalkDir(path,
 		func(s string, d fs.DirEntry, e error) error {
@@ -173,13 +284,31 @@ func checkDeletionSafety(path string, removableFiles []string) error {
 			const notRegolithFileError = "File is not on the list of files" +
 				" created by Regolith.\nPath: %s"
 			normalizedS := strings.ReplaceAll(s, "\\", "/")
-			if _, ok := removableSet[normalizedS]; !ok {
+			state, ok := removableFiles[normalizedS]
+			if !ok {
 				return burrito.WrappedErrorf(notRegolithFileError, s)
 			}
+			if state.Hash == "" { // Listed by an older version of Regolith
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return burrito.WrapErrorf(err, osStatErrorAny, s)
+			}
+			if info.Size() == state.Size && info.ModTime().Equal(state.ModTime) {
+				return nil
+			}
+			hash, err := hashExportedFile(filepath.Join(path, relpath))
+			if err != nil {
+				return burrito.PassError(err)
+			}
+			if hash != state.Hash {
+				modified = append(modified, normalizedS)
+			}
 			return nil
 		})
 	if err != nil {
-		return burrito.PassError(err)
+		return nil, burrito.PassError(err)
 	}
-	return nil
+	return modified, nil
 }
@@ -146,6 +146,75 @@ func (r *revertibleFsOperations) Delete(path string) error {
 	return deleteSingle(path)
 }
 
+// Swap replaces the target file or directory with the staged one. The
+// replaced target is moved to the backup path until the Close method is
+// called, so the operation can be reverted using the Undo method. The paths
+// are renamed, so the swap is fast if the staged path, the backup path and
+// the target are on the same volume. Otherwise, the files are copied. The
+// links are replaced without changing the files they point to.
+func (r *revertibleFsOperations) Swap(staged, target string) error {
+	if _, err := os.Lstat(staged); err != nil {
+		return burrito.WrapErrorf(err, osStatErrorAny, staged)
+	}
+	if _, err := os.Lstat(target); err == nil {
+		backup := r.getTempFilePath(target)
+		if err := renameOrCopy(target, backup); err != nil {
+			return burrito.PassError(err)
+		}
+		r.undoOperations = append(r.undoOperations, func() error {
+			return renameOrCopy(backup, target)
+		})
+	} else if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return burrito.WrapErrorf(err, osMkdirError, filepath.Dir(target))
+		}
+	} else {
+		return burrito.WrapErrorf(err, osStatErrorAny, target)
+	}
+	if err := renameOrCopy(staged, target); err != nil {
+		return burrito.PassError(err)
+	}
+	r.undoOperations = append(r.undoOperations, func() error {
+		return renameOrCopy(target, staged)
+	})
+	return nil
+}
+
+// renameOrCopy renames the source path to the target path. If renaming
+// fails, for example because the paths are on different volumes, the source
+// is copied to the target and removed. The links are recreated instead of
+// copying the files they point to.
+func renameOrCopy(source, target string) error {
+	err := os.Rename(source, target)
+	if err == nil {
+		return nil
+	}
+	Logger.Debugf(
+		"Failed to rename the path.\n\tSource: %s\n\tTarget: %s\n"+
+			"Trying to copy it instead...", source, target)
+	info, err := os.Lstat(source)
+	if err != nil {
+		return burrito.WrapErrorf(err, osStatErrorAny, source)
+	}
+	if info.Mode()&(fs.ModeSymlink|fs.ModeIrregular) != 0 {
+		err = syncLink(source, target)
+	} else {
+		err = copy.Copy(source, target, copy.Options{PreserveTimes: false, Sync: false})
+	}
+	if err != nil {
+		// Don't leave the partially copied files
+		if removeErr := removeJunctionSafe(target); removeErr != nil {
+			Logger.Warnf("Failed to remove the partially copied files.\n"+
+				"Path: %s\nError: %s", target, removeErr.Error())
+		}
+		return burrito.WrapErrorf(err, osCopyError, source, target)
+	}
+	if err := removeJunctionSafe(source); err != nil {
+		return burrito.PassError(err)
+	}
+	return nil
+}
+
 // Move moves a file or a directory from source to target.
 // For moving or copying entire directories, check out the MoveOrCopyDir.
 func (r *revertibleFsOperations) Move(source, target string) error {
@@ -250,6 +319,35 @@ func (r *revertibleFsOperations) MkdirAll(path string) error {
 	return nil
 }
 
+// WriteFile writes the data to the file like the os.WriteFile, creating its
+// parent directories if necessary. The previous content of the file is
+// moved to the backup path, so the operation can be reverted using the Undo
+// method until the Close method is called.
+func (r *revertibleFsOperations) WriteFile(path string, data []byte) error {
+	err := r.MkdirAll(filepath.Dir(path))
+	if err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, filepath.Dir(path))
+	}
+	err = r.Delete(path)
+	if err != nil {
+		return burrito.WrapErrorf(err, revertibleFsOperationsDeleteError, path)
+	}
+	// The undo operation is added first, so that it also removes the
+	// partially written file
+	r.undoOperations = append(r.undoOperations, func() error {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return burrito.WrapErrorf(err, osRemoveError, path)
+		}
+		return nil
+	})
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, path)
+	}
+	return nil
+}
+
 // MoveOrCopyDir safely moves a directory form source to target.
 // The target path must not exist or be empty directory.
 // This function is better for moving or copying directories than
@@ -580,6 +678,109 @@ func AreFilesEqual(a, b string) (bool, error) {
 	return true, nil
 }
 
+// The kinds of the changes reported by CompareDirectories.
+const (
+	FileAdded    = "added"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+)
+
+// FileChange is a difference between two directories found by
+// CompareDirectories.
+type FileChange struct {
+	// Path is the path to the file relative to the compared directories,
+	// using forward slashes.
+	Path string
+	// Kind is one of FileAdded, FileModified or FileDeleted.
+	Kind string
+}
+
+// CompareDirectories lists the changes that syncing the source directory to
+// the destination directory would make. Unlike SyncDirectories, it compares
+// the content of the files with AreFilesEqual instead of their modification
+// times, because the files in the tmp directory are always newer than the
+// exported ones. Missing directories are treated as empty. The result is
+// sorted by the paths of the files.
+func CompareDirectories(source, destination string) ([]FileChange, error) {
+	changes := []FileChange{}
+	// Walk the source to find the added and modified files
+	err := walkFiles(source, func(relPath string) error {
+		sourcePath := filepath.Join(source, relPath)
+		destinationPath := filepath.Join(destination, relPath)
+		stat, err := os.Stat(destinationPath)
+		if err != nil && !os.IsNotExist(err) {
+			return burrito.WrapErrorf(err, osStatErrorAny, destinationPath)
+		}
+		if err != nil || stat.IsDir() {
+			changes = append(changes, FileChange{filepath.ToSlash(relPath), FileAdded})
+			return nil
+		}
+		equal, err := AreFilesEqual(sourcePath, destinationPath)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		if !equal {
+			changes = append(changes, FileChange{filepath.ToSlash(relPath), FileModified})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	// Walk the destination to find the deleted files
+	err = walkFiles(destination, func(relPath string) error {
+		sourcePath := filepath.Join(source, relPath)
+		stat, err := os.Stat(sourcePath)
+		if err != nil && !os.IsNotExist(err) {
+			return burrito.WrapErrorf(err, osStatErrorAny, sourcePath)
+		}
+		if err != nil || stat.IsDir() {
+			changes = append(changes, FileChange{filepath.ToSlash(relPath), FileDeleted})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+	return changes, nil
+}
+
+// walkFiles calls fn with the relative path of every file in the root
+// directory and its subdirectories. It does nothing if the root doesn't
+// exist.
+func walkFiles(root string, fn func(relPath string) error) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+	// The export paths can be links created by the symlink export
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return burrito.WrapErrorf(err, filepathRelError, root, path)
+		}
+		return fn(relPath)
+	})
+	if err != nil {
+		return burrito.WrapErrorf(err, osWalkError, root)
+	}
+	return nil
+}
+
 // CopyFile copies a file from source to target. If it's necessary it creates
 // the target directory.
 func CopyFile(source, target string) error {
@@ -1,14 +1,17 @@
 package regolith
 
 import (
+	"encoding/json"
 	"fmt"
 	"slices"
+	"time"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
 )
 
 type FilterDefinition struct {
-	Id string `json:"-"`
+	Id      string        `json:"-"`
+	Timeout FilterTimeout `json:"timeout,omitempty"`
 }
 
 type Filter struct {
@@ -19,6 +22,18 @@ type Filter struct {
 	Settings           map[string]any `json:"settings,omitempty"`
 	When               string         `json:"when,omitempty"`
 	ExtraArgumentsMode string         `json:"extraArguments,omitempty"`
+	Cache              bool           `json:"cache,omitempty"`
+	Timeout            FilterTimeout  `json:"timeout,omitempty"`
+	Inputs             []string       `json:"inputs,omitempty"`
+}
+
+// FilterTimeout is the timeout of a filter. In JSON, it uses the same
+// duration format as the "timeout" property of the config file (see
+// timeoutFromObject).
+type FilterTimeout time.Duration
+
+func (t FilterTimeout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(t).String())
 }
 
 type RunContext struct {
@@ -34,6 +49,16 @@ type RunContext struct {
 	SymlinkExport        bool
 	DisableSizeTimeCheck bool
 
+	// BackupModifiedFiles backs up the exported files modified outside of
+	// Regolith to the .regolith directory instead of stopping the export.
+	BackupModifiedFiles bool
+
+	// DryRun replaces the export with printing the summary of the changes
+	// that the export would make (see PrintExportPlan). DryRunDiff adds
+	// the diffs of the changed text files to the summary.
+	DryRun     bool
+	DryRunDiff bool
+
 	// interruption is a channel used to receive notifications about changes
 	// in the source files, in order to trigger a restart of the program in
 	// the watch mode. The string sent to the channel is the name of the source
@@ -46,6 +71,41 @@ type RunContext struct {
 	fileWatchingError chan error
 
 	fileWatchingStage chan string
+
+	// cancel is closed when the currently running filter should be stopped,
+	// because it exceeded its timeout or because the source files changed
+	// in the watch mode. It's nil if the filter can't be cancelled.
+	cancel <-chan struct{}
+
+	// workingDir overrides the tmp directory as the directory in which the
+	// filters run. It's used by the isolated async filters, which run each
+	// subfilter in a separate copy of the tmp directory.
+	workingDir string
+
+	// watchState is used in the watch mode for restarting the profile from
+	// the first filter affected by the changes in the source files. It's nil
+	// outside of the watch mode.
+	watchState *watchState
+
+	// buildEvents is the server that sends the build events to other tools
+	// in the watch mode. It's nil if the server isn't running.
+	buildEvents *buildEventServer
+
+	// report records the spans of the run for the --report and --trace
+	// flags. It's nil if neither of them is used. reportSpan is the span of
+	// the filter that runs the filters of the context.
+	report     *runReport
+	reportSpan *reportSpan
+}
+
+// GetAbsoluteWorkingDirectory returns the absolute path to the directory in
+// which the filters of the context run. It's the tmp directory, unless the
+// filter is a subfilter of an isolated async filter.
+func (c *RunContext) GetAbsoluteWorkingDirectory() (string, error) {
+	if c.workingDir != "" {
+		return c.workingDir, nil
+	}
+	return GetAbsoluteWorkingDirectory(c.DotRegolithPath)
 }
 
 // GetProfile returns the Profile structure from the context.
@@ -70,7 +130,10 @@ func (c *RunContext) StartWatchingSourceFiles() error {
 	c.interruption = make(chan string)
 	c.fileWatchingError = make(chan error)
 	c.fileWatchingStage = make(chan string)
-	err := NewDirWatcher(c.Config, c.interruption, c.fileWatchingError, c.fileWatchingStage)
+	c.watchState = &watchState{}
+	err := NewDirWatcher(
+		c.Config, c.interruption, c.fileWatchingError, c.fileWatchingStage,
+		&c.watchState.changes)
 	if err != nil {
 		return err
 	}
@@ -170,13 +233,78 @@ func filterFromObject(obj map[string]any, id string) (*Filter, error) {
 		filter.ExtraArgumentsMode = extraArguments
 	}
 
+	// Cache
+	cache, ok := obj["cache"]
+	if ok {
+		cache, ok := cache.(bool)
+		if !ok {
+			return nil, burrito.WrappedErrorf(jsonPropertyTypeError, "cache", "boolean")
+		}
+		filter.Cache = cache
+	}
+
+	// Timeout
+	timeout, err := timeoutFromObject(obj)
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	filter.Timeout = FilterTimeout(timeout)
+
+	// Inputs
+	inputs, ok := obj["inputs"]
+	if ok {
+		inputs, ok := inputs.([]any)
+		if !ok {
+			return nil, burrito.WrappedErrorf(jsonPropertyTypeError, "inputs", "array")
+		}
+		for i, input := range inputs {
+			input, ok := input.(string)
+			if !ok {
+				return nil, burrito.WrappedErrorf(
+					jsonPathTypeError, fmt.Sprintf("inputs->%d", i), "string")
+			}
+			if !isValidGlob(input) {
+				return nil, burrito.WrappedErrorf(
+					invalidGlobError, input, fmt.Sprintf("inputs->%d", i))
+			}
+			filter.Inputs = append(filter.Inputs, input)
+		}
+	}
+
 	return filter, nil
 }
 
+// timeoutFromObject parses the optional "timeout" property of a filter or a
+// filter definition. The timeout uses the duration format, for example
+// "30s" or "5m". It returns 0 if the property is not set.
+func timeoutFromObject(obj map[string]any) (time.Duration, error) {
+	timeoutObj, ok := obj["timeout"]
+	if !ok {
+		return 0, nil
+	}
+	timeoutStr, ok := timeoutObj.(string)
+	if !ok {
+		return 0, burrito.WrappedErrorf(jsonPropertyTypeError, "timeout", "string")
+	}
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		return 0, burrito.WrappedErrorf(invalidTimeoutError, timeoutStr)
+	}
+	return timeout, nil
+}
+
 type FilterInstaller interface {
 	InstallDependencies(parent *RemoteFilterDefinition, dotRegolithPath string) error
 	Check(context RunContext) error
 	CreateFilterRunner(runConfiguration map[string]any, id string) (FilterRunner, error)
+
+	// GetTimeout returns the default timeout of the filters created from
+	// this definition. Zero means that there is no default timeout.
+	GetTimeout() time.Duration
+
+	// setTimeout sets the default timeout of the filters created from this
+	// definition.
+	setTimeout(timeout time.Duration)
 }
 
 type FilterRunner interface {
@@ -210,6 +338,24 @@ type FilterRunner interface {
 	// AddExtraArguments adds additional arguments to the filter according to
 	// the method provided in the filter runner settings
 	AddExtraArguments(extraArguments []string) error
+
+	// InterpolateTemplates replaces the "${expression}" templates in the
+	// settings and the arguments of the filter. It returns a function that
+	// restores the original settings and arguments, so the templates can be
+	// evaluated again in the next run.
+	InterpolateTemplates(ctx RunContext) (restore func(), err error)
+
+	// IsCacheEnabled returns whether the output of the filter should be cached and
+	// reused when the inputs of the filter don't change.
+	IsCacheEnabled() bool
+
+	// GetTimeout returns the timeout of the filter set in the profile. Zero
+	// means that the filter uses the timeout of its definition.
+	GetTimeout() time.Duration
+
+	// GetInputs returns the glob patterns of the paths in the tmp directory
+	// that the filter uses. Nil means that the filter can use any file.
+	GetInputs() []string
 }
 
 func (f *Filter) CopyArguments(parent *RemoteFilter) {
@@ -250,6 +396,26 @@ func (f *Filter) IsDisabled(ctx RunContext) (bool, error) {
 	return false, nil
 }
 
+func (f *Filter) IsCacheEnabled() bool {
+	return f.Cache
+}
+
+func (f *Filter) GetTimeout() time.Duration {
+	return time.Duration(f.Timeout)
+}
+
+func (f *Filter) GetInputs() []string {
+	return f.Inputs
+}
+
+func (f *FilterDefinition) GetTimeout() time.Duration {
+	return time.Duration(f.Timeout)
+}
+
+func (f *FilterDefinition) setTimeout(timeout time.Duration) {
+	f.Timeout = FilterTimeout(timeout)
+}
+
 func (f *Filter) IsUsingDataExport(_ string, _ RunContext) (bool, error) {
 	return false, nil
 }
@@ -270,6 +436,26 @@ func (f *Filter) AddExtraArguments(extraArguments []string) error {
 	return nil
 }
 
+func (f *Filter) InterpolateTemplates(ctx RunContext) (func(), error) {
+	settings, err := InterpolateSettings(f.Settings, ctx)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonPropertyParseError, "settings")
+	}
+	arguments := make([]string, len(f.Arguments))
+	for i, argument := range f.Arguments {
+		arguments[i], err = InterpolateString(argument, ctx)
+		if err != nil {
+			return nil, burrito.WrapErrorf(
+				err, jsonPathParseError, fmt.Sprintf("arguments->%d", i))
+		}
+	}
+	originalSettings, originalArguments := f.Settings, f.Arguments
+	f.Settings, f.Arguments = settings, arguments
+	return func() {
+		f.Settings, f.Arguments = originalSettings, originalArguments
+	}, nil
+}
+
 type filterInstallerFactory struct {
 	constructor func(string, map[string]any) (FilterInstaller, error)
 	name        string
@@ -330,6 +516,18 @@ var filterInstallerFactories = map[string]filterInstallerFactory{
 		},
 		name: "exe",
 	},
+	"starlark": {
+		constructor: func(id string, obj map[string]any) (FilterInstaller, error) {
+			return StarlarkFilterDefinitionFromObject(id, obj)
+		},
+		name: "Starlark",
+	},
+	"wasm": {
+		constructor: func(id string, obj map[string]any) (FilterInstaller, error) {
+			return WasmFilterDefinitionFromObject(id, obj)
+		},
+		name: "WebAssembly",
+	},
 	"": {
 		constructor: func(id string, obj map[string]any) (FilterInstaller, error) {
 			return RemoteFilterDefinitionFromObject(id, obj)
@@ -369,13 +567,21 @@ func FilterInstallerFromObject(id, rootId string, obj map[string]any) (FilterIns
 				"Unable to create %s filter from %q filter definition.",
 				factory.name, id)
 		}
+		timeout, err := timeoutFromObject(obj)
+		if err != nil {
+			return nil, burrito.WrapErrorf(
+				err,
+				"Unable to create %s filter from %q filter definition.",
+				factory.name, id)
+		}
+		filter.setTimeout(timeout)
 		return filter, nil
 	}
 	return nil, burrito.WrappedErrorf(
 		"Invalid runWith value filter definition.\n"+
 			"Filter: %s\n"+
 			"Value: %s\n"+
-			"Valid values: java, dotnet, nim, deno, nodejs, python, shell, exe",
+			"Valid values: java, dotnet, nim, deno, nodejs, bun, python, shell, exe, starlark, wasm",
 		runWith, id)
 }
 
@@ -391,6 +597,9 @@ func FilterRunnerFromObjectAndDefinitions(
 		if err != nil {
 			return nil, burrito.WrapError(err, filterFromObjectError)
 		}
+		if basicFilter.Cache {
+			return nil, burrito.WrappedErrorf(filterCacheUnsupportedError, "nested profiles")
+		}
 		return &ProfileFilter{
 			Filter:  *basicFilter,
 			Profile: profile,
@@ -399,6 +608,10 @@ func FilterRunnerFromObjectAndDefinitions(
 	if !isInAsyncFilter {
 		_, ok := obj["asyncFilters"]
 		if ok {
+			if cache, _ := obj["cache"].(bool); cache {
+				return nil, burrito.WrappedErrorf(
+					filterCacheUnsupportedError, "\"asyncFilters\"")
+			}
 			asyncFilter, err := AsyncFilterFromObject(obj, filterDefinitions)
 			if err != nil {
 				return nil, burrito.PassError(err)
@@ -419,6 +632,12 @@ func FilterRunnerFromObjectAndDefinitions(
 		if err != nil {
 			return nil, burrito.WrapErrorf(err, createFilterRunnerError, filter)
 		}
+		// The cache replaces the whole tmp directory, so it can't be used
+		// by the filters that run in parallel
+		if isInAsyncFilter && filterRunner.IsCacheEnabled() {
+			return nil, burrito.WrappedErrorf(
+				filterCacheUnsupportedError, "the subfilters of \"asyncFilters\"")
+		}
 		return filterRunner, nil
 	}
 	return nil, burrito.WrappedErrorf(
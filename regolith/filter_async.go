@@ -1,7 +1,15 @@
 package regolith
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,13 +19,47 @@ import (
 type AsyncFilter struct {
 	Filter
 	AsyncFilters []FilterRunner `json:"asyncFilters,omitempty"`
+
+	// After lists the IDs of the subfilters that must finish before the
+	// subfilter with the same index in AsyncFilters starts.
+	After [][]string `json:"-"`
+
+	// MaxParallel limits the number of the subfilters running at the same
+	// time. Zero means no limit.
+	MaxParallel int `json:"maxParallel,omitempty"`
+
+	// Isolated runs each subfilter in a separate copy of the tmp directory.
+	// The changes of a subfilter are merged into the tmp directory when it
+	// finishes. Changing the same file differently in multiple subfilters
+	// that aren't ordered with "after" is reported as a conflict.
+	Isolated bool `json:"isolated,omitempty"`
 }
 
+// asyncWorkspaceRoots are the directories of the tmp directory copied to the
+// workspaces of the isolated async filters.
+var asyncWorkspaceRoots = []string{"RP", "BP", "data"}
+
 func AsyncFilterFromObject(
 	obj map[string]any,
 	filterDefinitions map[string]FilterInstaller,
 ) (*AsyncFilter, error) {
 	result := &AsyncFilter{}
+	// isolated
+	if isolated, ok := obj["isolated"]; ok {
+		result.Isolated, ok = isolated.(bool)
+		if !ok {
+			return result, burrito.WrappedErrorf(jsonPathTypeError, "isolated", "boolean")
+		}
+	}
+	// maxParallel
+	if maxParallel, ok := obj["maxParallel"]; ok {
+		value, ok := maxParallel.(float64)
+		if !ok || value < 0 || value != math.Trunc(value) {
+			return result, burrito.WrappedErrorf(
+				jsonPathTypeError, "maxParallel", "non-negative integer")
+		}
+		result.MaxParallel = int(value)
+	}
 	// asyncFilters list
 	if _, ok := obj["asyncFilters"]; !ok {
 		return result, burrito.WrappedErrorf(jsonPathMissingError, "asyncFilters")
@@ -40,14 +82,40 @@ func AsyncFilterFromObject(
 				err, jsonPathParseError, fmt.Sprintf("asyncFilters->%d", i))
 		}
 		result.AsyncFilters = append(result.AsyncFilters, filterRunner)
+		// after
+		var after []string
+		if afterObj, ok := filter["after"]; ok {
+			afterList, ok := afterObj.([]any)
+			if !ok {
+				return result, burrito.WrappedErrorf(
+					jsonPathTypeError, fmt.Sprintf("asyncFilters->%d->after", i), "array")
+			}
+			for j, id := range afterList {
+				id, ok := id.(string)
+				if !ok {
+					return result, burrito.WrappedErrorf(
+						jsonPathTypeError,
+						fmt.Sprintf("asyncFilters->%d->after->%d", i, j), "string")
+				}
+				after = append(after, id)
+			}
+		}
+		result.After = append(result.After, after)
 	}
 	return result, nil
 }
 
-// run executes all subfilters of the async filter. It returns true if the
-// execution was interrupted via the RunContext.
+// run executes all subfilters of the async filter. The subfilters run in
+// parallel, unless they depend on each other with the "after" property or
+// the number of parallel subfilters is limited with the "maxParallel"
+// property. It returns true if the execution was interrupted via the
+// RunContext.
 func (f *AsyncFilter) run(context RunContext) (bool, error) {
 	Logger.Debugf("RunAsyncFilter...")
+	dependencies, err := f.resolveDependencies()
+	if err != nil {
+		return false, burrito.PassError(err)
+	}
 	// Run the filters asynchronously
 	start := time.Now()
 	var wg sync.WaitGroup
@@ -56,66 +124,219 @@ func (f *AsyncFilter) run(context RunContext) (bool, error) {
 		err         error
 	}
 	results := make(chan Result, len(f.AsyncFilters))
+	// finished[i] is closed when the i-th subfilter finishes, succeeded[i]
+	// is true if it finished without errors and interruptions
+	finished := make([]chan struct{}, len(f.AsyncFilters))
+	succeeded := make([]bool, len(f.AsyncFilters))
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+	var slots chan struct{}
+	if f.MaxParallel > 0 {
+		slots = make(chan struct{}, f.MaxParallel)
+	}
+	var merge *asyncMerge
+	if f.Isolated {
+		merge, err = newAsyncMerge(f, context, dependencies)
+		if err != nil {
+			return false, burrito.PassError(err)
+		}
+	}
 	for filter := range f.AsyncFilters {
 		wg.Go(func() {
-			filter := f.AsyncFilters[filter]
-			// Disabled filters are skipped
-			disabled, err := filter.IsDisabled(context)
-			if err != nil {
-				results <- Result{
-					interrupted: false,
-					err:         burrito.WrapErrorf(err, "Failed to check if filter is disabled"),
+			defer close(finished[filter])
+			// Wait for the dependencies, the subfilter is skipped if any
+			// of them failed (the error is already reported)
+			for _, dependency := range dependencies[filter] {
+				<-finished[dependency]
+				if !succeeded[dependency] {
+					return
 				}
-				return
-			}
-			if disabled {
-				Logger.Infof("Filter \"%s\" is disabled, skipping.", filter.GetId())
-				return
 			}
-			// Skip printing if the filter ID is empty (most likely a nested profile)
-			if filter.GetId() != "" {
-				Logger.Infof("Running filter %s", filter.GetId())
+			if slots != nil {
+				slots <- struct{}{}
+				defer func() { <-slots }()
 			}
-			// Run the filter in watch mode
-
-			interrupted, err := filter.Run(context)
-
+			interrupted, err := f.runSubfilter(filter, context, merge)
 			if err != nil {
-				results <- Result{
-					interrupted: false,
-					err:         burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId()),
-				}
+				results <- Result{interrupted: false, err: err}
 				return
 			}
 			if interrupted {
-				results <- Result{
-					interrupted: true,
-					err:         nil,
-				}
+				results <- Result{interrupted: true, err: nil}
 				return
 			}
-			results <- Result{
-				interrupted: false,
-				err:         nil,
-			}
+			succeeded[filter] = true
+			results <- Result{interrupted: false, err: nil}
 		})
 	}
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
+	// Wait for all subfilters, so none of them is still running when the
+	// async filter finishes
+	var firstErr error
+	interrupted := false
 	for result := range results {
-		if result.err != nil {
-			return false, result.err
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
 		}
-		if result.interrupted {
-			return true, nil
+		interrupted = interrupted || result.interrupted
+	}
+	if firstErr != nil {
+		return false, firstErr
+	}
+	if interrupted {
+		return true, nil
+	}
+	if merge != nil {
+		if err := merge.conflictsError(); err != nil {
+			return false, burrito.PassError(err)
 		}
 	}
 	Logger.Debugf("Executed in %s", time.Since(start))
 	return false, nil
 }
 
+// runSubfilter runs the subfilter with the given index. If the merge is not
+// nil, the subfilter runs in its own workspace, and its changes are merged
+// into the tmp directory when it finishes.
+func (f *AsyncFilter) runSubfilter(
+	index int, context RunContext, merge *asyncMerge,
+) (bool, error) {
+	filter := f.AsyncFilters[index]
+	// Disabled filters are skipped
+	disabled, err := filter.IsDisabled(context)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, "Failed to check if filter is disabled")
+	}
+	if disabled {
+		Logger.Infof("Filter \"%s\" is disabled, skipping.", filter.GetId())
+		return false, nil
+	}
+	var workspace *asyncWorkspace
+	if merge != nil {
+		workspace, err = merge.createWorkspace(index)
+		defer func() {
+			if workspace == nil {
+				return
+			}
+			if err := os.RemoveAll(workspace.path); err != nil {
+				Logger.Warnf("Failed to remove the workspace of the async filter.\n"+
+					"Path: %s\nError: %s", workspace.path, err.Error())
+			}
+		}()
+		if err != nil {
+			return false, burrito.PassError(err)
+		}
+		context.workingDir = workspace.path
+	}
+	// Skip printing if the filter ID is empty (most likely a nested profile)
+	if filter.GetId() != "" {
+		Logger.Infof("Running filter %s", filter.GetId())
+	}
+	restore, err := filter.InterpolateTemplates(context)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
+	}
+	defer restore()
+	context.buildEvents.emit(BuildEvent{
+		Type: buildFilterStartedEvent, Profile: context.Profile, Filter: filter.GetId()})
+	start := time.Now()
+	interrupted, err := runFilter(filter, context)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
+	}
+	if interrupted {
+		return true, nil
+	}
+	context.buildEvents.emit(BuildEvent{
+		Type:       buildFilterFinishedEvent,
+		Profile:    context.Profile,
+		Filter:     filter.GetId(),
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	if merge != nil {
+		if err := merge.mergeWorkspace(workspace); err != nil {
+			return false, burrito.PassError(err)
+		}
+	}
+	return false, nil
+}
+
+// resolveDependencies returns the indices of the subfilters that must finish
+// before each subfilter starts. It returns an error if a subfilter depends
+// on an unknown subfilter or if the dependencies form a cycle.
+func (f *AsyncFilter) resolveDependencies() ([][]int, error) {
+	dependencies := make([][]int, len(f.AsyncFilters))
+	for i, after := range f.After {
+		for _, id := range after {
+			found, self := false, false
+			for j, filter := range f.AsyncFilters {
+				if filter.GetId() != id {
+					continue
+				}
+				if j == i {
+					self = true
+					continue
+				}
+				dependencies[i] = append(dependencies[i], j)
+				found = true
+			}
+			if !found && self {
+				// The subfilter can only wait for itself
+				name := f.subfilterName(i)
+				return nil, burrito.WrappedErrorf(
+					asyncFilterCycleError, name+" -> "+name)
+			}
+			if !found {
+				return nil, burrito.WrappedErrorf(
+					asyncFilterUnknownDependencyError, f.subfilterName(i), id)
+			}
+		}
+	}
+	// Find the cycles with depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(f.AsyncFilters))
+	var path []int
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			cycleStart := slices.Index(path, i)
+			names := []string{}
+			for _, j := range append(path[cycleStart:], i) {
+				names = append(names, f.subfilterName(j))
+			}
+			return burrito.WrappedErrorf(
+				asyncFilterCycleError, strings.Join(names, " -> "))
+		}
+		state[i] = visiting
+		path = append(path, i)
+		for _, dependency := range dependencies[i] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range f.AsyncFilters {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return dependencies, nil
+}
+
 func (f *AsyncFilter) Run(context RunContext) (bool, error) {
 	interrupted, err := f.run(context)
 	if err != nil {
@@ -128,6 +349,9 @@ func (f *AsyncFilter) Run(context RunContext) (bool, error) {
 }
 
 func (f *AsyncFilter) Check(context RunContext) error {
+	if _, err := f.resolveDependencies(); err != nil {
+		return burrito.PassError(err)
+	}
 	for _, filter := range f.AsyncFilters {
 		err := filter.Check(context)
 		if err != nil {
@@ -153,3 +377,237 @@ func (f *AsyncFilter) IsUsingDataExport(dotRegolithPath string, ctx RunContext)
 	}
 	return false, nil
 }
+
+// subfilterName returns the name of the subfilter used in the error messages.
+func (f *AsyncFilter) subfilterName(index int) string {
+	if id := f.AsyncFilters[index].GetId(); id != "" {
+		return id
+	}
+	return fmt.Sprintf("subfilter %d", index+1)
+}
+
+// asyncMerge merges the changes made by the subfilters of an isolated async
+// filter in their workspaces into the tmp directory.
+type asyncMerge struct {
+	filter *AsyncFilter
+	// tmpPath is the directory into which the changes are merged.
+	tmpPath string
+	// asyncPath is the directory of the workspaces.
+	asyncPath string
+	// ancestors[i] is the set of the subfilters that always finish before
+	// the i-th subfilter starts.
+	ancestors []map[int]bool
+
+	// mutex guards the fields below and the tmp directory during the
+	// creation of the workspaces and merging.
+	mutex sync.Mutex
+	// changes maps the paths of the merged changes to the subfilters that
+	// made them.
+	changes map[string]mergedChange
+	// conflicts are the descriptions of the conflicting changes.
+	conflicts []string
+}
+
+// mergedChange is a change of a file merged into the tmp directory.
+type mergedChange struct {
+	// subfilter is the index of the subfilter that made the change.
+	subfilter int
+	// hash is the hash of the new content of the file or an empty string if
+	// the file was deleted.
+	hash string
+}
+
+// asyncWorkspace is a copy of the tmp directory used by a subfilter of an
+// isolated async filter.
+type asyncWorkspace struct {
+	// subfilter is the index of the subfilter.
+	subfilter int
+	// path is the path to the workspace.
+	path string
+	// base maps the paths of the files of the workspace to their hashes from
+	// the moment when the workspace was created.
+	base map[string]string
+}
+
+func newAsyncMerge(
+	filter *AsyncFilter, context RunContext, dependencies [][]int,
+) (*asyncMerge, error) {
+	tmpPath, err := context.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return nil, burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	asyncPath := filepath.Join(context.DotRegolithPath, "async")
+	if err := os.MkdirAll(asyncPath, 0755); err != nil {
+		return nil, burrito.WrapErrorf(err, osMkdirError, asyncPath)
+	}
+	// The dependencies don't have cycles, so the ancestors can be found
+	// with a simple recursion
+	ancestors := make([]map[int]bool, len(dependencies))
+	var findAncestors func(i int) map[int]bool
+	findAncestors = func(i int) map[int]bool {
+		if ancestors[i] != nil {
+			return ancestors[i]
+		}
+		result := map[int]bool{}
+		for _, dependency := range dependencies[i] {
+			result[dependency] = true
+			for ancestor := range findAncestors(dependency) {
+				result[ancestor] = true
+			}
+		}
+		ancestors[i] = result
+		return result
+	}
+	for i := range dependencies {
+		findAncestors(i)
+	}
+	return &asyncMerge{
+		filter:    filter,
+		tmpPath:   tmpPath,
+		asyncPath: asyncPath,
+		ancestors: ancestors,
+		changes:   map[string]mergedChange{},
+	}, nil
+}
+
+// createWorkspace creates a copy of the current state of the tmp directory
+// for the subfilter. The returned workspace should be removed even if the
+// function fails.
+func (m *asyncMerge) createWorkspace(subfilter int) (*asyncWorkspace, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	path, err := os.MkdirTemp(m.asyncPath, "workspace-")
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, osMkdirError, m.asyncPath)
+	}
+	workspace := &asyncWorkspace{subfilter: subfilter, path: path}
+	for _, root := range asyncWorkspaceRoots {
+		source := filepath.Join(m.tmpPath, root)
+		target := filepath.Join(path, root)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return workspace, burrito.WrapErrorf(err, osMkdirError, target)
+			}
+			continue
+		}
+		// The packs in tmp can be links created by the symlink export,
+		// copyExportPath copies the files they point to.
+		if err := copyExportPath(source, target, false); err != nil {
+			return workspace, burrito.PassError(err)
+		}
+	}
+	workspace.base, err = hashWorkspace(path)
+	if err != nil {
+		return workspace, burrito.PassError(err)
+	}
+	return workspace, nil
+}
+
+// mergeWorkspace applies the changes made by the subfilter in its workspace
+// to the tmp directory. The changes to the files that were already changed
+// differently by the subfilters that aren't the ancestors of this subfilter
+// are recorded as conflicts and skipped.
+func (m *asyncMerge) mergeWorkspace(workspace *asyncWorkspace) error {
+	current, err := hashWorkspace(workspace.path)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	// Find the changes of the subfilter
+	changes := map[string]string{}
+	for path, hash := range current {
+		if workspace.base[path] != hash {
+			changes[path] = hash
+		}
+	}
+	for path := range workspace.base {
+		if _, ok := current[path]; !ok {
+			changes[path] = ""
+		}
+	}
+	paths := make([]string, 0, len(changes))
+	for path := range changes {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, path := range paths {
+		hash := changes[path]
+		previous, ok := m.changes[path]
+		if ok && !m.ancestors[workspace.subfilter][previous.subfilter] {
+			if previous.hash != hash {
+				// The subfilters are listed in the order from the config,
+				// not in the order in which they finished
+				first := min(previous.subfilter, workspace.subfilter)
+				second := max(previous.subfilter, workspace.subfilter)
+				m.conflicts = append(m.conflicts, fmt.Sprintf(
+					"%s (%s, %s)", path,
+					m.filter.subfilterName(first),
+					m.filter.subfilterName(second)))
+			}
+			continue
+		}
+		target := filepath.Join(m.tmpPath, filepath.FromSlash(path))
+		if hash == "" {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return burrito.WrapErrorf(err, osRemoveError, target)
+			}
+		} else {
+			source := filepath.Join(workspace.path, filepath.FromSlash(path))
+			if err := CopyFile(source, target); err != nil {
+				return burrito.WrapErrorf(err, osCopyError, source, target)
+			}
+		}
+		m.changes[path] = mergedChange{subfilter: workspace.subfilter, hash: hash}
+	}
+	return nil
+}
+
+// conflictsError returns an error describing the conflicts found while
+// merging the workspaces, or nil if there were no conflicts.
+func (m *asyncMerge) conflictsError() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.conflicts) == 0 {
+		return nil
+	}
+	slices.Sort(m.conflicts)
+	return burrito.WrappedErrorf(
+		asyncFilterConflictError, strings.Join(m.conflicts, "\n"))
+}
+
+// hashWorkspace returns the hashes of all files of the workspace. The keys
+// of the map are the paths relative to the workspace, using forward
+// slashes.
+func hashWorkspace(workspace string) (map[string]string, error) {
+	result := map[string]string{}
+	for _, root := range asyncWorkspaceRoots {
+		rootPath := filepath.Join(workspace, root)
+		err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) && path == rootPath {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(workspace, path)
+			if err != nil {
+				return burrito.WrapErrorf(err, filepathRelError, workspace, path)
+			}
+			h := sha256.New()
+			if err := hashFile(h, path); err != nil {
+				return burrito.PassError(err)
+			}
+			result[filepath.ToSlash(relPath)] = hex.EncodeToString(h.Sum(nil))
+			return nil
+		})
+		if err != nil {
+			return nil, burrito.WrapErrorf(err, osWalkError, rootPath)
+		}
+	}
+	return result, nil
+}
@@ -35,7 +35,7 @@ func BunFilterDefinitionFromObject(id string, obj map[string]any) (*BunFilterDef
 }
 
 func (f *BunFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -45,7 +45,8 @@ func (f *BunFilter) run(context RunContext) error {
 	}
 	// Run filter
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			bunRunner,
 			append([]string{
 				"run",
@@ -62,7 +63,8 @@ func (f *BunFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			bunRunner,
 			append([]string{
 				"run",
@@ -0,0 +1,225 @@
+package regolith
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// filterCacheKeyFile is the name of the file that stores the key of the
+// cached filter output.
+const filterCacheKeyFile = "key.txt"
+
+// filterCacheSubpaths is the list of the tmp directory subpaths that are
+// used as the input of the filter and stored as its output.
+var filterCacheSubpaths = []string{"RP", "BP", "data"}
+
+// getFilterCachePath returns the path to the cache entry of the filter at the
+// given position of the profile. Every filter of the profile has only one
+// cache entry, which is replaced when the inputs of the filter change.
+func getFilterCachePath(dotRegolithPath, profile string, index int, id string) string {
+	h := sha256.New()
+	h.Write([]byte(profile + "\x00" + strconv.Itoa(index) + "\x00" + id))
+	return filepath.Join(
+		dotRegolithPath, "cache", "filter_outputs",
+		hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+// getFilterCacheKey returns a hash of all inputs of the filter. The inputs
+// are the contents of the tmp directory, the configuration of the filter
+// (including its settings and arguments), the filter definition and the
+// version of the filter. For the remote filters, the version is the installed
+// version. For the local filters, the hash of the script or executable is
+// used instead.
+func getFilterCacheKey(filter FilterRunner, ctx RunContext) (string, error) {
+	h := sha256.New()
+	// The filter configuration
+	filterJson, err := json.Marshal(filter)
+	if err != nil {
+		return "", burrito.WrapErrorf(err, filterCacheSerializeError, filter.GetId())
+	}
+	writeCacheKeyPart(h, "filter", filterJson)
+	// The filter definition
+	definition, ok := ctx.Config.FilterDefinitions[filter.GetId()]
+	if !ok {
+		return "", burrito.WrappedErrorf(
+			"Caching is only supported for filters with a filter "+
+				"definition.\nFilter: %s", filter.GetId())
+	}
+	definitionJson, err := json.Marshal(definition)
+	if err != nil {
+		return "", burrito.WrapErrorf(err, filterCacheSerializeError, filter.GetId())
+	}
+	writeCacheKeyPart(h, "definition", definitionJson)
+	// The version of the filter
+	if remoteDefinition, ok := definition.(*RemoteFilterDefinition); ok {
+		version, err := remoteDefinition.InstalledVersion(ctx.DotRegolithPath)
+		if err != nil {
+			return "", burrito.WrapErrorf(
+				err, "Failed to get the installed version of the filter.\n"+
+					"Filter: %s", filter.GetId())
+		}
+		writeCacheKeyPart(h, "version", []byte(version))
+	} else {
+		// The local filters don't have versions, use the hash of the file
+		// that is executed instead. Note that the changes in other files
+		// used by the script (e.g. imported modules) are not detected.
+		var definitionObj map[string]any
+		if err := json.Unmarshal(definitionJson, &definitionObj); err != nil {
+			return "", burrito.WrapErrorf(err, filterCacheSerializeError, filter.GetId())
+		}
+		for _, property := range []string{"script", "exe", "path"} {
+			path, ok := definitionObj[property].(string)
+			if !ok {
+				continue
+			}
+			stat, err := os.Stat(path)
+			if err != nil || stat.IsDir() {
+				continue
+			}
+			writeCacheKeyPart(h, property, nil)
+			if err := hashFile(h, path); err != nil {
+				return "", burrito.PassError(err)
+			}
+		}
+	}
+	// The contents of the tmp directory
+	absTmpPath, err := ctx.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return "", burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	for _, subpath := range filterCacheSubpaths {
+		writeCacheKeyPart(h, subpath, nil)
+		if err := hashDirectory(h, filepath.Join(absTmpPath, subpath)); err != nil {
+			return "", burrito.PassError(err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeCacheKeyPart writes a labeled, length-prefixed part of the cache key
+// to the hash, so that the boundaries between the parts are unambiguous.
+func writeCacheKeyPart(h hash.Hash, label string, data []byte) {
+	h.Write([]byte(label))
+	binary.Write(h, binary.LittleEndian, uint64(len(data)))
+	h.Write(data)
+}
+
+// hashFile writes the contents of the file to the hash.
+func hashFile(h hash.Hash, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return burrito.WrapErrorf(err, osOpenError, path)
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return burrito.WrapErrorf(err, osStatErrorAny, path)
+	}
+	binary.Write(h, binary.LittleEndian, uint64(stat.Size()))
+	if _, err := io.Copy(h, file); err != nil {
+		return burrito.WrapErrorf(err, fileReadError, path)
+	}
+	return nil
+}
+
+// hashDirectory writes the relative paths and the contents of all files and
+// directories from the root directory to the hash. The directory is walked in
+// lexical order, so the result doesn't depend on the file system. A missing
+// directory is treated the same as an empty one.
+func hashDirectory(h hash.Hash, root string) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+	// The tmp directories are links to the export target when using the
+	// symlink export.
+	if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolvedRoot
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return burrito.WrapErrorf(err, filepathRelError, root, path)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if d.IsDir() {
+			writeCacheKeyPart(h, "dir", []byte(relPath))
+			return nil
+		}
+		writeCacheKeyPart(h, "file", []byte(relPath))
+		return hashFile(h, path)
+	})
+	if err != nil {
+		return burrito.WrapErrorf(err, osWalkError, root)
+	}
+	return nil
+}
+
+// restoreFilterCache restores the output of the filter from the cache entry
+// if the key of the entry matches the given key. It returns true if the
+// output was restored.
+func restoreFilterCache(cachePath, key string, ctx RunContext) (bool, error) {
+	keyPath := filepath.Join(cachePath, filterCacheKeyFile)
+	cachedKey, err := os.ReadFile(keyPath)
+	if err != nil || string(cachedKey) != key {
+		return false, nil
+	}
+	absTmpPath, err := ctx.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return false, burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	for _, subpath := range filterCacheSubpaths {
+		source := filepath.Join(cachePath, subpath)
+		target := filepath.Join(absTmpPath, subpath)
+		if err := SyncDirectories(source, target, false); err != nil {
+			return false, burrito.WrapErrorf(
+				err, "Failed to restore the cached filter output.\n"+
+					"Cache path: %s", cachePath)
+		}
+	}
+	return true, nil
+}
+
+// saveFilterCache saves the contents of the tmp directory as the output of
+// the filter with the given key. The key is written last, so an interrupted
+// save never leaves a valid cache entry.
+func saveFilterCache(cachePath, key string, ctx RunContext) error {
+	keyPath := filepath.Join(cachePath, filterCacheKeyFile)
+	if err := os.Remove(keyPath); err != nil && !os.IsNotExist(err) {
+		return burrito.WrapErrorf(err, osRemoveError, keyPath)
+	}
+	absTmpPath, err := ctx.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	for _, subpath := range filterCacheSubpaths {
+		source := filepath.Join(absTmpPath, subpath)
+		target := filepath.Join(cachePath, subpath)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			if err := os.MkdirAll(source, 0755); err != nil {
+				return burrito.WrapErrorf(err, osMkdirError, source)
+			}
+		}
+		if err := SyncDirectories(source, target, false); err != nil {
+			return burrito.WrapErrorf(
+				err, "Failed to save the filter output to the cache.\n"+
+					"Cache path: %s", cachePath)
+		}
+	}
+	if err := os.WriteFile(keyPath, []byte(key), 0644); err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, keyPath)
+	}
+	return nil
+}
@@ -35,7 +35,7 @@ func DenoFilterDefinitionFromObject(id string, obj map[string]any) (*DenoFilterD
 }
 
 func (f *DenoFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -44,7 +44,8 @@ func (f *DenoFilter) run(context RunContext) error {
 		return burrito.WrapError(err, getRunnerError)
 	}
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			denoRunner,
 			append([]string{
 				"run", "--allow-all",
@@ -61,7 +62,8 @@ func (f *DenoFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			denoRunner,
 			append([]string{
 				"run", "--allow-all",
@@ -39,7 +39,7 @@ func (f *DotNetFilter) Run(context RunContext) (bool, error) {
 }
 
 func (f *DotNetFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -48,7 +48,8 @@ func (f *DotNetFilter) run(context RunContext) error {
 		return burrito.WrapError(err, getRunnerError)
 	}
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			dotnetRunner,
 			append(
 				[]string{
@@ -66,7 +67,8 @@ func (f *DotNetFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			dotnetRunner,
 			append(
 				[]string{
@@ -0,0 +1,136 @@
+package regolith
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FilterEventPrefix is the prefix of the lines printed by the filters to
+// send structured events to Regolith. The prefix is followed by a single
+// JSON object. The prefix is also available to the filters in the
+// REGOLITH_EVENT_PREFIX environment variable.
+//
+// Examples of supported events:
+//
+//	__REGOLITH_EVENT__{"type": "log", "level": "warn", "message": "Text"}
+//	__REGOLITH_EVENT__{"type": "progress", "progress": 50, "message": "Text"}
+//	__REGOLITH_EVENT__{"type": "warning", "message": "Text", "file": "BP/a.json", "line": 3, "column": 5}
+//	__REGOLITH_EVENT__{"type": "output", "files": ["BP/a.json", "RP/b.json"]}
+//
+// The events can be printed both to stdout and stderr.
+const FilterEventPrefix = "__REGOLITH_EVENT__"
+
+// Types of the events supported by the filter event protocol.
+const (
+	// FilterEventLog is a log record with a level. Valid levels are
+	// "debug", "info", "warn" and "error". The default level is "info".
+	FilterEventLog = "log"
+	// FilterEventProgress reports the progress of the filter in percents.
+	FilterEventProgress = "progress"
+	// FilterEventWarning is a warning related to a file. The line and
+	// column are optional.
+	FilterEventWarning = "warning"
+	// FilterEventOutput is a list of files created or modified by the filter.
+	FilterEventOutput = "output"
+)
+
+// FilterEvent is a single structured event sent by a filter.
+type FilterEvent struct {
+	Type     string   `json:"type"`
+	Level    string   `json:"level,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Progress *float64 `json:"progress,omitempty"`
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Files    []string `json:"files,omitempty"`
+
+	// Source is the label of the process that sent the event (usually the
+	// name of the filter). It's not a part of the protocol.
+	Source string `json:"source,omitempty"`
+}
+
+// ParseFilterEvent checks if the line printed by a filter is an event. If
+// it's not, the second returned value is false. If it is, but the event is
+// invalid, an error is returned.
+func ParseFilterEvent(line string) (FilterEvent, bool, error) {
+	var event FilterEvent
+	data, ok := strings.CutPrefix(strings.TrimSpace(line), FilterEventPrefix)
+	if !ok {
+		return event, false, nil
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, true, err
+	}
+	switch event.Type {
+	case FilterEventLog:
+		switch event.Level {
+		case "":
+			event.Level = "info"
+		case "debug", "info", "warn", "error":
+		default:
+			return event, true, fmt.Errorf("unknown log level %q", event.Level)
+		}
+	case FilterEventProgress:
+		if event.Progress == nil {
+			return event, true, fmt.Errorf("missing \"progress\" property")
+		}
+	case FilterEventWarning:
+	case FilterEventOutput:
+	default:
+		return event, true, fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return event, true, nil
+}
+
+// handleFilterEvent renders the event in the logger and records it in the
+// report span of the filter that sent it. The span can be nil.
+func handleFilterEvent(event FilterEvent, outputLabel string, span *reportSpan) {
+	event.Source = outputLabel
+	switch event.Type {
+	case FilterEventLog:
+		logFunc := Logger.Infof
+		switch event.Level {
+		case "debug":
+			logFunc = Logger.Debugf
+		case "warn":
+			logFunc = Logger.Warnf
+		case "error":
+			logFunc = Logger.Errorf
+		}
+		logFunc("[%s] %s", outputLabel, event.Message)
+	case FilterEventProgress:
+		if event.Message == "" {
+			Logger.Infof("[%s] %.0f%%", outputLabel, *event.Progress)
+		} else {
+			Logger.Infof(
+				"[%s] %.0f%% %s", outputLabel, *event.Progress, event.Message)
+		}
+	case FilterEventWarning:
+		Logger.Warnf(
+			"[%s] %s: %s", outputLabel, formatEventLocation(event),
+			event.Message)
+	case FilterEventOutput:
+		Logger.Debugf(
+			"[%s] Output files:\n\t%s", outputLabel,
+			strings.Join(event.Files, "\n\t"))
+	}
+	span.addEvent(event)
+}
+
+// formatEventLocation returns the location of a warning event in the
+// file:line:column format.
+func formatEventLocation(event FilterEvent) string {
+	result := event.File
+	if result == "" {
+		result = "<unknown file>"
+	}
+	if event.Line > 0 {
+		result += fmt.Sprintf(":%d", event.Line)
+		if event.Column > 0 {
+			result += fmt.Sprintf(":%d", event.Column)
+		}
+	}
+	return result
+}
@@ -75,18 +75,18 @@ func (f *ExeFilter) run(
 	settings map[string]any,
 	context RunContext,
 ) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
 	if len(settings) == 0 {
-		err = executeExeFile(f.Id,
+		err = executeExeFile(context, f.Id,
 			f.Definition.Exe,
 			f.Arguments, context.AbsoluteLocation,
 			absWorkingDir)
 	} else {
 		jsonSettings, _ := json.Marshal(settings)
-		err = executeExeFile(f.Id,
+		err = executeExeFile(context, f.Id,
 			f.Definition.Exe,
 			append([]string{string(jsonSettings)}, f.Arguments...),
 			context.AbsoluteLocation, absWorkingDir)
@@ -98,12 +98,12 @@ func (f *ExeFilter) run(
 	return nil
 }
 
-func executeExeFile(id string,
+func executeExeFile(context RunContext, id string,
 	exe string, args []string, filterDir string, workingDir string,
 ) error {
 	exe = filepath.Join(filterDir, exe)
 	Logger.Debugf("Running exe file %s:", exe)
-	err := RunSubProcess(exe, args, filterDir, workingDir, id)
+	err := runFilterSubProcess(context, exe, args, filterDir, workingDir, id)
 	if err != nil {
 		return burrito.WrapErrorf(err, runSubProcessError)
 	}
@@ -51,7 +51,7 @@ func (f *JavaFilter) Run(context RunContext) (bool, error) {
 }
 
 func (f *JavaFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -60,7 +60,8 @@ func (f *JavaFilter) run(context RunContext) error {
 		return burrito.WrapError(err, getRunnerError)
 	}
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			javaRunner,
 			append(
 				[]string{
@@ -78,7 +79,8 @@ func (f *JavaFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			javaRunner,
 			append(
 				[]string{
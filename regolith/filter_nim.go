@@ -53,7 +53,7 @@ func NimFilterDefinitionFromObject(
 }
 
 func (f *NimFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -62,7 +62,8 @@ func (f *NimFilter) run(context RunContext) error {
 		return burrito.WrapError(err, getRunnerError)
 	}
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			nimRunner,
 			append([]string{
 				"-r", "c", "--hints:off", "--warnings:off", "--mm:orc",
@@ -78,7 +79,8 @@ func (f *NimFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			nimRunner,
 			append([]string{
 				"-r", "c", "--hints:off", "--warnings:off", "--mm:orc",
@@ -51,7 +51,7 @@ func NodeJSFilterDefinitionFromObject(id string, obj map[string]any) (*NodeJSFil
 }
 
 func (f *NodeJSFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -60,7 +60,8 @@ func (f *NodeJSFilter) run(context RunContext) error {
 		return burrito.WrapError(err, getRunnerError)
 	}
 	if len(f.Settings) == 0 {
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			nodeRunner,
 			append([]string{
 				context.AbsoluteLocation + string(os.PathSeparator) +
@@ -76,7 +77,8 @@ func (f *NodeJSFilter) run(context RunContext) error {
 		}
 	} else {
 		jsonSettings, _ := json.Marshal(f.Settings)
-		err := RunSubProcess(
+		err := runFilterSubProcess(
+			context,
 			nodeRunner,
 			append([]string{
 				context.AbsoluteLocation + string(os.PathSeparator) +
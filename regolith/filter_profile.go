@@ -15,9 +15,14 @@ func (f *ProfileFilter) Run(context RunContext) (bool, error) {
 		Config:           context.Config,
 		Parent:           &context,
 		interruption:     context.interruption,
+		cancel:           context.cancel,
+		workingDir:       context.workingDir,
 		DotRegolithPath:  context.DotRegolithPath,
 		Settings:         f.Settings,
 		UnsafeMode:       context.UnsafeMode,
+		buildEvents:      context.buildEvents,
+		report:           context.report,
+		reportSpan:       context.reportSpan,
 	})
 }
 
@@ -54,7 +54,7 @@ func PythonFilterDefinitionFromObject(id string, obj map[string]any) (*PythonFil
 }
 
 func (f *PythonFilter) run(context RunContext) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
@@ -95,7 +95,8 @@ func (f *PythonFilter) run(context RunContext) error {
 			f.Arguments...,
 		)
 	}
-	err = RunSubProcess(
+	err = runFilterSubProcess(
+		context,
 		pythonCommand, args, context.AbsoluteLocation,
 		absWorkingDir,
 		ShortFilterName(f.Id))
@@ -99,6 +99,10 @@ func (f *RemoteFilter) run(context RunContext) (bool, error) {
 			DotRegolithPath:  context.DotRegolithPath,
 			Settings:         filter.GetSettings(),
 			UnsafeMode:       context.UnsafeMode,
+			cancel:           context.cancel,
+			workingDir:       context.workingDir,
+			report:           context.report,
+			reportSpan:       context.reportSpan,
 		}
 		// Disabled filters are skipped
 		disabled, err := filter.IsDisabled(runContext)
@@ -114,7 +118,7 @@ func (f *RemoteFilter) run(context RunContext) (bool, error) {
 		// Overwrite the venvSlot with the parent value
 		// TODO - remote filters can contain multiple filters, the interruption
 		// check should be performed after every subfilter
-		_, err = filter.Run(runContext)
+		_, err = runFilter(filter, runContext)
 		if err != nil {
 			return false, burrito.WrapErrorf(
 				err, filterRunnerRunError,
@@ -276,6 +280,21 @@ func (f *RemoteFilterDefinition) CopyFilterData(dataPath string, dotRegolithPath
 	}
 }
 
+// matchesLockedFilter checks if the URL and the version of the filter
+// definition match the lock file entry. The "latest" and "HEAD" versions and
+// the empty version match any locked version, because the lock file stores
+// the version that they resolved to during the installation.
+func (f *RemoteFilterDefinition) matchesLockedFilter(locked LockedFilter) bool {
+	if locked.Url != f.Url {
+		return false
+	}
+	switch f.Version {
+	case "", "latest", "HEAD":
+		return true
+	}
+	return locked.Version == trimFilterPrefix(f.Version, f.Id)
+}
+
 // GetDownloadPath returns the path location where the filter can be found.
 func (f *RemoteFilter) GetDownloadPath(dotRegolithPath string) string {
 	return filepath.Join(filepath.Join(dotRegolithPath, "cache/filters"), f.Id)
@@ -298,9 +317,9 @@ func (f *RemoteFilter) GetCachedVersion(dotRegolithPath string) (*string, error)
 	}
 
 	var filterCollection map[string]any
-	err = json.Unmarshal(file, &filterCollection)
+	err = unmarshalJSONC(file, &filterCollection, path)
 	if err != nil {
-		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, file)
+		return nil, burrito.PassError(err)
 	}
 	versionObj, ok := filterCollection["version"]
 	if !ok {
@@ -323,9 +342,9 @@ func (f *RemoteFilter) IsUsingDataExport(dotRegolithPath string, _ RunContext) (
 		return false, burrito.WrappedErrorf(readFilterJsonError, filterJsonPath)
 	}
 	var filterJsonObj map[string]any
-	err = json.Unmarshal(file, &filterJsonObj)
+	err = unmarshalJSONC(file, &filterJsonObj, filterJsonPath)
 	if err != nil {
-		return false, burrito.WrapErrorf(err, jsonUnmarshalError, filterJsonPath)
+		return false, burrito.PassError(err)
 	}
 	// Get the exportData field (default to false)
 	exportDataObj, ok := filterJsonObj["exportData"]
@@ -361,10 +380,12 @@ func FilterDefinitionFromTheInternet(
 	}, nil
 }
 
-// Download
+// Download downloads the filter to the cache of the project. It returns the
+// lock file entry of the downloaded filter or nil if the download was skipped
+// because the filter is already installed and isForced is false.
 func (f *RemoteFilterDefinition) Download(
 	isForced bool, dotRegolithPath string, refreshFilters bool,
-) error {
+) (*LockedFilter, error) {
 	if _, err := os.Stat(f.GetDownloadPath(dotRegolithPath)); err == nil {
 		if !isForced {
 			Logger.Warnf(
@@ -372,7 +393,7 @@ func (f *RemoteFilterDefinition) Download(
 					"be the case only if the filter is installed.\n"+
 					"    Skipped the download. You can force the it by "+
 					"passing the \"-force\" flag.", f.Id)
-			return nil
+			return nil, nil
 		} else {
 			f.Uninstall(dotRegolithPath)
 		}
@@ -381,88 +402,157 @@ func (f *RemoteFilterDefinition) Download(
 	Logger.Infof("Downloading filter %s...", f.Id)
 
 	// Download the filter using Git Getter
-	MeasureStart("Check git")
+	measure := MeasureStart("Check git")
 	if !hasGit() {
-		return burrito.WrappedError(gitNotInstalledWarning)
+		return nil, burrito.WrappedError(gitNotInstalledWarning)
 	}
-	MeasureStart("Get remote filter download ref")
+	measure.End()
+	measure = MeasureStart("Get remote filter download ref")
 	repoVersion, err := GetRemoteFilterDownloadRef(f.Url, f.Id, f.Version)
+	measure.End()
 	if err != nil {
-		return burrito.WrapErrorf(
+		return nil, burrito.WrapErrorf(
 			err, getRemoteFilterDownloadRefError, f.Url, f.Id, f.Version)
 	}
+	return f.downloadRef(
+		dotRegolithPath, repoVersion, trimFilterPrefix(repoVersion, f.Id),
+		refreshFilters)
+}
+
+// DownloadLocked downloads the filter from the commit pinned in the lock file
+// entry. It returns an error if the hash of the downloaded files doesn't
+// match the hash from the lock file.
+func (f *RemoteFilterDefinition) DownloadLocked(
+	locked LockedFilter, dotRegolithPath string, refreshFilters bool,
+) error {
+	if locked.Url != f.Url {
+		return burrito.WrappedErrorf(
+			"The URL of the filter doesn't match the URL from the lock file.\n"+
+				"Filter: %s\n"+
+				"URL: %s\n"+
+				"Locked URL: %s", f.Id, f.Url, locked.Url)
+	}
+	f.Uninstall(dotRegolithPath)
+	Logger.Infof("Downloading filter %s (commit %s)...", f.Id, locked.Commit)
+	if !hasGit() {
+		return burrito.WrappedError(gitNotInstalledWarning)
+	}
+	result, err := f.downloadRef(
+		dotRegolithPath, locked.Commit, locked.Version, refreshFilters)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	if result.Hash != locked.Hash {
+		f.Uninstall(dotRegolithPath)
+		return burrito.WrappedErrorf(
+			"The files of the filter don't match the lock file.\n"+
+				"Filter: %s\n"+
+				"Commit: %s\n"+
+				"Hash: %s\n"+
+				"Locked hash: %s",
+			f.Id, locked.Commit, result.Hash, locked.Hash)
+	}
+	return nil
+}
+
+// downloadRef downloads the filter from the given git ref and saves the
+// version string in its filter.json file. It returns the lock file entry of
+// the downloaded filter.
+func (f *RemoteFilterDefinition) downloadRef(
+	dotRegolithPath, ref, version string, refreshFilters bool,
+) (*LockedFilter, error) {
 	url := fmt.Sprintf("https://%s", f.Url)
 	downloadPath := f.GetDownloadPath(dotRegolithPath)
 
-	_, err = os.Stat(downloadPath)
+	_, err := os.Stat(downloadPath)
 	downloadPathIsNew := os.IsNotExist(err)
-	err = downloadFilterRepository(downloadPath, url, repoVersion, f.Id, refreshFilters)
+	commit, err := downloadFilterRepository(downloadPath, url, ref, f.Id, refreshFilters)
 	if err != nil {
 		if downloadPathIsNew { // Remove the path created by getter
 			os.Remove(downloadPath)
 		}
-		return burrito.WrapErrorf(
+		return nil, burrito.WrapErrorf(
 			err, "Could not download filter from %s.\n"+
 				"Does that filter exist?", f.Url)
 	}
 	// Save the version of the filter we downloaded
-	MeasureStart("Save version info")
-	err = f.SaveVersionInfo(trimFilterPrefix(repoVersion, f.Id), dotRegolithPath)
+	measure := MeasureStart("Save version info")
+	err = f.SaveVersionInfo(version, dotRegolithPath)
 	if err != nil {
-		return burrito.PassError(err)
+		return nil, burrito.PassError(err)
 	}
-	MeasureEnd()
+	measure.End()
 	// Remove 'test' folder, which we never want to use (saves space on disk)
 	testFolder := path.Join(downloadPath, "test")
 	if _, err := os.Stat(testFolder); err == nil {
 		os.RemoveAll(testFolder)
 	}
+	// Hash the files before installing the dependencies, which may add new
+	// files to the filter directory
+	hash, err := HashFilterDirectory(downloadPath)
+	if err != nil {
+		return nil, burrito.WrapErrorf(
+			err, "Failed to hash the files of the filter.\nFilter: %s", f.Id)
+	}
 
 	Logger.Infof("Filter \"%s\" downloaded successfully.", f.Id)
-	return nil
+	return &LockedFilter{
+		Url:     f.Url,
+		Version: version,
+		Commit:  commit,
+		Hash:    hash,
+	}, nil
 }
 
-func downloadFilterRepository(downloadPath, url, ref, filter string, forceUpdate bool) error {
+// downloadFilterRepository checks out the ref in the cached clone of the
+// repository and copies the filter to the download path. It returns the SHA
+// of the checked out commit.
+func downloadFilterRepository(downloadPath, url, ref, filter string, forceUpdate bool) (string, error) {
 	config, err := getCombinedUserConfig()
 	if err != nil {
-		return burrito.WrapErrorf(err, getUserConfigError)
+		return "", burrito.WrapErrorf(err, getUserConfigError)
 	}
 	cooldown, err := time.ParseDuration(*config.ResolverCacheUpdateCooldown)
 
 	cache, err := getFilterCache(url)
 	if err != nil {
-		return burrito.WrapErrorf(err, "Could not get cache path for %s", url)
+		return "", burrito.WrapErrorf(err, "Could not get cache path for %s", url)
 	}
+	fetched := false
 	// Check if exists in cache
 clone:
 	if _, err := os.Stat(cache); err != nil && os.IsNotExist(err) {
 		err := os.MkdirAll(cache, 0755)
 		if err != nil {
-			return burrito.WrapErrorf(err, osMkdirError, cache)
+			return "", burrito.WrapErrorf(err, osMkdirError, cache)
 		}
 		// Clone the repository
-		MeasureStart("Clone repository %s", url)
+		measure := MeasureStart("Clone repository %s", url)
 		output, err := RunGitProcess([]string{"clone", url, "."}, cache)
+		measure.End()
 		if err != nil {
 			Logger.Error(strings.Join(output, "\n"))
-			return burrito.WrapErrorf(err, "Failed to clone repository.\nURL: %s", url)
+			return "", burrito.WrapErrorf(err, "Failed to clone repository.\nURL: %s", url)
 		}
 		forceUpdate = false
 	} else if err != nil {
-		return burrito.WrapErrorf(err, osStatErrorAny, cache)
+		return "", burrito.WrapErrorf(err, osStatErrorAny, cache)
 	}
+fetch:
 	info, _ := os.Stat(cache)
 	if forceUpdate || info.ModTime().Before(time.Now().Add(cooldown*-1)) {
+		fetched = true
 		// Fetch the repository
-		MeasureStart("Fetch repository %s", url)
+		measure := MeasureStart("Fetch repository %s", url)
 		output, err := RunGitProcess([]string{"fetch"}, cache)
+		measure.End()
 		if err != nil {
 			Logger.Error(strings.Join(output, "\n"))
 			Logger.Errorf("Failed to fetch repository.\nURL: %s", url)
 			Logger.Infof("Trying to clone the repository instead...")
 			err := os.RemoveAll(cache)
 			if err != nil {
-				return burrito.WrapErrorf(err, osRemoveError, cache)
+				return "", burrito.WrapErrorf(err, osRemoveError, cache)
 			}
 			goto clone
 		}
@@ -471,15 +561,16 @@ clone:
 			Logger.Debugf(osChtimesError, cache)
 		}
 		// Fetch the repository
-		MeasureStart("Fetch repository tags %s", url)
+		measure = MeasureStart("Fetch repository tags %s", url)
 		output, err = RunGitProcess([]string{"fetch", "--tags"}, cache)
+		measure.End()
 		if err != nil {
 			Logger.Error(strings.Join(output, "\n"))
 			Logger.Errorf("Failed to fetch repository.\nURL: %s", url)
 			Logger.Infof("Trying to clone the repository instead...")
 			err := os.RemoveAll(cache)
 			if err != nil {
-				return burrito.WrapErrorf(err, osRemoveError, cache)
+				return "", burrito.WrapErrorf(err, osRemoveError, cache)
 			}
 			goto clone
 		}
@@ -489,20 +580,30 @@ clone:
 		}
 	}
 	// Checkout the specified ref
-	MeasureStart("Checkout ref %s", ref)
+	measure := MeasureStart("Checkout ref %s", ref)
 	output, err := RunGitProcess([]string{"checkout", ref}, cache)
-	if err != nil {
+	measure.End()
+	if err != nil && !fetched {
+		// The ref may be missing in an outdated cache (e.g. a new commit)
+		Logger.Debugf("Failed to checkout ref %s. Fetching the repository...", ref)
+		forceUpdate = true
+		goto fetch
+	} else if err != nil {
 		Logger.Error(strings.Join(output, "\n"))
-		return burrito.WrapErrorf(err, "Failed to checkout ref.\nURL: %s\nRef: %s", url, ref)
+		return "", burrito.WrapErrorf(err, "Failed to checkout ref.\nURL: %s\nRef: %s", url, ref)
+	}
+	commit, err := getRepositoryCommit(cache)
+	if err != nil {
+		return "", burrito.PassError(err)
 	}
 	// Copy to download path
-	MeasureStart("Copy to download path %s", downloadPath)
+	measure = MeasureStart("Copy to download path %s", downloadPath)
 	err = copy.Copy(filepath.Join(cache, filter), downloadPath)
 	if err != nil {
-		return burrito.WrapErrorf(err, osCopyError, filepath.Join(cache, filter), downloadPath)
+		return "", burrito.WrapErrorf(err, osCopyError, filepath.Join(cache, filter), downloadPath)
 	}
-	MeasureEnd()
-	return nil
+	measure.End()
+	return commit, nil
 }
 
 // SaveVersionInfo saves puts the specified version string into the
@@ -530,7 +631,7 @@ func (f *RemoteFilterDefinition) LoadFilterJson(dotRegolithPath string) (map[str
 	filterJsonPath := path.Join(downloadPath, "filter.json")
 	filterJson, err1 := os.ReadFile(filterJsonPath)
 	var filterJsonMap map[string]any
-	err2 := json.Unmarshal(filterJson, &filterJsonMap)
+	err2 := unmarshalJSONC(filterJson, &filterJsonMap, filterJsonPath)
 	if err := firstErr(err1, err2); err != nil {
 		return nil, burrito.PassError(err)
 	}
@@ -554,28 +655,72 @@ func (f *RemoteFilterDefinition) InstalledVersion(dotRegolithPath string) (strin
 	return versionStr, nil
 }
 
-func (f *RemoteFilterDefinition) Update(force bool, dotRegolithPath, dataPath string, refreshFilters bool) error {
+// Update installs or updates the filter and its dependencies and records the
+// installed filter in the lock file. In the frozen mode, the filter is
+// always installed from the commit pinned in the lock file.
+func (f *RemoteFilterDefinition) Update(
+	force bool, dotRegolithPath, dataPath string, refreshFilters bool,
+	lockFile *LockFile, frozen bool,
+) error {
+	if frozen {
+		locked, ok := lockFile.Filters[f.Id]
+		if !ok {
+			return burrito.WrappedErrorf(
+				"The filter is missing in the lock file.\n"+
+					"Filter: %s\n"+
+					"Run \"regolith install-all\" without the \"--frozen\" "+
+					"flag to update the lock file.", f.Id)
+		}
+		if !f.matchesLockedFilter(locked) {
+			return burrito.WrappedErrorf(
+				lockFileOutdatedError, f.Id, f.Url, f.Version, locked.Url,
+				locked.Version)
+		}
+		err := f.DownloadLocked(locked, dotRegolithPath, refreshFilters)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		// Copy the data of the remote filter to the data path
+		f.CopyFilterData(dataPath, dotRegolithPath)
+		err = f.InstallDependencies(f, dotRegolithPath)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		Logger.Infof("Filter %q installed from the lock file.", f.Id)
+		return nil
+	}
 	installedVersion, err := f.InstalledVersion(dotRegolithPath)
 	installedVersion = trimFilterPrefix(installedVersion, f.Id)
 	if err != nil && force {
 		Logger.Warnf("Unable to get installed version of filter %q.", f.Id)
 	}
-	MeasureStart("Get remote filter download ref")
+	measure := MeasureStart("Get remote filter download ref")
 	version, err := GetRemoteFilterDownloadRef(f.Url, f.Id, f.Version)
 	if err != nil {
 		return burrito.WrapErrorf(
 			err, getRemoteFilterDownloadRefError, f.Url, f.Id, f.Version)
 	}
-	MeasureEnd()
+	measure.End()
 	version = trimFilterPrefix(version, f.Id)
-	if installedVersion != version || force {
-		Logger.Infof(
-			"Updating filter %q to new version: %q->%q.",
-			f.Id, installedVersion, version)
-		err = f.Download(true, dotRegolithPath, refreshFilters)
+	locked, isLocked := lockFile.Filters[f.Id]
+	isLocked = isLocked && locked.Url == f.Url && locked.Version == version
+	if installedVersion != version || force || !isLocked {
+		if installedVersion == version && !force {
+			// The filter must be downloaded again to get the hash of the
+			// original files
+			Logger.Infof(
+				"Filter %q is missing in the lock file. Reinstalling "+
+					"version %q.", f.Id, version)
+		} else {
+			Logger.Infof(
+				"Updating filter %q to new version: %q->%q.",
+				f.Id, installedVersion, version)
+		}
+		lockedFilter, err := f.Download(true, dotRegolithPath, refreshFilters)
 		if err != nil {
 			return burrito.PassError(err)
 		}
+		lockFile.Filters[f.Id] = *lockedFilter
 		// Copy the data of the remote filter to the data path
 		f.CopyFilterData(dataPath, dotRegolithPath)
 		err = f.InstallDependencies(f, dotRegolithPath)
@@ -614,15 +759,16 @@ func hasGit() bool {
 }
 
 // loadFilterConfig loads the remote filter configuration from the given path.
+// The file can contain comments and trailing commas.
 func loadFilterConfig(path string) (map[string]any, error) {
 	file, err := os.ReadFile(path)
 	if err != nil {
 		return nil, burrito.WrapErrorf(err, fileReadError, path)
 	}
 	var filterCollection map[string]any
-	err = json.Unmarshal(file, &filterCollection)
+	err = unmarshalJSONC(file, &filterCollection, path)
 	if err != nil {
-		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, path)
+		return nil, burrito.PassError(err)
 	}
 	return filterCollection, nil
 }
@@ -630,9 +776,11 @@ func loadFilterConfig(path string) (map[string]any, error) {
 // extraFilterJsonErrorInfo is used to wrap errors related to parsing the
 // filter.json file. It's common for other functions to handle loading and
 // parsing of this file, so using this is necessary to provide both the
-// information about the file path and reuse the errors from errors.go
+// information about the file path and reuse the errors from errors.go. The
+// location of the invalid value is added to the error if it can be found.
 func extraFilterJsonErrorInfo(filterJsonFilePath string, err error) error {
 	return burrito.WrapErrorf(
-		err, "Failed to load the filter configuration.\n"+
+		locateJsonError(err, filterJsonFilePath),
+		"Failed to load the filter configuration.\n"+
 			"Filter configuration file: %s", filterJsonFilePath)
 }
@@ -81,18 +81,18 @@ func (f *ShellFilter) run(
 	settings map[string]any,
 	context RunContext,
 ) error {
-	absWorkingDir, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
 	if err != nil {
 		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
 	}
 	if len(settings) == 0 {
-		err = executeCommand(f.Id,
+		err = executeCommand(context, f.Id,
 			f.Definition.Command,
 			f.Arguments, context.AbsoluteLocation,
 			absWorkingDir)
 	} else {
 		jsonSettings, _ := json.Marshal(settings)
-		err = executeCommand(f.Id,
+		err = executeCommand(context, f.Id,
 			f.Definition.Command,
 			append([]string{string(jsonSettings)}, f.Arguments...),
 			context.AbsoluteLocation,
@@ -104,7 +104,7 @@ func (f *ShellFilter) run(
 	return nil
 }
 
-func executeCommand(id string,
+func executeCommand(context RunContext, id string,
 	command string, args []string, filterDir string, workingDir string,
 ) error {
 	joined := strings.Join(append([]string{command}, shellescape.QuoteCommand(args)), " ")
@@ -113,7 +113,7 @@ func executeCommand(id string,
 	if err != nil {
 		return burrito.WrapError(err, "Unable to find a valid shell.")
 	}
-	err = RunSubProcess(shell, []string{arg, joined}, filterDir, workingDir, ShortFilterName(id))
+	err = runFilterSubProcess(context, shell, []string{arg, joined}, filterDir, workingDir, ShortFilterName(id))
 	if err != nil {
 		return burrito.WrapError(err, runSubProcessError)
 	}
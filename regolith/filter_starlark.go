@@ -0,0 +1,245 @@
+package regolith
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// StarlarkFilterDefinition is a definition of a filter that runs a Starlark
+// script with the interpreter embedded in Regolith, so it doesn't require
+// any external runtime.
+type StarlarkFilterDefinition struct {
+	FilterDefinition
+	Script string `json:"script,omitempty"`
+}
+
+type StarlarkFilter struct {
+	Filter
+	Definition StarlarkFilterDefinition `json:"-"`
+}
+
+// scriptRoots are the directories of the tmp directory that are available
+// to the scripts of the embedded filters.
+var scriptRoots = []string{"RP", "BP", "data"}
+
+func StarlarkFilterDefinitionFromObject(id string, obj map[string]any) (*StarlarkFilterDefinition, error) {
+	filter := &StarlarkFilterDefinition{FilterDefinition: *FilterDefinitionFromObject(id)}
+	scriptObj, ok := obj["script"]
+	if !ok {
+		return nil, burrito.WrappedErrorf(jsonPropertyMissingError, "script")
+	}
+	script, ok := scriptObj.(string)
+	if !ok {
+		return nil, burrito.WrappedErrorf(
+			jsonPropertyTypeError, "script", "string")
+	}
+	filter.Script = script
+	return filter, nil
+}
+
+func (f *StarlarkFilter) run(context RunContext) error {
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	scriptPath := filepath.Join(context.AbsoluteLocation, f.Definition.Script)
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileReadError, scriptPath)
+	}
+	err = runStarlarkScript(
+		f, context, scriptFileSystem{root: absWorkingDir}, scriptPath, script)
+	if err != nil {
+		return burrito.WrapErrorf(err, "Failed to run Starlark script.")
+	}
+	return nil
+}
+
+func (f *StarlarkFilter) Run(context RunContext) (bool, error) {
+	if err := f.run(context); err != nil {
+		return false, burrito.PassError(err)
+	}
+	return context.IsInterrupted(), nil
+}
+
+func (f *StarlarkFilterDefinition) CreateFilterRunner(runConfiguration map[string]any, id string) (FilterRunner, error) {
+	basicFilter, err := filterFromObject(runConfiguration, id)
+	if err != nil {
+		return nil, burrito.WrapError(err, filterFromObjectError)
+	}
+	filter := &StarlarkFilter{
+		Filter:     *basicFilter,
+		Definition: *f,
+	}
+	return filter, nil
+}
+
+func (f *StarlarkFilterDefinition) Check(context RunContext) error {
+	return nil
+}
+
+func (f *StarlarkFilterDefinition) InstallDependencies(
+	parent *RemoteFilterDefinition, dotRegolithPath string,
+) error {
+	return nil
+}
+
+func (f *StarlarkFilter) Check(context RunContext) error {
+	return f.Definition.Check(context)
+}
+
+// scriptFileSystem is the file API available to the scripts of the embedded
+// filters. The paths used by the scripts are relative to the tmp directory,
+// use forward slashes and must start with one of the scriptRoots, for
+// example "BP/entities/player.json". The scripts can't access any other
+// files.
+type scriptFileSystem struct {
+	// root is the absolute path to the tmp directory.
+	root string
+}
+
+// resolve returns the absolute path to the file from the path used by the
+// script. It returns an error if the path is outside of the scriptRoots.
+func (s scriptFileSystem) resolve(path string) (string, error) {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	root, _, _ := strings.Cut(clean, "/")
+	if filepath.IsAbs(path) || !slices.Contains(scriptRoots, root) {
+		return "", burrito.WrappedErrorf(scriptPathOutsideSandboxError, path)
+	}
+	result := filepath.Join(s.root, filepath.FromSlash(clean))
+	// Don't follow the symlinks that lead outside of the sandbox. The path
+	// may not exist yet, so the check uses its nearest existing ancestor.
+	rootPath, err := evalExistingSymlinks(filepath.Join(s.root, root))
+	if err != nil {
+		return "", burrito.WrapErrorf(err, osStatErrorAny, root)
+	}
+	resolved, err := evalExistingSymlinks(result)
+	if err != nil {
+		return "", burrito.WrapErrorf(err, osStatErrorAny, path)
+	}
+	if !isInDir(resolved, rootPath) {
+		return "", burrito.WrappedErrorf(scriptPathOutsideSandboxError, path)
+	}
+	return result, nil
+}
+
+// evalExistingSymlinks returns the path with the symlinks evaluated like
+// filepath.EvalSymlinks, but it also accepts the paths that don't exist. The
+// symlinks of the nearest existing ancestor of the path are evaluated and the
+// remaining parts of the path are joined to the result.
+func evalExistingSymlinks(path string) (string, error) {
+	var rest []string
+	existing := path
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", burrito.PassError(err)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil // Nothing on the path exists
+		}
+		rest = append([]string{filepath.Base(existing)}, rest...)
+		existing = parent
+	}
+}
+
+// read returns the content of the file.
+func (s scriptFileSystem) read(path string) ([]byte, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, path)
+	}
+	return content, nil
+}
+
+// write saves the content to the file. It creates the parent directories
+// if they don't exist.
+func (s scriptFileSystem) write(path string, content []byte) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, filepath.Dir(path))
+	}
+	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, path)
+	}
+	return nil
+}
+
+// remove deletes the file or the directory with all of its content.
+func (s scriptFileSystem) remove(path string) error {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	// The root directories are required by the next filters
+	if slices.Contains(scriptRoots, filepath.ToSlash(filepath.Clean(path))) {
+		return burrito.WrappedErrorf(scriptPathOutsideSandboxError, path)
+	}
+	if err := os.RemoveAll(fullPath); err != nil {
+		return burrito.WrapErrorf(err, osRemoveError, path)
+	}
+	return nil
+}
+
+// exists returns whether the file or directory exists.
+func (s scriptFileSystem) exists(path string) (bool, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return false, burrito.PassError(err)
+	}
+	_, err = os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, burrito.WrapErrorf(err, osStatErrorAny, path)
+	}
+	return true, nil
+}
+
+// list returns the sorted paths of all files in the directory and its
+// subdirectories. The paths use the same format as the paths passed to the
+// other functions of the scriptFileSystem.
+func (s scriptFileSystem) list(path string) ([]string, error) {
+	fullPath, err := s.resolve(path)
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	result := []string{}
+	err = filepath.WalkDir(fullPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return burrito.WrapErrorf(err, filepathRelError, s.root, p)
+		}
+		result = append(result, filepath.ToSlash(relPath))
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, burrito.WrapErrorf(err, osWalkError, path)
+	}
+	slices.Sort(result)
+	return result, nil
+}
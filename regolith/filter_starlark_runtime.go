@@ -0,0 +1,217 @@
+package regolith
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// runStarlarkScript runs the Starlark script of the filter. The script can
+// use the following predeclared values:
+//   - settings - the settings of the filter
+//   - args - the list of the arguments of the filter
+//   - json - the "json" module with the encode and decode functions
+//   - read(path), write(path, content), remove(path), exists(path) and
+//     list(path) - the functions for working with the files of the RP, BP
+//     and data directories
+func runStarlarkScript(
+	f *StarlarkFilter, context RunContext, files scriptFileSystem,
+	scriptPath string, script []byte,
+) error {
+	label := ShortFilterName(f.Id)
+	thread := &starlark.Thread{
+		Name: label,
+		Print: func(_ *starlark.Thread, msg string) {
+			Logger.Infof("[%s] %s", label, msg)
+		},
+	}
+	// Stop the script when the filter is cancelled
+	finished := make(chan struct{})
+	defer close(finished)
+	if context.cancel != nil {
+		go func() {
+			select {
+			case <-context.cancel:
+				thread.Cancel(subProcessCancelledError)
+			case <-finished:
+			}
+		}()
+	}
+	settings, err := toStarlarkValue(f.Settings)
+	if err != nil {
+		return burrito.WrapError(err, "Failed to convert the settings of the filter.")
+	}
+	args := make([]starlark.Value, len(f.Arguments))
+	for i, arg := range f.Arguments {
+		args[i] = starlark.String(arg)
+	}
+	predeclared := starlark.StringDict{
+		"settings": settings,
+		"args":     starlark.NewList(args),
+		"json":     starlarkjson.Module,
+		"read":     starlark.NewBuiltin("read", starlarkRead(files)),
+		"write":    starlark.NewBuiltin("write", starlarkWrite(files)),
+		"remove":   starlark.NewBuiltin("remove", starlarkRemove(files)),
+		"exists":   starlark.NewBuiltin("exists", starlarkExists(files)),
+		"list":     starlark.NewBuiltin("list", starlarkList(files)),
+	}
+	_, err = starlark.ExecFile(thread, scriptPath, script, predeclared)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return burrito.WrappedError(evalErr.Backtrace())
+		}
+		return burrito.WrapError(err, "Failed to parse the script.")
+	}
+	return nil
+}
+
+// starlarkBuiltin is a signature of the functions implementing the built-in
+// functions of Starlark.
+type starlarkBuiltin = func(
+	thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error)
+
+// starlarkError converts a Regolith error to a single line error returned
+// by the built-in functions.
+func starlarkError(fn *starlark.Builtin, err error) error {
+	return fmt.Errorf("%s: %s", fn.Name(), errorSummary(err))
+}
+
+func starlarkRead(files scriptFileSystem) starlarkBuiltin {
+	return func(
+		_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+			return nil, err
+		}
+		content, err := files.read(path)
+		if err != nil {
+			return nil, starlarkError(fn, err)
+		}
+		return starlark.String(content), nil
+	}
+}
+
+func starlarkWrite(files scriptFileSystem) starlarkBuiltin {
+	return func(
+		_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var path, content string
+		err := starlark.UnpackArgs(
+			fn.Name(), args, kwargs, "path", &path, "content", &content)
+		if err != nil {
+			return nil, err
+		}
+		if err := files.write(path, []byte(content)); err != nil {
+			return nil, starlarkError(fn, err)
+		}
+		return starlark.None, nil
+	}
+}
+
+func starlarkRemove(files scriptFileSystem) starlarkBuiltin {
+	return func(
+		_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+			return nil, err
+		}
+		if err := files.remove(path); err != nil {
+			return nil, starlarkError(fn, err)
+		}
+		return starlark.None, nil
+	}
+}
+
+func starlarkExists(files scriptFileSystem) starlarkBuiltin {
+	return func(
+		_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+			return nil, err
+		}
+		exists, err := files.exists(path)
+		if err != nil {
+			return nil, starlarkError(fn, err)
+		}
+		return starlark.Bool(exists), nil
+	}
+}
+
+func starlarkList(files scriptFileSystem) starlarkBuiltin {
+	return func(
+		_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple,
+		kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		var path string
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "path", &path); err != nil {
+			return nil, err
+		}
+		paths, err := files.list(path)
+		if err != nil {
+			return nil, starlarkError(fn, err)
+		}
+		result := make([]starlark.Value, len(paths))
+		for i, p := range paths {
+			result[i] = starlark.String(p)
+		}
+		return starlark.NewList(result), nil
+	}
+}
+
+// toStarlarkValue converts a value parsed from JSON to a Starlark value.
+func toStarlarkValue(value any) (starlark.Value, error) {
+	switch value := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(value), nil
+	case string:
+		return starlark.String(value), nil
+	case float64:
+		if value == float64(int64(value)) {
+			return starlark.MakeInt64(int64(value)), nil
+		}
+		return starlark.Float(value), nil
+	case []any:
+		items := make([]starlark.Value, len(value))
+		for i, item := range value {
+			converted, err := toStarlarkValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		return starlark.NewList(items), nil
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		dict := starlark.NewDict(len(value))
+		for _, key := range keys {
+			converted, err := toStarlarkValue(value[key])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(key), converted); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
@@ -0,0 +1,276 @@
+package regolith
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/otiai10/copy"
+)
+
+// filterTestsDir is the default directory with the test cases of the filters
+// used by "regolith test-filter". The test cases of a filter are stored in a
+// subdirectory named after the filter.
+const filterTestsDir = "filter_tests"
+
+// filterTestRoots are the directories of the tmp directory that are compared
+// with the expected output of the filter tests.
+var filterTestRoots = []string{"RP", "BP", "data"}
+
+// TestFilter handles the "regolith test-filter" command. It runs the filter
+// on the input files of every test case from the testsPath directory and
+// compares the results with the expected output. If update is true, the
+// expected output of the failing test cases is replaced with the actual
+// output of the filter.
+//
+// Every subdirectory of the testsPath is a test case with the following
+// layout:
+//   - input/RP, input/BP, input/data - the files passed to the filter
+//   - expected/RP, expected/BP, expected/data - the expected output
+//   - settings.json - the optional settings of the filter
+//
+// If testsPath is empty, the "filter_tests/<filter name>" directory of the
+// project is used.
+func TestFilter(
+	filterName string, filterArgs []string, testsPath string, update bool,
+	debug bool, env string,
+) error {
+	InitLogging(debug)
+	defer ShutdownLogging()
+	if err := loadEnvFileFromArg(env); err != nil {
+		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
+	}
+	// Load the Config and the filter definition
+	configJson, err := LoadConfigAsMap()
+	if err != nil {
+		return burrito.WrapError(err, "Could not load \"config.json\".")
+	}
+	config, err := configFromFileObject(configJson, ConfigFilePath)
+	if err != nil {
+		return burrito.WrapError(err, "Could not load \"config.json\".")
+	}
+	filterDefinition, ok := config.FilterDefinitions[filterName]
+	if !ok {
+		return burrito.WrappedErrorf(
+			"Unable to find the filter on the \"filterDefinitions\" list "+
+				"of the \"config.json\" file.\n"+
+				"Filter name: %s", filterName)
+	}
+	if testsPath == "" {
+		testsPath = filepath.Join(filterTestsDir, filterName)
+	}
+	testCases, err := listFilterTestCases(testsPath)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	// Get dotRegolithPath
+	dotRegolithPath, err := GetDotRegolith(".")
+	if err != nil {
+		return burrito.WrapError(
+			err, "Unable to get the path to regolith cache folder.")
+	}
+	err = os.MkdirAll(dotRegolithPath, 0755)
+	if err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, dotRegolithPath)
+	}
+	// Lock the session
+	unlockSession, sessionLockErr := acquireSessionLock(dotRegolithPath)
+	if sessionLockErr != nil {
+		return burrito.WrapError(sessionLockErr, acquireSessionLockError)
+	}
+	defer func() { sessionLockErr = unlockSession() }()
+
+	// Run the test cases
+	var failed []string
+	for _, testCase := range testCases {
+		casePath := filepath.Join(testsPath, testCase)
+		Logger.Infof("Running the %q test case of the %q filter.", testCase, filterName)
+		passed, err := runFilterTestCase(
+			filterDefinition, filterName, filterArgs, casePath, config,
+			dotRegolithPath, update)
+		if err != nil {
+			return burrito.WrapErrorf(err, filterTestCaseError, testCase)
+		}
+		if !passed {
+			failed = append(failed, testCase)
+		}
+	}
+	if len(failed) > 0 {
+		return burrito.WrappedErrorf(
+			filterTestsFailedError, len(failed), len(testCases),
+			strings.Join(failed, ", "))
+	}
+	Logger.Infof("All %d test cases of the %q filter passed.", len(testCases), filterName)
+	return sessionLockErr // Return the error from the defer function
+}
+
+// listFilterTestCases returns the sorted names of the test cases in the
+// directory.
+func listFilterTestCases(testsPath string) ([]string, error) {
+	entries, err := os.ReadDir(testsPath)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, osReadDirError, testsPath)
+	}
+	var result []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			result = append(result, entry.Name())
+		}
+	}
+	if len(result) == 0 {
+		return nil, burrito.WrappedErrorf(filterTestsNotFoundError, testsPath)
+	}
+	return result, nil
+}
+
+// runFilterTestCase runs a single test case of the filter and returns true
+// if the output of the filter matches the expected output. The differences
+// are printed as unified diffs. If update is true, the expected output is
+// replaced with the actual output and the test case passes.
+func runFilterTestCase(
+	filterDefinition FilterInstaller, filterName string, filterArgs []string,
+	casePath string, config *Config, dotRegolithPath string, update bool,
+) (bool, error) {
+	// Create the filter
+	runConfiguration := map[string]any{
+		"arguments": filterArgs,
+	}
+	settingsPath := filepath.Join(casePath, "settings.json")
+	if content, err := os.ReadFile(settingsPath); err == nil {
+		var settings map[string]any
+		if err := json.Unmarshal(content, &settings); err != nil {
+			return false, burrito.WrapErrorf(err, jsonUnmarshalError, settingsPath)
+		}
+		runConfiguration["settings"] = settings
+	} else if !os.IsNotExist(err) {
+		return false, burrito.WrapErrorf(err, fileReadError, settingsPath)
+	}
+	filterRunner, err := filterDefinition.CreateFilterRunner(runConfiguration, filterName)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, createFilterRunnerError, filterName)
+	}
+	path, _ := filepath.Abs(".")
+	runContext := RunContext{
+		Config:           config,
+		Parent:           nil,
+		Profile:          "[dynamic profile]",
+		DotRegolithPath:  dotRegolithPath,
+		interruption:     nil,
+		AbsoluteLocation: path,
+		Settings:         filterRunner.GetSettings(),
+	}
+	err = filterRunner.Check(runContext)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerCheckError, filterName)
+	}
+	// Copy the input files to a new working directory, so that the test
+	// doesn't change the tmp directory of the project
+	absDotRegolithPath, err := filepath.Abs(dotRegolithPath)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filepathAbsError, dotRegolithPath)
+	}
+	workingDir, err := os.MkdirTemp(absDotRegolithPath, "filter-test-")
+	if err != nil {
+		return false, burrito.WrapErrorf(err, osMkdirError, absDotRegolithPath)
+	}
+	defer func() {
+		if err := os.RemoveAll(workingDir); err != nil {
+			Logger.Warnf("Failed to remove the working directory of the test.\n"+
+				"Path: %s\nError: %s", workingDir, err.Error())
+		}
+	}()
+	runContext.workingDir = workingDir
+	err = setupFilterTestFiles(filepath.Join(casePath, "input"), workingDir)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, setupTmpFilesError, dotRegolithPath)
+	}
+	// Run the filter
+	_, err = runFilter(filterRunner, runContext)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerRunError, filterName)
+	}
+	// Compare the output with the expected output
+	var sections []exportPlanSection
+	for _, root := range filterTestRoots {
+		expectedPath := filepath.Join(casePath, "expected", root)
+		actualPath := filepath.Join(workingDir, root)
+		changes, err := CompareDirectories(actualPath, expectedPath)
+		if err != nil {
+			return false, burrito.WrapErrorf(
+				err, "Failed to compare the output of the filter.\nPath: %s",
+				expectedPath)
+		}
+		if len(changes) > 0 {
+			sections = append(sections, exportPlanSection{
+				title:       root,
+				source:      actualPath,
+				destination: expectedPath,
+				changes:     changes,
+			})
+		}
+	}
+	if len(sections) == 0 {
+		Logger.Infof("PASS: %s", casePath)
+		return true, nil
+	}
+	if update {
+		for _, section := range sections {
+			err := replaceDirectory(section.source, section.destination)
+			if err != nil {
+				return false, burrito.PassError(err)
+			}
+		}
+		Logger.Infof("UPDATED: %s", casePath)
+		return true, nil
+	}
+	Logger.Errorf("FAIL: %s", casePath)
+	fmt.Fprintf(
+		os.Stdout,
+		"The output of the filter doesn't match the expected output of %q "+
+			"(\"-\" expected, \"+\" actual):\n", casePath)
+	printFileChanges(os.Stdout, sections, true)
+	fmt.Fprintln(os.Stdout)
+	return false, nil
+}
+
+// setupFilterTestFiles copies the input files of the test case to the empty
+// working directory of the test. The missing input directories are created
+// empty.
+func setupFilterTestFiles(inputPath, workingDir string) error {
+	for _, root := range filterTestRoots {
+		source := filepath.Join(inputPath, root)
+		target := filepath.Join(workingDir, root)
+		if _, err := os.Stat(source); err == nil {
+			err = copy.Copy(source, target, copy.Options{PreserveTimes: false, Sync: false})
+			if err != nil {
+				return burrito.WrapErrorf(err, osCopyError, source, target)
+			}
+		} else if os.IsNotExist(err) {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return burrito.WrapErrorf(err, osMkdirError, target)
+			}
+		} else {
+			return burrito.WrapErrorf(err, osStatErrorAny, source)
+		}
+	}
+	return nil
+}
+
+// replaceDirectory replaces the content of the destination directory with
+// the content of the source directory.
+func replaceDirectory(source, destination string) error {
+	if err := os.RemoveAll(destination); err != nil {
+		return burrito.WrapErrorf(err, osRemoveError, destination)
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, destination)
+	}
+	err := copy.Copy(source, destination, copy.Options{PreserveTimes: false, Sync: false})
+	if err != nil {
+		return burrito.WrapErrorf(err, osCopyError, source, destination)
+	}
+	return nil
+}
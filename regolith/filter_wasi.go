@@ -0,0 +1,243 @@
+package regolith
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// WasmFilterDefinition is a definition of a filter that runs a WebAssembly
+// module compiled for WASI. The module runs in the runtime embedded in
+// Regolith, so a single file works on every platform.
+type WasmFilterDefinition struct {
+	FilterDefinition
+	Wasm string `json:"wasm,omitempty"`
+}
+
+type WasmFilter struct {
+	Filter
+	Definition WasmFilterDefinition `json:"-"`
+}
+
+// wasmFilterDir is the path under which the directory of the filter is
+// available to the WebAssembly modules.
+const wasmFilterDir = "/filter"
+
+func WasmFilterDefinitionFromObject(id string, obj map[string]any) (*WasmFilterDefinition, error) {
+	filter := &WasmFilterDefinition{FilterDefinition: *FilterDefinitionFromObject(id)}
+	wasmObj, ok := obj["wasm"]
+	if !ok {
+		return nil, burrito.WrappedErrorf(jsonPropertyMissingError, "wasm")
+	}
+	wasm, ok := wasmObj.(string)
+	if !ok {
+		return nil, burrito.WrappedErrorf(jsonPropertyTypeError, "wasm", "string")
+	}
+	filter.Wasm = wasm
+	return filter, nil
+}
+
+func (f *WasmFilter) run(context RunContext) error {
+	absWorkingDir, err := context.GetAbsoluteWorkingDirectory()
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	modulePath := filepath.Join(context.AbsoluteLocation, f.Definition.Wasm)
+	module, err := os.ReadFile(modulePath)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileReadError, modulePath)
+	}
+	// The arguments are passed the same way as to the Python filters
+	args := []string{filepath.Base(modulePath)}
+	if len(f.Settings) != 0 {
+		jsonSettings, _ := json.Marshal(f.Settings)
+		args = append(args, string(jsonSettings))
+	}
+	args = append(args, f.Arguments...)
+	err = runWasmModule(wasmModuleRun{
+		cancel:      context.cancel,
+		module:      module,
+		args:        args,
+		workingDir:  absWorkingDir,
+		filterDir:   filepath.Dir(modulePath),
+		cacheDir:    filepath.Join(context.DotRegolithPath, "cache", "wasm"),
+		outputLabel: ShortFilterName(f.Id),
+		reportSpan:  context.reportSpan,
+	})
+	if err != nil {
+		return burrito.WrapError(err, "Failed to run WebAssembly module.")
+	}
+	return nil
+}
+
+func (f *WasmFilter) Run(context RunContext) (bool, error) {
+	if err := f.run(context); err != nil {
+		return false, burrito.PassError(err)
+	}
+	return context.IsInterrupted(), nil
+}
+
+func (f *WasmFilterDefinition) CreateFilterRunner(runConfiguration map[string]any, id string) (FilterRunner, error) {
+	basicFilter, err := filterFromObject(runConfiguration, id)
+	if err != nil {
+		return nil, burrito.WrapError(err, filterFromObjectError)
+	}
+	filter := &WasmFilter{
+		Filter:     *basicFilter,
+		Definition: *f,
+	}
+	return filter, nil
+}
+
+func (f *WasmFilterDefinition) Check(context RunContext) error {
+	return nil
+}
+
+func (f *WasmFilterDefinition) InstallDependencies(
+	parent *RemoteFilterDefinition, dotRegolithPath string,
+) error {
+	return nil
+}
+
+func (f *WasmFilter) Check(context RunContext) error {
+	return f.Definition.Check(context)
+}
+
+// wasmModuleRun describes a single run of a WebAssembly module.
+type wasmModuleRun struct {
+	// cancel stops the module when closed. It can be nil.
+	cancel <-chan struct{}
+	// module is the content of the .wasm file.
+	module []byte
+	// args are the command line arguments passed to the module, including
+	// the name of the program.
+	args []string
+	// workingDir is the tmp directory pre-opened as the root directory and
+	// the working directory of the module.
+	workingDir string
+	// filterDir is the directory of the filter, available to the module in
+	// read-only mode as wasmFilterDir.
+	filterDir string
+	// cacheDir is the directory used for caching the compiled modules.
+	cacheDir string
+	// outputLabel is the label used for logging the output of the module.
+	outputLabel string
+	// reportSpan is the span of the filter in the report of the run, which
+	// records the filter events. It can be nil.
+	reportSpan *reportSpan
+}
+
+// runWasmModule runs a WebAssembly module using WASI. The output of the
+// module is handled the same way as the output of the sub-processes of the
+// other filters, including the filter events.
+func runWasmModule(r wasmModuleRun) error {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	// Stop the module on cancellation
+	var stopReason string
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+	if r.cancel != nil {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			defer close(stopped)
+			defer signal.Stop(sigChan)
+			select {
+			case <-r.cancel:
+				stopReason = subProcessCancelledError
+			case <-sigChan:
+				stopReason = subProcessStoppedByUserError
+			case <-finished:
+				return
+			}
+			cancelCtx()
+		}()
+	} else {
+		close(stopped)
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if err := os.MkdirAll(r.cacheDir, 0755); err == nil {
+		cache, err := wazero.NewCompilationCacheWithDir(r.cacheDir)
+		if err == nil {
+			defer cache.Close(ctx)
+			runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+		} else {
+			Logger.Debugf("Failed to create the WebAssembly cache: %s", err.Error())
+		}
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer runtime.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	compiled, err := runtime.CompileModule(ctx, r.module)
+	if err != nil {
+		close(finished)
+		<-stopped
+		return burrito.WrapError(err, wasmCompileError)
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		logStd(stdoutReader, Logger.Infof, r.outputLabel, r.reportSpan)
+	}()
+	go func() {
+		defer wg.Done()
+		logStd(stderrReader, Logger.Errorf, r.outputLabel, r.reportSpan)
+	}()
+
+	moduleConfig := wazero.NewModuleConfig().
+		WithArgs(r.args...).
+		WithStdout(stdoutWriter).
+		WithStderr(stderrWriter).
+		WithFSConfig(wazero.NewFSConfig().
+			WithDirMount(r.workingDir, "/").
+			WithReadOnlyDirMount(r.filterDir, wasmFilterDir)).
+		WithEnv("FILTER_DIR", wasmFilterDir).
+		WithEnv("DEBUG", strconv.FormatBool(burrito.PrintStackTrace)).
+		WithEnv("REGOLITH_EVENT_PREFIX", FilterEventPrefix).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	Logger.Debugf("Exec: %s", strings.Join(r.args, " "))
+	_, err = runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
+	close(finished)
+	<-stopped
+	if stopReason != "" {
+		return burrito.WrappedError(stopReason)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == 0 {
+			return nil
+		}
+		return burrito.WrappedErrorf(wasmExitCodeError, exitErr.ExitCode())
+	}
+	if err != nil {
+		return burrito.WrapError(err, wasmRunError)
+	}
+	return nil
+}
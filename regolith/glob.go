@@ -0,0 +1,60 @@
+package regolith
+
+import (
+	"path"
+	"strings"
+)
+
+// The glob patterns match the slash-separated paths. They use the syntax of
+// path.Match for the path segments, extended with the "**" segment, which
+// matches any number of segments. For example "RP/**/*.png" matches all PNG
+// files in the "RP" directory and its subdirectories.
+
+// isValidGlob returns true if the glob pattern has a valid syntax.
+func isValidGlob(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGlob returns true if the slash-separated path matches the glob
+// pattern.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(
+		strings.Split(pattern, "/"), strings.Split(name, "/"), false)
+}
+
+// matchGlobDir returns true if the slash-separated path matches the glob
+// pattern or if it's a path of a directory that can contain the paths that
+// match the pattern.
+func matchGlobDir(pattern, name string) bool {
+	return matchGlobSegments(
+		strings.Split(pattern, "/"), strings.Split(name, "/"), true)
+}
+
+// matchGlobSegments matches the segments of the path with the segments of
+// the glob pattern. If prefix is true, the path can also match only the
+// beginning of the pattern.
+func matchGlobSegments(pattern, name []string, prefix bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return prefix
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
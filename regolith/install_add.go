@@ -27,10 +27,13 @@ type parsedInstallFilterArg struct {
 
 // installFilters installs the filters from the list and their dependencies,
 // and copies their data to the data path. If the filter is already installed,
-// it returns an error unless the force flag is set.
+// it returns an error unless the force flag is set. The installed remote
+// filters are recorded in the lock file. In the frozen mode, the remote
+// filters are installed from the commits pinned in the lock file instead.
 func installFilters(
 	filtersToInstall map[string]FilterInstaller, force bool,
 	dataPath, dotRegolithPath string, refreshFilters bool,
+	lockFile *LockFile, frozen bool,
 ) error {
 	joinedPath := filepath.Join(dotRegolithPath, "cache/filters")
 	err := os.MkdirAll(joinedPath, 0755)
@@ -48,7 +51,8 @@ func installFilters(
 		Logger.Infof("Downloading %q filter...", name)
 		if remoteFilter, ok := filterDefinition.(*RemoteFilterDefinition); ok {
 			// Download the remote filter, and its dependencies
-			err := remoteFilter.Update(force, dotRegolithPath, dataPath, refreshFilters)
+			err := remoteFilter.Update(
+				force, dotRegolithPath, dataPath, refreshFilters, lockFile, frozen)
 			if err != nil {
 				return burrito.WrapErrorf(err, remoteFilterDownloadError, name)
 			}
@@ -95,14 +99,14 @@ func addFiltersToConfig(
 				return burrito.WrapErrorf(
 					err, "Profile %s does not exist or is invalid.", profile)
 			}
-			if profileMap["filters"] == nil {
-				profileMap["filters"] = make([]any, 0)
-			}
 			// Add the filter to the profile
-			profileMap["filters"] = append(
-				profileMap["filters"].([]any), map[string]any{
-					"filter": name,
-				})
+			err = appendProfileFilterObject(profileMap, map[string]any{
+				"filter": name,
+			})
+			if err != nil {
+				return burrito.WrapErrorf(
+					err, "Failed to add the filter to the %s profile.", profile)
+			}
 		}
 	}
 	// Save the config file
@@ -297,3 +301,32 @@ func trimFilterPrefix(versionTag, prefix string) string {
 	}
 	return versionTag
 }
+
+// appendProfileFilterObject adds the filter to the end of the "filters"
+// list of the profile object. The profiles that extend other profiles
+// and don't replace their filters get the filter in the "append" list of
+// their "filters" object.
+func appendProfileFilterObject(profileMap map[string]any, filter map[string]any) error {
+	filters, ok := profileMap["filters"]
+	if !ok || filters == nil {
+		if _, ok := profileMap["extends"]; ok {
+			filters = map[string]any{}
+		} else {
+			filters = []any{}
+		}
+	}
+	switch filters := filters.(type) {
+	case []any:
+		profileMap["filters"] = append(filters, filter)
+	case map[string]any:
+		appended, ok := filters["append"].([]any)
+		if _, exists := filters["append"]; exists && !ok {
+			return burrito.WrappedErrorf(jsonPathTypeError, "filters->append", "array")
+		}
+		filters["append"] = append(appended, filter)
+		profileMap["filters"] = filters
+	default:
+		return burrito.WrappedErrorf(jsonPathTypeError, "filters", "array or object")
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package regolith
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// Functions for reading the JSON files that can contain comments and trailing
+// commas (JSONC), like "config.json" and "filter.json". The comments and the
+// trailing commas are replaced with whitespace instead of being removed, so
+// the offsets of the JSON errors point to the same place in the original
+// file and can be shown as line and column numbers.
+
+// jsoncSyntaxError is a syntax error of the JSONC data found by stripJSONC,
+// like an unterminated block comment. Offset is the offset of the invalid
+// part of the data.
+type jsoncSyntaxError struct {
+	msg    string
+	Offset int64
+}
+
+func (e *jsoncSyntaxError) Error() string { return e.msg }
+
+// stripJSONC returns a copy of the JSONC data with the comments and the
+// trailing commas replaced with spaces. The new lines are preserved. It
+// returns a *jsoncSyntaxError if a block comment isn't terminated.
+func stripJSONC(data []byte) ([]byte, error) {
+	result := bytes.Clone(data)
+	// Skip the UTF-8 byte order mark
+	if bytes.HasPrefix(result, []byte{0xEF, 0xBB, 0xBF}) {
+		copy(result, "   ")
+	}
+	// lastComma is the position of the comma that is trailing if the next
+	// character that isn't whitespace closes an object or an array
+	lastComma := -1
+	for i := 0; i < len(result); i++ {
+		switch c := result[i]; {
+		case c == '"':
+			lastComma = -1
+			for i++; i < len(result) && result[i] != '"'; i++ {
+				if result[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(result) && result[i+1] == '/':
+			for ; i < len(result) && result[i] != '\n'; i++ {
+				result[i] = ' '
+			}
+		case c == '/' && i+1 < len(result) && result[i+1] == '*':
+			end := bytes.Index(result[i+2:], []byte("*/"))
+			if end == -1 {
+				return nil, &jsoncSyntaxError{
+					msg:    "unterminated block comment",
+					Offset: int64(i),
+				}
+			}
+			for end += i + 4; i < end; i++ {
+				if result[i] != '\n' && result[i] != '\r' {
+					result[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma != -1 {
+				result[lastComma] = ' '
+			}
+			lastComma = -1
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			lastComma = -1
+		}
+	}
+	return result, nil
+}
+
+// unmarshalJSONC parses the JSONC data and stores the result in the value
+// pointed to by v. The path is the path to the file used in the error
+// messages, which include the line and column of the error.
+func unmarshalJSONC(data []byte, v any, path string) error {
+	stripped, err := stripJSONC(data)
+	if err == nil {
+		err = json.Unmarshal(stripped, v)
+	}
+	if err == nil {
+		return nil
+	}
+	if offset, ok := jsonErrorOffset(err); ok {
+		path = jsonOffsetLocation(path, data, offset)
+	}
+	return burrito.WrapErrorf(err, jsonUnmarshalError, path)
+}
+
+// jsonErrorOffset returns the offset of the invalid part of the JSON data
+// from the error returned by json.Unmarshal. It returns false if the error
+// doesn't have an offset.
+func jsonErrorOffset(err error) (int, bool) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var jsoncErr *jsoncSyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset of the syntax error is the offset after the invalid
+		// character
+		return int(syntaxErr.Offset) - 1, true
+	case errors.As(err, &typeErr):
+		return int(typeErr.Offset), true
+	case errors.As(err, &jsoncErr):
+		return int(jsoncErr.Offset), true
+	}
+	return 0, false
+}
+
+// jsonOffsetLocation returns the location of the byte with the given offset
+// in the "path:line:column" format. The line and the column start at 1.
+func jsonOffsetLocation(path string, data []byte, offset int) string {
+	offset = min(max(offset, 0), len(data))
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - (bytes.LastIndexByte(data[:offset], '\n') + 1) + 1
+	return fmt.Sprintf("%s:%d:%d", path, line, column)
+}
+
+// jsonValueOffsets returns the offsets of all values of the JSONC data. The
+// keys of the map are the JSON paths of the values, using the same format as
+// the paths in the error messages ("profiles->default->filters->0"). The
+// path of the root value is an empty string.
+func jsonValueOffsets(data []byte) (map[string]int, error) {
+	stripped, err := stripJSONC(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(stripped))
+	result := map[string]int{}
+	// valueStart returns the offset of the next value, skipping the
+	// whitespace and the separators after the last token
+	valueStart := func() int {
+		offset := int(decoder.InputOffset())
+		for offset < len(stripped) && strings.IndexByte(" \t\r\n,:", stripped[offset]) != -1 {
+			offset++
+		}
+		return offset
+	}
+	var parseValue func(path string) error
+	parseValue = func(path string) error {
+		result[path] = valueStart()
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		prefix := path
+		if prefix != "" {
+			prefix += "->"
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				if err := parseValue(prefix + fmt.Sprint(key)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if err := parseValue(prefix + strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+	if err := parseValue(""); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return result, nil
+}
+
+// jsonErrorPathPattern matches the JSON paths and the properties in the error
+// messages created with the jsonPath* and jsonProperty* error templates.
+var jsonErrorPathPattern = regexp.MustCompile(`(?m)^(?:JSON Path|Property): (.*)$`)
+
+// jsonErrorPath returns the JSON path of the value that caused the error. The
+// path is built from the paths of the nested errors, because each function
+// that parses a part of the JSON file reports the paths relative to the
+// object that it parses. If the innermost error is about a missing value,
+// the path of the object that should contain it is returned.
+func jsonErrorPath(err error) (string, bool) {
+	var parts []string
+	missing := false
+	for _, message := range burrito.GetAllMessages(err) {
+		match := jsonErrorPathPattern.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		parts = append(parts, match[1])
+		missing = strings.HasPrefix(message, "Required JSON")
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	path := strings.Join(parts, "->")
+	if missing {
+		path, _ = cutLastJsonPathPart(path)
+	}
+	return path, true
+}
+
+// cutLastJsonPathPart removes the last part of the JSON path. It returns
+// false if the path is empty.
+func cutLastJsonPathPart(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	if i := strings.LastIndex(path, "->"); i != -1 {
+		return path[:i], true
+	}
+	return "", true
+}
+
+// locateJsonError adds the location of the value that caused the error to
+// the error created while parsing the JSONC file. The location is found
+// using the JSON paths from the error messages. If the location can't be
+// found, the error is returned unchanged.
+func locateJsonError(err error, path string) error {
+	jsonPath, ok := jsonErrorPath(err)
+	if !ok {
+		return err
+	}
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return err
+	}
+	location, ok := jsonValueLocation(path, data, jsonPath)
+	if !ok {
+		return err
+	}
+	return burrito.WrapErrorf(err, jsonLocationError, location)
+}
+
+// jsonValueLocation returns the location of the value with the given JSON
+// path in the "path:line:column" format. If the value doesn't exist, the
+// location of its closest existing parent is used. It returns false if the
+// data isn't valid JSONC.
+func jsonValueLocation(path string, data []byte, jsonPath string) (string, bool) {
+	offsets, err := jsonValueOffsets(data)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if offset, ok := offsets[jsonPath]; ok {
+			return jsonOffsetLocation(path, data, offset), true
+		}
+		var ok bool
+		if jsonPath, ok = cutLastJsonPathPart(jsonPath); !ok {
+			return "", false
+		}
+	}
+}
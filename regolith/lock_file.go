@@ -0,0 +1,128 @@
+package regolith
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// LockFilePath is the path to the lock file of the project, relative to the
+// project root.
+const LockFilePath = "regolith.lock"
+
+// LockFile is the content of the "regolith.lock" file. It pins the remote
+// filters of the project to exact commits, so that every installation of the
+// project uses the same filter files.
+type LockFile struct {
+	Filters map[string]LockedFilter `json:"filters"`
+}
+
+// LockedFilter is a single remote filter entry of the lock file.
+type LockedFilter struct {
+	// Url is the URL of the repository with the filter.
+	Url string `json:"url"`
+	// Version is the version of the filter saved in its filter.json file.
+	Version string `json:"version"`
+	// Commit is the SHA of the git commit used to install the filter.
+	Commit string `json:"commit"`
+	// Hash is the hash of the installed filter files, created with
+	// HashFilterDirectory.
+	Hash string `json:"hash"`
+}
+
+// NewLockFile creates an empty lock file.
+func NewLockFile() *LockFile {
+	return &LockFile{Filters: map[string]LockedFilter{}}
+}
+
+// LoadLockFile loads the lock file from the given path. If the file doesn't
+// exist, it returns an empty lock file.
+func LoadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewLockFile(), nil
+	} else if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, path)
+	}
+	result := NewLockFile()
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, path)
+	}
+	if result.Filters == nil {
+		result.Filters = map[string]LockedFilter{}
+	}
+	return result, nil
+}
+
+// Dump saves the lock file to the given path. The lock file is not created
+// if it doesn't exist yet and there are no filters to lock.
+func (l *LockFile) Dump(path string) error {
+	if len(l.Filters) == 0 {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	result, err := json.MarshalIndent(l, "", "\t")
+	if err != nil { // This should never happen.
+		return burrito.WrapError(err, "Failed to marshal the lock file JSON.")
+	}
+	err = os.WriteFile(path, append(result, '\n'), 0644)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, path)
+	}
+	return nil
+}
+
+// HashFilterDirectory returns a hash of the paths and contents of all files
+// in the directory. Line endings are normalized before hashing, so the hash
+// doesn't depend on the line ending conversion done by git on different
+// operating systems.
+func HashFilterDirectory(root string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return burrito.WrapErrorf(err, filepathRelError, root, path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return burrito.WrapErrorf(err, fileReadError, path)
+		}
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		writeCacheKeyPart(h, filepath.ToSlash(relPath), content)
+		return nil
+	})
+	if err != nil {
+		return "", burrito.WrapErrorf(err, osWalkError, root)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// getRepositoryCommit returns the SHA of the commit checked out in the git
+// repository.
+func getRepositoryCommit(repositoryPath string) (string, error) {
+	commandArgs := []string{"rev-parse", "HEAD"}
+	cmd := exec.Command("git", commandArgs...)
+	cmd.Dir = repositoryPath
+	output, err := cmd.Output()
+	if err != nil {
+		commandText := "git " + strings.Join(commandArgs, " ")
+		return "", burrito.WrapErrorf(err, execCommandError, commandText)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"go.uber.org/zap"
+	"golang.org/x/mod/semver"
 )
 
 var disallowedFiles = []string{
@@ -120,11 +122,20 @@ func Install(filters []string, force, refreshResolvers, refreshFilters bool, pro
 		filterInstallers[parsedArg.name] = remoteFilterDefinition
 	}
 	// Download the filter definitions
+	lockFile, err := LoadLockFile(LockFilePath)
+	if err != nil {
+		return burrito.WrapError(err, loadLockFileError)
+	}
 	err = installFilters(
-		filterInstallers, force, dataPath, dotRegolithPath, refreshFilters)
+		filterInstallers, force, dataPath, dotRegolithPath, refreshFilters,
+		lockFile, false)
 	if err != nil {
 		return burrito.WrapError(err, "Failed to install filters.")
 	}
+	err = lockFile.Dump(LockFilePath)
+	if err != nil {
+		return burrito.WrapError(err, dumpLockFileError)
+	}
 
 	err = addFiltersToConfig(config, filterInstallers, profiles)
 	if err != nil {
@@ -147,20 +158,29 @@ func Install(filters []string, force, refreshResolvers, refreshFilters bool, pro
 // The "force" parameter is a boolean that determines if the installation
 // should be forced even if the filter is already installed.
 //
+// The "frozen" parameter is a boolean that determines if the remote filters
+// should be installed from the commits pinned in the "regolith.lock" file.
+// In this mode the lock file is not modified and the installation fails if
+// the files of a filter don't match the lock file.
+//
 // The "debug" parameter is a boolean that determines if the debug messages
 // should be printed.
-func InstallAll(force, update, debug, refreshFilters bool, env string) error {
+func InstallAll(force, update, debug, refreshFilters, frozen bool, env string) error {
 	InitLogging(debug)
 	defer ShutdownLogging()
 	if err := loadEnvFileFromArg(env); err != nil {
 		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
 	}
+	if frozen && update {
+		return burrito.WrappedError(
+			"The \"--frozen\" and \"--update\" flags can't be used together.")
+	}
 	Logger.Info("Installing filters...")
 	if !hasGit() {
 		Logger.Warn(gitNotInstalledWarning)
 	}
 	configMap, err1 := LoadConfigAsMap()
-	config, err2 := ConfigFromObject(configMap)
+	config, err2 := configFromFileObject(configMap, ConfigFilePath)
 	if err := firstErr(err1, err2); err != nil {
 		return burrito.WrapError(err, "Failed to load config.json.")
 	}
@@ -199,11 +219,28 @@ func InstallAll(force, update, debug, refreshFilters bool, env string) error {
 		filtersToInstall = config.FilterDefinitions
 	}
 	// Install the filters
+	lockFile, err := LoadLockFile(LockFilePath)
+	if err != nil {
+		return burrito.WrapError(err, loadLockFileError)
+	}
 	err = installFilters(
-		filtersToInstall, force, config.DataPath, dotRegolithPath, refreshFilters)
+		filtersToInstall, force, config.DataPath, dotRegolithPath, refreshFilters,
+		lockFile, frozen)
 	if err != nil {
 		return burrito.WrapError(err, "Could not install filters.")
 	}
+	if !frozen {
+		// Remove the filters that are no longer used by the project
+		for name := range lockFile.Filters {
+			if _, ok := filtersToInstall[name].(*RemoteFilterDefinition); !ok {
+				delete(lockFile.Filters, name)
+			}
+		}
+		err = lockFile.Dump(LockFilePath)
+		if err != nil {
+			return burrito.WrapError(err, dumpLockFileError)
+		}
+	}
 	// Update the config
 	if update {
 		err = addFiltersToConfig(configMap, remoteFilters, nil)
@@ -215,6 +252,165 @@ func InstallAll(force, update, debug, refreshFilters bool, env string) error {
 	return sessionLockErr // Return the error from the defer function
 }
 
+// Outdated handles the "regolith outdated" command. It prints the installed,
+// configured and latest versions of the remote filters from the
+// filterDefinitions list in the config.json file.
+func Outdated(debug bool, env string) error {
+	InitLogging(debug)
+	defer ShutdownLogging()
+	if err := loadEnvFileFromArg(env); err != nil {
+		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
+	}
+	if !hasGit() {
+		Logger.Warn(gitNotInstalledWarning)
+	}
+	configMap, err1 := LoadConfigAsMap()
+	config, err2 := configFromFileObject(configMap, ConfigFilePath)
+	if err := firstErr(err1, err2); err != nil {
+		return burrito.WrapError(err, "Failed to load config.json.")
+	}
+	dotRegolithPath, err := GetDotRegolith(".")
+	if err != nil {
+		return burrito.WrapError(
+			err, "Unable to get the path to regolith cache folder.")
+	}
+	names := remoteFilterNames(config.FilterDefinitions)
+	if len(names) == 0 {
+		Logger.Info("The project doesn't use any remote filters.")
+		return nil
+	}
+	Logger.Info("Checking the versions of the remote filters...")
+	versions := make([]FilterVersions, len(names))
+	for i, name := range names {
+		filter := config.FilterDefinitions[name].(*RemoteFilterDefinition)
+		versions[i] = getFilterVersions(name, filter, dotRegolithPath)
+	}
+	printFilterVersions(versions)
+	return nil
+}
+
+// Update handles the "regolith update" command. It updates the remote
+// filters to their newest versions, changes their versions in the
+// filterDefinitions list in the config.json file and reinstalls them.
+//
+// The "filters" parameter is a list of the names of the filters to update.
+// If it's empty, all of the remote filters are updated.
+//
+// The "patchOnly" and "minorOnly" parameters limit the updates to the
+// versions with the same major and minor version, or with the same major
+// version respectively.
+//
+// The "debug" parameter is a boolean that determines if the debug messages
+// should be printed.
+func Update(filters []string, patchOnly, minorOnly, debug bool, env string) error {
+	InitLogging(debug)
+	defer ShutdownLogging()
+	if err := loadEnvFileFromArg(env); err != nil {
+		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
+	}
+	if patchOnly && minorOnly {
+		return burrito.WrappedError(
+			"The \"--patch\" and \"--minor\" flags can't be used together.")
+	}
+	level := UpgradeMajor
+	if patchOnly {
+		level = UpgradePatch
+	} else if minorOnly {
+		level = UpgradeMinor
+	}
+	if !hasGit() {
+		Logger.Warn(gitNotInstalledWarning)
+	}
+	configMap, err1 := LoadConfigAsMap()
+	config, err2 := configFromFileObject(configMap, ConfigFilePath)
+	if err := firstErr(err1, err2); err != nil {
+		return burrito.WrapError(err, "Failed to load config.json.")
+	}
+	// Get dotRegolithPath
+	dotRegolithPath, err := GetDotRegolith(".")
+	if err != nil {
+		return burrito.WrapError(
+			err, "Unable to get the path to regolith cache folder.")
+	}
+	// Lock the session
+	unlockSession, sessionLockErr := acquireSessionLock(dotRegolithPath)
+	if sessionLockErr != nil {
+		return burrito.WrapError(sessionLockErr, acquireSessionLockError)
+	}
+	defer func() { sessionLockErr = unlockSession() }()
+	// Select the filters to update
+	if len(filters) == 0 {
+		filters = remoteFilterNames(config.FilterDefinitions)
+	}
+	filtersToInstall := make(map[string]FilterInstaller, 0)
+	newVersions := make(map[string]string, 0) // Used for updating the config
+	for _, name := range filters {
+		filterDefinition, ok := config.FilterDefinitions[name]
+		if !ok {
+			return burrito.WrappedErrorf(filterDefinitionMissingError, name)
+		}
+		remoteFilter, ok := filterDefinition.(*RemoteFilterDefinition)
+		if !ok {
+			return burrito.WrappedErrorf(filterNotRemoteError, name)
+		}
+		if !semver.IsValid("v" + remoteFilter.Version) {
+			Logger.Infof(
+				"Filter %q uses version %q, which isn't a semver version. "+
+					"Skipping.", name, remoteFilter.Version)
+			continue
+		}
+		tags, err := ListRemoteFilterTags(remoteFilter.Url, remoteFilter.Id)
+		if err != nil {
+			return burrito.WrapErrorf(
+				err, getRemoteFilterDownloadRefError,
+				remoteFilter.Url, remoteFilter.Id, remoteFilter.Version)
+		}
+		version := FindFilterUpdate(tags, remoteFilter.Id, remoteFilter.Version, level)
+		if version == "" {
+			Logger.Infof(
+				"Filter %q is up to date. Version: %q.", name, remoteFilter.Version)
+			continue
+		}
+		Logger.Infof(
+			"Updating filter %q: %q->%q.", name, remoteFilter.Version, version)
+		updatedFilter := *remoteFilter
+		updatedFilter.Version = version
+		filtersToInstall[name] = &updatedFilter
+		newVersions[name] = version
+	}
+	if len(filtersToInstall) == 0 {
+		Logger.Info("All of the filters are up to date.")
+		return nil
+	}
+	// Update the config first, so that an error during the installation
+	// can be fixed with "regolith install-all"
+	err = updateFilterVersionsInConfig(newVersions)
+	if err != nil {
+		return burrito.WrapError(err, "Failed to update the config file.")
+	}
+	// Install the filters
+	lockFile, err := LoadLockFile(LockFilePath)
+	if err != nil {
+		return burrito.WrapError(err, loadLockFileError)
+	}
+	err = installFilters(
+		filtersToInstall, false, config.DataPath, dotRegolithPath, false,
+		lockFile, false)
+	if err != nil {
+		return burrito.WrapError(
+			err,
+			"Updated the versions of the filters in the config file, "+
+				"but failed to install them.\n"+
+				"Run \"regolith install-all\" to install them.")
+	}
+	err = lockFile.Dump(LockFilePath)
+	if err != nil {
+		return burrito.WrapError(err, dumpLockFileError)
+	}
+	Logger.Info("Successfully updated the filters.")
+	return sessionLockErr // Return the error from the defer function
+}
+
 // prepareRunContext prepares the context for the "regolith run" and
 // "regolith watch" commands.
 func prepareRunContext(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, symlinkExport bool, disableSizeTimeCheck bool) (*RunContext, error) {
@@ -230,7 +426,7 @@ func prepareRunContext(profileName string, extraFilterArgs []string, debug bool,
 	if err != nil {
 		return nil, burrito.WrapError(err, "Could not load \"config.json\".")
 	}
-	config, err := ConfigFromObject(configJson)
+	config, err := configFromFileObject(configJson, ConfigFilePath)
 	if err != nil {
 		return nil, burrito.WrapError(err, "Could not load \"config.json\".")
 	}
@@ -273,12 +469,24 @@ func prepareRunContext(profileName string, extraFilterArgs []string, debug bool,
 // Run handles the "regolith run" command. It runs selected profile and exports
 // created resource pack and behavior pack to the target destination.
 func Run(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, symlinkExport bool, disableSizeTimeCheck bool) error {
+	return RunWithReport(profileName, extraFilterArgs, debug, env, unsafeMode, symlinkExport, disableSizeTimeCheck, false, "", "")
+}
+
+// RunWithReport handles the "regolith run" command with the --report and
+// --trace flags. It works like Run, but it also writes the report of the run
+// to reportPath and the Chrome trace of the run to tracePath. The empty paths
+// are skipped. The files are written even if the run fails.
+//
+// If backupModified is true, the exported files modified outside of Regolith
+// are backed up instead of stopping the export.
+func RunWithReport(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, symlinkExport bool, disableSizeTimeCheck bool, backupModified bool, reportPath string, tracePath string) error {
 	// Get the context
 	context, err := prepareRunContext(profileName, extraFilterArgs, debug, env, unsafeMode, symlinkExport, disableSizeTimeCheck)
 	defer ShutdownLogging()
 	if err != nil {
 		return burrito.PassError(err)
 	}
+	context.BackupModifiedFiles = backupModified
 	// Lock the session
 	unlockSession, sessionLockErr := acquireSessionLock(context.DotRegolithPath)
 	if sessionLockErr != nil {
@@ -286,7 +494,23 @@ func Run(profileName string, extraFilterArgs []string, debug bool, env string, u
 	}
 	defer func() { sessionLockErr = unlockSession() }()
 	// Run the profile
+	if reportPath != "" || tracePath != "" {
+		context.report = newRunReport(reportPath != "")
+	}
 	err = RunProfile(*context)
+	context.report.finish(err)
+	if reportPath != "" {
+		if err := context.report.writeReport(reportPath, context.Profile); err != nil {
+			return burrito.WrapErrorf(err, writeRunReportError, reportPath)
+		}
+		Logger.Infof("Saved the report of the run to %q.", reportPath)
+	}
+	if tracePath != "" {
+		if err := context.report.writeTrace(tracePath, context.Profile); err != nil {
+			return burrito.WrapErrorf(err, writeRunReportError, tracePath)
+		}
+		Logger.Infof("Saved the trace of the run to %q.", tracePath)
+	}
 	if err != nil {
 		return burrito.WrapErrorf(err, "Failed to run profile %q", profileName)
 	}
@@ -294,22 +518,104 @@ func Run(profileName string, extraFilterArgs []string, debug bool, env string, u
 	return sessionLockErr // Return the error from the defer function
 }
 
+// DryRun handles the "regolith run --dry-run" command. It runs selected
+// profile, but instead of exporting the files it prints the summary of the
+// changes that the export would make in the export targets and in the data
+// folder. If showDiff is true, the summary includes the unified diffs of the
+// changed text files.
+func DryRun(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, disableSizeTimeCheck bool, showDiff bool) error {
+	// Get the context. The symlink export is disabled because it would let
+	// the filters modify the export targets directly.
+	context, err := prepareRunContext(profileName, extraFilterArgs, debug, env, unsafeMode, false, disableSizeTimeCheck)
+	defer ShutdownLogging()
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	context.DryRun = true
+	context.DryRunDiff = showDiff
+	// Lock the session
+	unlockSession, sessionLockErr := acquireSessionLock(context.DotRegolithPath)
+	if sessionLockErr != nil {
+		return burrito.WrapError(sessionLockErr, acquireSessionLockError)
+	}
+	defer func() { sessionLockErr = unlockSession() }()
+	// Run the profile
+	err = RunProfile(*context)
+	if err != nil {
+		return burrito.WrapErrorf(err, "Failed to run profile %q", context.Profile)
+	}
+	Logger.Infof("Successfully ran the %q profile in the dry run mode.", context.Profile)
+	return sessionLockErr // Return the error from the defer function
+}
+
+// Package handles the "regolith package" command. It runs selected profile
+// and instead of using the export targets of the profile, it packs the
+// created resource pack and behavior pack into archives. The format can be
+// either "mcaddon" or "mcpack". The path is a template of the archive path,
+// if it's empty, the default path of the format is used.
+func Package(profileName, format, path string, debug bool, env string) error {
+	if !isArchiveExportTarget(format) {
+		InitLogging(debug)
+		defer ShutdownLogging()
+		return burrito.WrappedErrorf(
+			"Invalid package format %q. Valid formats are: mcaddon, mcpack.",
+			format)
+	}
+	// Get the context
+	context, err := prepareRunContext(profileName, []string{}, debug, env, false, false, false)
+	defer ShutdownLogging()
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	// Replace the export targets of the profile with the archive target
+	profile := context.Config.Profiles[context.Profile]
+	profile.ExportTarget = ExportTargets{{Target: format, Path: path}}
+	context.Config.Profiles[context.Profile] = profile
+	// Lock the session
+	unlockSession, sessionLockErr := acquireSessionLock(context.DotRegolithPath)
+	if sessionLockErr != nil {
+		return burrito.WrapError(sessionLockErr, acquireSessionLockError)
+	}
+	defer func() { sessionLockErr = unlockSession() }()
+	// Run the profile
+	err = RunProfile(*context)
+	if err != nil {
+		return burrito.WrapErrorf(err, "Failed to package profile %q", context.Profile)
+	}
+	Logger.Infof("Successfully packaged the %q profile.", context.Profile)
+	return sessionLockErr // Return the error from the defer function
+}
+
 // Watch handles the "regolith watch" command. It watches the project
 // directories, and it runs selected profile and exports created resource pack
 // and behavior pack to the target destination when the project changes.
-func Watch(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, symlinkExport bool, disableSizeTimeCheck bool) error {
+//
+// If backupModified is true, the exported files modified outside of Regolith
+// are backed up instead of stopping the export. If serveAddress isn't empty,
+// the build events are served on that address (see startBuildEventServer).
+func Watch(profileName string, extraFilterArgs []string, debug bool, env string, unsafeMode bool, symlinkExport bool, disableSizeTimeCheck bool, backupModified bool, serveAddress string) error {
 	// Get the context
 	context, err := prepareRunContext(profileName, extraFilterArgs, debug, env, unsafeMode, symlinkExport, disableSizeTimeCheck)
 	defer ShutdownLogging()
 	if err != nil {
 		return burrito.PassError(err)
 	}
+	context.BackupModifiedFiles = backupModified
 	// Lock the session
 	unlockSession, sessionLockErr := acquireSessionLock(context.DotRegolithPath)
 	if sessionLockErr != nil {
 		return burrito.WrapError(sessionLockErr, acquireSessionLockError)
 	}
 	defer func() { sessionLockErr = unlockSession() }()
+	// Start the build event server
+	if serveAddress != "" {
+		server, err := startBuildEventServer(serveAddress)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		defer server.Close()
+		context.buildEvents = server
+	}
 	// Setup the channel for stopping the watching
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -325,8 +631,15 @@ func Watch(profileName string, extraFilterArgs []string, debug bool, env string,
 			Logger.Errorf(
 				"Failed to run profile %q: %s",
 				profileName, burrito.PassError(err).Error())
+			context.buildEvents.emit(BuildEvent{
+				Type:    buildFailedEvent,
+				Profile: context.Profile,
+				Error:   strings.Join(burrito.GetAllMessages(err), "\n"),
+			})
 		} else {
 			Logger.Infof("Successfully ran the %q profile.", profileName)
+			context.buildEvents.emit(BuildEvent{
+				Type: buildFinishedEvent, Profile: context.Profile})
 		}
 		context.Initial = false
 		Logger.Info("Press Ctrl+C to stop watching.")
@@ -359,7 +672,7 @@ func ApplyFilter(filterName string, filterArgs []string, debug bool, env string)
 	if err != nil {
 		return burrito.WrapError(err, "Could not load \"config.json\".")
 	}
-	config, err := ConfigFromObject(configJson)
+	config, err := configFromFileObject(configJson, ConfigFilePath)
 	if err != nil {
 		return burrito.WrapError(err, "Could not load \"config.json\".")
 	}
@@ -482,7 +795,7 @@ func Init(debug, force bool, env string) error {
 			ResourceFolder: "./packs/RP",
 		},
 		RegolithProject: RegolithProject{
-			FormatVersion:     "1.8.0",
+			FormatVersion:     "1.9.0",
 			DataPath:          "./packs/data",
 			FilterDefinitions: map[string]FilterInstaller{},
 			Profiles: map[string]Profile{
@@ -638,6 +951,44 @@ func UpdateResolvers(debug bool, env string) error {
 	return err
 }
 
+// Doctor handles the "regolith doctor" command. It checks the environment
+// used by Regolith and prints the results of the checks as a table or as
+// JSON if the "jsonOutput" parameter is true. It returns an error if any of
+// the checks fail.
+//
+// The "debug" parameter is a boolean that determines if the debug messages
+// should be printed.
+func Doctor(jsonOutput, debug bool, env string) error {
+	InitLogging(debug)
+	defer ShutdownLogging()
+	if err := loadEnvFileFromArg(env); err != nil {
+		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
+	}
+	if jsonOutput && !debug {
+		// Hide the info messages to keep the output valid JSON
+		LoggerLevel.SetLevel(zap.WarnLevel)
+	}
+	checks := RunDoctorChecks()
+	if jsonOutput {
+		if err := printDoctorChecksJson(checks); err != nil {
+			return burrito.PassError(err)
+		}
+	} else {
+		printDoctorChecks(checks)
+	}
+	var failed []string
+	for _, check := range checks {
+		if check.Status == DoctorFail {
+			failed = append(failed, check.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return burrito.WrappedErrorf(
+			doctorChecksFailedError, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // manageUserConfigPrint is a helper function for ManageConfig used to print
 // the specified value from the user configuration.
 func manageUserConfigPrint(full bool, setting string) error {
@@ -707,7 +1058,7 @@ func manageUserConfigEdit(setting string, index int, key, value string) error {
 	userConfig.fillWithFileData(configPath)
 
 	// Only list properties can use 'index'
-	if setting != "resolvers" && index != -1 {
+	if setting != "resolvers" && setting != "watch_ignore" && index != -1 {
 		return burrito.WrappedError(userSettingIncorrectIndexUseError)
 	}
 	// Only map properties can use 'key'
@@ -791,6 +1142,43 @@ func manageUserConfigEdit(setting string, index int, key, value string) error {
 		userConfig.NpmRunner = &value
 	case "python_runner":
 		userConfig.PythonRunner = &value
+	case "filter_timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return burrito.WrappedErrorf(invalidTimeoutError, value)
+		}
+		userConfig.FilterTimeout = &value
+	case "watch_ignore":
+		if !isValidGlob(value) {
+			return burrito.WrappedErrorf(
+				"Invalid value for glob pattern property.\n"+
+					"\tValue: %s", value)
+		}
+		if index == -1 {
+			userConfig.WatchIgnore = append(userConfig.WatchIgnore, value)
+		} else {
+			if len(userConfig.WatchIgnore) <= index {
+				return burrito.WrappedError("Index out of range.")
+			}
+			userConfig.WatchIgnore[index] = value
+		}
+	case "watch_debounce":
+		if _, err := parseWatchDuration(value, "watch_debounce", true); err != nil {
+			return burrito.PassError(err)
+		}
+		userConfig.WatchDebounce = &value
+	case "watch_polling":
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return burrito.WrapErrorf(err, "Invalid value for boolean property.\n"+
+				"\tValue: %s", value)
+		}
+		userConfig.WatchPolling = &boolValue
+	case "watch_polling_interval":
+		if _, err := parseWatchDuration(value, "watch_polling_interval", false); err != nil {
+			return burrito.PassError(err)
+		}
+		userConfig.WatchPollingInterval = &value
 	default:
 		return burrito.WrappedErrorf(invalidUserConfigPropertyError, setting)
 	}
@@ -798,6 +1186,8 @@ func manageUserConfigEdit(setting string, index int, key, value string) error {
 	if err != nil {
 		return burrito.WrapErrorf(err, userConfigDumpError, configPath)
 	}
+	// Reload the user configuration the next time it's used
+	cachedCombinedUserConfig, cachedGlobalUserConfig = nil, nil
 	return nil
 }
 
@@ -813,7 +1203,7 @@ func manageUserConfigDelete(setting string, index int, key string) error {
 	userConfig.fillWithFileData(configPath)
 
 	// Only list properties can use 'index'
-	if setting != "resolvers" && index != -1 {
+	if setting != "resolvers" && setting != "watch_ignore" && index != -1 {
 		return burrito.WrappedError(userSettingIncorrectIndexUseError)
 	}
 	// Only map properties can use 'key'
@@ -866,6 +1256,25 @@ func manageUserConfigDelete(setting string, index int, key string) error {
 		userConfig.NpmRunner = nil
 	case "python_runner":
 		userConfig.PythonRunner = nil
+	case "filter_timeout":
+		userConfig.FilterTimeout = nil
+	case "watch_ignore":
+		if index == -1 {
+			userConfig.WatchIgnore = nil
+		} else {
+			if len(userConfig.WatchIgnore) <= index {
+				return burrito.WrappedError("Index out of range.")
+			}
+			userConfig.WatchIgnore = append(
+				userConfig.WatchIgnore[:index],
+				userConfig.WatchIgnore[index+1:]...)
+		}
+	case "watch_debounce":
+		userConfig.WatchDebounce = nil
+	case "watch_polling":
+		userConfig.WatchPolling = nil
+	case "watch_polling_interval":
+		userConfig.WatchPollingInterval = nil
 	default:
 		return burrito.WrappedErrorf(invalidUserConfigPropertyError, setting)
 	}
@@ -873,6 +1282,8 @@ func manageUserConfigDelete(setting string, index int, key string) error {
 	if err != nil {
 		return burrito.WrapErrorf(err, userConfigDumpError, configPath)
 	}
+	// Reload the user configuration the next time it's used
+	cachedCombinedUserConfig, cachedGlobalUserConfig = nil, nil
 	return nil
 }
 
@@ -0,0 +1,463 @@
+package regolith
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// The "manifest" property of a profile rewrites the manifest.json files of
+// the packs after running the filters and before exporting them. It can set
+// the version of the packs, link the packs with dependencies and give them
+// new UUIDs. The source files of the project are never changed.
+
+// ManifestOptions are the options of the "manifest" property of a profile.
+// When editing, adjust ManifestOptionsFromObject function as well.
+type ManifestOptions struct {
+	// Version is the version of the packs in the "major.minor.patch" format.
+	// It can use "${expression}" templates (e.g. "${variables.version}").
+	Version string `json:"version,omitempty"`
+
+	// GitTagVersion sets the version of the packs to the latest git tag of
+	// the project. The "v" prefix of the tag is ignored.
+	GitTagVersion bool `json:"gitTagVersion,omitempty"`
+
+	// LinkPacks makes the RP and the BP depend on each other.
+	LinkPacks bool `json:"linkPacks,omitempty"`
+
+	// NewUuids replaces the UUIDs of the packs and of their modules with new
+	// random UUIDs. The UUIDs are generated once and saved in the cache of
+	// the project, so that every run of the profile uses the same UUIDs.
+	NewUuids bool `json:"newUuids,omitempty"`
+}
+
+// ManifestOptionsFromObject creates a ManifestOptions object from the
+// "manifest" property of a profile.
+func ManifestOptionsFromObject(obj map[string]any) (ManifestOptions, error) {
+	result := ManifestOptions{}
+	// Version - can be empty
+	if version, ok := obj["version"]; ok {
+		result.Version, ok = version.(string)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPropertyTypeError, "version", "string")
+		}
+	}
+	// GitTagVersion, LinkPacks, NewUuids - can be empty
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"gitTagVersion", &result.GitTagVersion},
+		{"linkPacks", &result.LinkPacks},
+		{"newUuids", &result.NewUuids},
+	}
+	for _, flag := range flags {
+		value, ok := obj[flag.name]
+		if !ok {
+			continue
+		}
+		*flag.value, ok = value.(bool)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPropertyTypeError, flag.name, "boolean")
+		}
+	}
+	if result.Version != "" && result.GitTagVersion {
+		return result, burrito.WrappedError(manifestVersionConflictError)
+	}
+	return result, nil
+}
+
+// manifestVersionPattern matches the versions accepted by the manifest
+// options. The prerelease and the build metadata are only kept in the
+// manifests that use the string versions.
+var manifestVersionPattern = regexp.MustCompile(
+	`^(\d+)\.(\d+)\.(\d+)([-+].*)?$`)
+
+// UpdateManifests rewrites the manifest.json files of the RP and the BP in
+// the tmp directory based on the manifest options of the profile.
+func UpdateManifests(context RunContext, options ManifestOptions) error {
+	version, err := resolveManifestVersion(context, options)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	tmpPath, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	var manifests []*editableManifest
+	for _, pack := range []string{"BP", "RP"} {
+		manifest, err := readEditableManifest(
+			filepath.Join(tmpPath, pack, "manifest.json"))
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		if manifest == nil {
+			Logger.Debugf("Pack %q has no manifest. Skipping its update.", pack)
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	if options.NewUuids {
+		// The map of the old UUIDs to the new ones is shared by the packs,
+		// so that the dependencies can be updated.
+		uuidsPath := getManifestUuidsPath(context.DotRegolithPath, context.Profile)
+		newUuids, err := loadManifestUuids(uuidsPath)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+		generated := false
+		for _, manifest := range manifests {
+			manifestGenerated, err := manifest.replaceUuids(newUuids)
+			if err != nil {
+				return burrito.PassError(err)
+			}
+			generated = generated || manifestGenerated
+		}
+		for _, manifest := range manifests {
+			manifest.replaceDependencyUuids(newUuids)
+		}
+		if generated {
+			if err := saveManifestUuids(uuidsPath, newUuids); err != nil {
+				return burrito.PassError(err)
+			}
+		}
+	}
+	if version != "" {
+		for _, manifest := range manifests {
+			if err := manifest.setVersion(version); err != nil {
+				return burrito.PassError(err)
+			}
+		}
+	}
+	// Keep the dependencies between the packs up to date
+	for _, manifest := range manifests {
+		for _, other := range manifests {
+			if other != manifest {
+				manifest.updateDependency(other, options.LinkPacks)
+			}
+		}
+	}
+	for _, manifest := range manifests {
+		if err := manifest.write(); err != nil {
+			return burrito.PassError(err)
+		}
+	}
+	return nil
+}
+
+// getManifestUuidsPath returns the path to the file with the UUIDs generated
+// for the packs of the profile by the "newUuids" option.
+func getManifestUuidsPath(dotRegolithPath, profile string) string {
+	h := sha256.New()
+	h.Write([]byte(profile))
+	return filepath.Join(
+		dotRegolithPath, "cache", "manifest_uuids",
+		hex.EncodeToString(h.Sum(nil))[:16]+".json")
+}
+
+// loadManifestUuids loads the map of the original UUIDs to the generated
+// ones. It returns an empty map if the file doesn't exist.
+func loadManifestUuids(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, path)
+	}
+	result := map[string]string{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, path)
+	}
+	return result, nil
+}
+
+// saveManifestUuids saves the map of the original UUIDs to the generated
+// ones.
+func saveManifestUuids(path string, uuids map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, filepath.Dir(path))
+	}
+	data, _ := json.MarshalIndent(uuids, "", "\t") // no error
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, path)
+	}
+	return nil
+}
+
+// resolveManifestVersion returns the version of the packs set by the
+// manifest options, or an empty string if the options don't change the
+// version.
+func resolveManifestVersion(context RunContext, options ManifestOptions) (string, error) {
+	var version string
+	if options.GitTagVersion {
+		commandArgs := []string{"describe", "--tags", "--abbrev=0"}
+		output, err := exec.Command("git", commandArgs...).Output()
+		if err != nil {
+			commandText := "git " + strings.Join(commandArgs, " ")
+			return "", burrito.WrapErrorf(err, execCommandError, commandText)
+		}
+		version = strings.TrimSpace(string(output))
+	} else if options.Version != "" {
+		var err error
+		version, err = InterpolateString(options.Version, context)
+		if err != nil {
+			return "", burrito.WrapErrorf(err, jsonPropertyParseError, "manifest->version")
+		}
+	} else {
+		return "", nil
+	}
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !manifestVersionPattern.MatchString(version) {
+		return "", burrito.WrappedErrorf(invalidManifestVersionError, version)
+	}
+	return version, nil
+}
+
+// editableManifest is a manifest.json file of a pack read as a generic JSON
+// object, so that rewriting it keeps all of its properties.
+type editableManifest struct {
+	path   string
+	data   map[string]any
+	header map[string]any
+
+	// offsets are the offsets of the values in the original file (see
+	// jsonValueOffsets), used to write the properties in their original
+	// order.
+	offsets map[string]int
+}
+
+// readEditableManifest reads the manifest.json file. It returns nil if the
+// file doesn't exist.
+func readEditableManifest(path string) (*editableManifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, path)
+	}
+	result := &editableManifest{path: path}
+	if err := unmarshalJSONC(data, &result.data, path); err != nil {
+		return nil, burrito.PassError(err)
+	}
+	result.offsets, err = jsonValueOffsets(data)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, path)
+	}
+	header, _ := result.data["header"].(map[string]any)
+	if uuid, _ := header["uuid"].(string); uuid == "" || header["version"] == nil {
+		return nil, burrito.WrappedErrorf(invalidPackManifestError, path)
+	}
+	result.header = header
+	return result, nil
+}
+
+// uuid returns the UUID of the pack.
+func (m *editableManifest) uuid() string {
+	uuid, _ := m.header["uuid"].(string)
+	return uuid
+}
+
+// objects returns the objects of the array property of the manifest (e.g.
+// "modules" or "dependencies"). The other values of the array are skipped.
+func (m *editableManifest) objects(property string) []map[string]any {
+	values, _ := m.data[property].([]any)
+	result := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		if obj, ok := value.(map[string]any); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// replaceUuids gives new UUIDs to the pack and to its modules. The UUIDs
+// from the newUuids map are reused and the UUIDs generated for the other
+// objects are added to it. It returns true if any UUID was generated.
+func (m *editableManifest) replaceUuids(newUuids map[string]string) (bool, error) {
+	generated := false
+	objects := append([]map[string]any{m.header}, m.objects("modules")...)
+	for _, obj := range objects {
+		oldUuid, ok := obj["uuid"].(string)
+		if !ok {
+			continue
+		}
+		newUuid, ok := newUuids[oldUuid]
+		if !ok {
+			var err error
+			newUuid, err = newRandomUuid()
+			if err != nil {
+				return false, burrito.WrapErrorf(err, updateManifestError, m.path)
+			}
+			newUuids[oldUuid] = newUuid
+			generated = true
+		}
+		obj["uuid"] = newUuid
+	}
+	return generated, nil
+}
+
+// replaceDependencyUuids updates the dependencies of the pack that use the
+// replaced UUIDs.
+func (m *editableManifest) replaceDependencyUuids(newUuids map[string]string) {
+	for _, dependency := range m.objects("dependencies") {
+		uuid, _ := dependency["uuid"].(string)
+		if newUuid, ok := newUuids[uuid]; ok {
+			dependency["uuid"] = newUuid
+		}
+	}
+}
+
+// setVersion sets the version of the pack and of its modules. The version is
+// written in the same form as the current version of the pack, which is
+// either a "major.minor.patch" string or an array of three numbers.
+func (m *editableManifest) setVersion(version string) error {
+	var value any = version
+	if _, ok := m.header["version"].(string); !ok {
+		match := manifestVersionPattern.FindStringSubmatch(version)
+		numbers := make([]any, 3)
+		for i := range numbers {
+			number, err := strconv.Atoi(match[i+1])
+			if err != nil {
+				return burrito.WrappedErrorf(invalidManifestVersionError, version)
+			}
+			numbers[i] = number
+		}
+		value = numbers
+	}
+	m.header["version"] = value
+	for _, module := range m.objects("modules") {
+		if _, ok := module["version"]; ok {
+			module["version"] = value
+		}
+	}
+	return nil
+}
+
+// updateDependency updates the version of the dependency on the other pack
+// of the project. If the pack doesn't depend on the other pack, the
+// dependency is added only if add is true.
+func (m *editableManifest) updateDependency(other *editableManifest, add bool) {
+	for _, dependency := range m.objects("dependencies") {
+		if uuid, _ := dependency["uuid"].(string); uuid == other.uuid() {
+			dependency["version"] = other.header["version"]
+			return
+		}
+	}
+	if !add {
+		return
+	}
+	dependencies, _ := m.data["dependencies"].([]any)
+	m.data["dependencies"] = append(dependencies, map[string]any{
+		"uuid":    other.uuid(),
+		"version": other.header["version"],
+	})
+}
+
+// write saves the manifest to its file. The properties are written in their
+// original order, followed by the added properties. The characters like "<"
+// and "&" in the strings aren't escaped.
+func (m *editableManifest) write() error {
+	compact := &bytes.Buffer{}
+	encoder := json.NewEncoder(compact)
+	encoder.SetEscapeHTML(false)
+	if err := m.encodeValue(encoder, compact, m.data, ""); err != nil {
+		return burrito.WrapErrorf(err, updateManifestError, m.path)
+	}
+	data := &bytes.Buffer{}
+	if err := json.Indent(data, compact.Bytes(), "", "\t"); err != nil {
+		return burrito.WrapErrorf(err, updateManifestError, m.path)
+	}
+	if err := os.WriteFile(m.path, data.Bytes(), 0644); err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, m.path)
+	}
+	return nil
+}
+
+// encodeValue writes the value with the given JSON path to the buffer of the
+// encoder. The keys of the objects are sorted by their offsets in the
+// original file, and the keys that weren't in the file are sorted by name.
+func (m *editableManifest) encodeValue(
+	encoder *json.Encoder, buffer *bytes.Buffer, value any, path string,
+) error {
+	prefix := path
+	if prefix != "" {
+		prefix += "->"
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		keys := slices.Collect(maps.Keys(value))
+		slices.SortFunc(keys, func(a, b string) int {
+			offsetA, okA := m.offsets[prefix+a]
+			offsetB, okB := m.offsets[prefix+b]
+			switch {
+			case okA && okB:
+				return offsetA - offsetB
+			case okA:
+				return -1
+			case okB:
+				return 1
+			}
+			return cmp.Compare(a, b)
+		})
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := m.encodeValue(encoder, buffer, key, ""); err != nil {
+				return err
+			}
+			buffer.WriteByte(':')
+			if err := m.encodeValue(encoder, buffer, value[key], prefix+key); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case []any:
+		buffer.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			err := m.encodeValue(encoder, buffer, item, prefix+strconv.Itoa(i))
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		// Remove the newline added by the encoder
+		buffer.Truncate(buffer.Len() - 1)
+	}
+	return nil
+}
+
+// newRandomUuid returns a new random (version 4) UUID.
+func newRandomUuid() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", burrito.PassError(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
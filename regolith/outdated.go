@@ -0,0 +1,168 @@
+package regolith
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/fatih/color"
+	"golang.org/x/mod/semver"
+)
+
+// Functions used for the "regolith outdated" and "regolith update" commands
+
+// Upgrade levels that limit the versions used by the "regolith update"
+// command.
+const (
+	// UpgradeMajor allows updating to any newer version.
+	UpgradeMajor = "major"
+	// UpgradeMinor allows only the versions with the same major version.
+	UpgradeMinor = "minor"
+	// UpgradePatch allows only the versions with the same major and minor
+	// version.
+	UpgradePatch = "patch"
+)
+
+// FilterVersions are the versions of a remote filter printed by the
+// "regolith outdated" command.
+type FilterVersions struct {
+	Name       string
+	Installed  string
+	Configured string
+	Latest     string
+}
+
+// FindFilterUpdate returns the newest version from the tags of the remote
+// filter (see ListRemoteFilterTags) that is newer than the current version
+// and allowed by the upgrade level. The prerelease versions are used only
+// if the current version is a prerelease. It returns an empty string if there
+// is no such version or if the current version isn't a semver version.
+func FindFilterUpdate(tags []string, name, current, level string) string {
+	vCurrent := "v" + current
+	if !semver.IsValid(vCurrent) {
+		return ""
+	}
+	result := ""
+	for _, tag := range tags {
+		version := trimFilterPrefix(tag, name)
+		vVersion := "v" + version
+		if !semver.IsValid(vVersion) || semver.Compare(vVersion, vCurrent) <= 0 {
+			continue
+		}
+		if semver.Prerelease(vVersion) != "" && semver.Prerelease(vCurrent) == "" {
+			continue
+		}
+		switch level {
+		case UpgradePatch:
+			if semver.MajorMinor(vVersion) != semver.MajorMinor(vCurrent) {
+				continue
+			}
+		case UpgradeMinor:
+			if semver.Major(vVersion) != semver.Major(vCurrent) {
+				continue
+			}
+		}
+		if result == "" || semver.Compare(vVersion, "v"+result) > 0 {
+			result = version
+		}
+	}
+	return result
+}
+
+// remoteFilterNames returns the sorted names of the remote filters from the
+// filter definitions.
+func remoteFilterNames(filterDefinitions map[string]FilterInstaller) []string {
+	var result []string
+	for name, filterDefinition := range filterDefinitions {
+		if _, ok := filterDefinition.(*RemoteFilterDefinition); ok {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// getFilterVersions returns the installed, configured and latest versions
+// of the remote filter. The versions that can't be checked are reported as
+// "-".
+func getFilterVersions(
+	name string, filter *RemoteFilterDefinition, dotRegolithPath string,
+) FilterVersions {
+	result := FilterVersions{
+		Name: name, Installed: "-", Configured: filter.Version, Latest: "-"}
+	if installed, err := filter.InstalledVersion(dotRegolithPath); err == nil {
+		result.Installed = trimFilterPrefix(installed, filter.Id)
+	} else {
+		Logger.Debugf("Filter %q isn't installed:\n%s", name, err.Error())
+	}
+	if latest, err := GetLatestRemoteFilterTag(filter.Url, filter.Id); err == nil {
+		result.Latest = trimFilterPrefix(latest, filter.Id)
+	} else {
+		Logger.Warnf(
+			"Unable to get the latest version of the filter.\nFilter: %s\n%s",
+			name, errorSummary(err))
+	}
+	return result
+}
+
+// printFilterVersions prints the versions of the remote filters as a table.
+func printFilterVersions(versions []FilterVersions) {
+	w := tabwriter.NewWriter(color.Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILTER\tINSTALLED\tCONFIGURED\tLATEST")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Name, v.Installed, v.Configured, v.Latest)
+	}
+	w.Flush()
+}
+
+// updateFilterVersionsInConfig sets the "version" properties of the filter
+// definitions in the config file to the new versions. Only the values of
+// the properties are replaced, so the rest of the file, including the
+// comments and the order of the properties, doesn't change.
+func updateFilterVersionsInConfig(versions map[string]string) error {
+	data, err := os.ReadFile(ConfigFilePath)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileReadError, ConfigFilePath)
+	}
+	offsets, err := jsonValueOffsets(data)
+	if err != nil {
+		return burrito.WrapErrorf(err, jsonUnmarshalError, ConfigFilePath)
+	}
+	type replacement struct {
+		start, end int
+		value      []byte
+	}
+	var replacements []replacement
+	for name, version := range versions {
+		jsonPath := "regolith->filterDefinitions->" + name + "->version"
+		start, ok := offsets[jsonPath]
+		if !ok {
+			return burrito.WrappedErrorf(jsonPathMissingError, jsonPath)
+		}
+		// The offset points to the old value, which is a single token
+		decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+		if _, err := decoder.Token(); err != nil {
+			return burrito.WrapErrorf(err, jsonUnmarshalError, ConfigFilePath)
+		}
+		value, _ := json.Marshal(version)
+		replacements = append(replacements, replacement{
+			start, start + int(decoder.InputOffset()), value})
+	}
+	// Replace the values from the end of the file, so that the offsets of
+	// the other values don't change
+	slices.SortFunc(replacements, func(a, b replacement) int {
+		return b.start - a.start
+	})
+	for _, r := range replacements {
+		data = slices.Concat(data[:r.start], r.value, data[r.end:])
+	}
+	err = os.WriteFile(ConfigFilePath, data, 0644)
+	if err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, ConfigFilePath)
+	}
+	return nil
+}
@@ -3,10 +3,13 @@ package regolith
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +31,22 @@ func runShellCommands(commands []string) error {
 	return runShellCommandsUnix(commands)
 }
 
+// interpolateShellCommands returns the shell commands with the
+// "${expression}" templates replaced using InterpolateString. The "${" used
+// by the shell must be escaped as "$${".
+func interpolateShellCommands(commands []string, ctx RunContext) ([]string, error) {
+	result := make([]string, len(commands))
+	for i, command := range commands {
+		command, err := InterpolateString(command, ctx)
+		if err != nil {
+			return nil, burrito.WrapErrorf(
+				err, jsonPathParseError, strconv.Itoa(i))
+		}
+		result[i] = command
+	}
+	return result, nil
+}
+
 // runShellCommandsWindows executes commands in PowerShell and captures environment changes
 func runShellCommandsWindows(commands []string) error {
 	// Build a script that:
@@ -142,6 +161,9 @@ func SetupTmpFiles(context RunContext) error {
 				Logger.Debugf("Symlink export is enabled but the profile has multiple active export targets. Using regular export.")
 			}
 			useSymlinkExport = false
+		} else if isArchiveExportTarget(activeTargets[0].Target) {
+			Logger.Debugf("Symlink export is enabled but the export target creates archives. Using regular export.")
+			useSymlinkExport = false
 		} else {
 			primaryTarget := activeTargets[0]
 			bpExportPath, rpExportPath, err = GetExportPaths(primaryTarget, context)
@@ -195,12 +217,11 @@ func SetupTmpFiles(context RunContext) error {
 	if shouldCreateSymlinks {
 		if !context.UnsafeMode {
 			editedFiles := LoadEditedFiles(dotRegolithPath)
-			err := editedFiles.CheckDeletionSafety(rpExportPath, bpExportPath)
+			err := checkExportPathsSafety(
+				context, editedFiles, rpExportPath, bpExportPath,
+				&modifiedFilesBackup{dotRegolithPath: dotRegolithPath}, 1)
 			if err != nil {
-				return burrito.WrapErrorf(
-					err,
-					checkDeletionSafetyError,
-					rpExportPath, bpExportPath)
+				return burrito.PassError(err)
 			}
 		}
 		if err := os.MkdirAll(bpExportPath, 0755); err != nil {
@@ -337,24 +358,44 @@ func CheckProfileImpl(
 // times in case of interruptions (changes in the source files).
 func RunProfile(context RunContext) error {
 start:
+	context.buildEvents.emit(BuildEvent{
+		Type: buildStartedEvent, Profile: context.Profile})
 	// Execute preShell commands if present
 	profile, err := context.GetProfile()
 	if err != nil {
 		return burrito.WrapErrorf(err, runContextGetProfileError)
 	}
-	preShellCmds := profile.PreShell.GetCommandsForCurrentOS()
+	preShellCmds, err := interpolateShellCommands(
+		profile.PreShell.GetCommandsForCurrentOS(), context)
+	if err != nil {
+		return burrito.WrapErrorf(err, jsonPropertyParseError, "preShell")
+	}
 	if len(preShellCmds) > 0 {
 		Logger.Info("Running preShell commands...")
+		span := context.report.startSpan(context, "preShell", reportStepCategory)
 		err := runShellCommands(preShellCmds)
+		span.end(context, false, err)
 		if err != nil {
 			return burrito.WrapErrorf(err, "PreShell commands failed")
 		}
 	}
 
-	// Prepare tmp files
-	err = SetupTmpFiles(context)
-	if err != nil {
-		return burrito.WrapErrorf(err, setupTmpFilesError, context.DotRegolithPath)
+	// Prepare tmp files. In the watch mode, the tmp files can be restored
+	// from the snapshot of the first filter affected by the changes.
+	restored := false
+	if context.watchState != nil {
+		restored, err = context.watchState.restoreSnapshot(context, profile)
+		if err != nil {
+			return burrito.PassError(err)
+		}
+	}
+	if !restored {
+		span := context.report.startSpan(context, "setup", reportStepCategory)
+		err = SetupTmpFiles(context)
+		span.end(context, false, err)
+		if err != nil {
+			return burrito.WrapErrorf(err, setupTmpFilesError, context.DotRegolithPath)
+		}
 	}
 	if context.IsInterrupted() {
 		goto start
@@ -367,13 +408,32 @@ start:
 	if interrupted {
 		goto start
 	}
+	// Rewrite the manifests of the packs
+	if profile.Manifest != nil {
+		span := context.report.startSpan(context, "manifest", reportStepCategory)
+		err = UpdateManifests(context, *profile.Manifest)
+		span.end(context, false, err)
+		if err != nil {
+			return burrito.WrapError(err, "Failed to update the manifests of the packs.")
+		}
+	}
+	// Show the changes instead of exporting the files
+	if context.DryRun {
+		err = PrintExportPlan(context, context.DryRunDiff)
+		if err != nil {
+			return burrito.WrapError(err, "Failed to compare the files with the export targets.")
+		}
+		return nil
+	}
 	// Export files
 	Logger.Info("Moving files to target directory.")
 	start := time.Now()
 	if context.IsInWatchMode() {
 		context.fileWatchingStage <- "pause"
 	}
+	span := context.report.startSpan(context, "export", reportStepCategory)
 	err = ExportProject(context)
+	span.end(context, false, err)
 	if context.IsInWatchMode() {
 		// We need to restart the watcher before error handling. See:
 		// https://github.com/Bedrock-OSS/regolith/pull/297#issuecomment-2411981894
@@ -386,12 +446,23 @@ start:
 		goto start
 	}
 	Logger.Debug("Done in ", time.Since(start))
+	context.buildEvents.emit(BuildEvent{
+		Type:       buildExportedEvent,
+		Profile:    context.Profile,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
 
 	// Execute postShell commands if present
-	postShellCmds := profile.PostShell.GetCommandsForCurrentOS()
+	postShellCmds, err := interpolateShellCommands(
+		profile.PostShell.GetCommandsForCurrentOS(), context)
+	if err != nil {
+		return burrito.WrapErrorf(err, jsonPropertyParseError, "postShell")
+	}
 	if len(postShellCmds) > 0 {
 		Logger.Info("Running postShell commands...")
+		span := context.report.startSpan(context, "postShell", reportStepCategory)
 		err := runShellCommands(postShellCmds)
+		span.end(context, false, err)
 		if err != nil {
 			return burrito.WrapErrorf(err, "PostShell commands failed")
 		}
@@ -407,9 +478,26 @@ func RunProfileImpl(context RunContext) (bool, error) {
 	if err != nil {
 		return false, burrito.WrapErrorf(err, runContextGetProfileError)
 	}
+	// The snapshots for restarting the profile in the watch mode are only
+	// used by the top level profile.
+	state := context.watchState
+	first, snapshotLimit := 0, 0
+	if state != nil && context.Parent == nil {
+		first = state.firstFilter
+		snapshotLimit, err = watchSnapshotLimit(profile, context)
+		if err != nil {
+			return false, burrito.PassError(err)
+		}
+	} else {
+		state = nil
+	}
 	// Run the filters!
-	for filter := range profile.Filters {
-		filter := profile.Filters[filter]
+	for i := first; i < len(profile.Filters); i++ {
+		filter := profile.Filters[i]
+		err = state.saveSnapshot(context, i, snapshotLimit)
+		if err != nil {
+			return false, burrito.PassError(err)
+		}
 		// Disabled filters are skipped
 		disabled, err := filter.IsDisabled(context)
 		if err != nil {
@@ -424,25 +512,197 @@ func RunProfileImpl(context RunContext) (bool, error) {
 			Logger.Infof("Running filter %s", filter.GetId())
 		}
 
-		err = filter.AddExtraArguments(context.ExtraArguments)
+		restore, err := filter.InterpolateTemplates(context)
 		if err != nil {
 			return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
 		}
+		interrupted, err := runProfileFilter(filter, i, context)
+		restore()
+		if err != nil || interrupted {
+			return interrupted, err
+		}
+	}
+	err = state.saveSnapshot(context, len(profile.Filters), snapshotLimit)
+	if err != nil {
+		return false, burrito.PassError(err)
+	}
+	return false, nil
+}
 
-		// Run the filter in watch mode
-		start := time.Now()
-		interrupted, err := filter.Run(context)
-		Logger.Debugf("Executed in %s", time.Since(start))
+// runProfileFilter runs the filter with the given index of the profile from
+// the context. It returns true if the execution was interrupted.
+func runProfileFilter(filter FilterRunner, i int, context RunContext) (bool, error) {
+	err := filter.AddExtraArguments(context.ExtraArguments)
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
+	}
+	// Try to restore the output of the filter from the cache
+	var cacheKey, cachePath string
+	if filter.IsCacheEnabled() {
+		cachePath = getFilterCachePath(
+			context.DotRegolithPath, context.Profile, i, filter.GetId())
+		cacheKey, err = getFilterCacheKey(filter, context)
+		if err != nil {
+			return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
+		}
+		restored, err := restoreFilterCache(cachePath, cacheKey, context)
 		if err != nil {
 			return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
 		}
-		if interrupted {
-			return true, nil
+		if restored {
+			Logger.Infof("Inputs of filter %s didn't change, using cached output.", filter.GetId())
+			context.report.startSpan(context, filter.GetId(), reportFilterCategory).
+				endWithStatus("cached", nil)
+			context.buildEvents.emit(BuildEvent{
+				Type:    buildFilterFinishedEvent,
+				Profile: context.Profile,
+				Filter:  filter.GetId(),
+				Cached:  true,
+			})
+			return context.IsInterrupted(), nil
+		}
+	}
+
+	// Run the filter in watch mode
+	context.buildEvents.emit(BuildEvent{
+		Type: buildFilterStartedEvent, Profile: context.Profile, Filter: filter.GetId()})
+	start := time.Now()
+	interrupted, err := runFilter(filter, context)
+	Logger.Debugf("Executed in %s", time.Since(start))
+	if err != nil {
+		return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
+	}
+	if interrupted {
+		return true, nil
+	}
+	context.buildEvents.emit(BuildEvent{
+		Type:       buildFilterFinishedEvent,
+		Profile:    context.Profile,
+		Filter:     filter.GetId(),
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	if filter.IsCacheEnabled() {
+		err = saveFilterCache(cachePath, cacheKey, context)
+		if err != nil {
+			return false, burrito.WrapErrorf(err, filterRunnerRunError, filter.GetId())
 		}
 	}
 	return false, nil
 }
 
+// getFilterTimeout returns the timeout of the filter. The timeout set in the
+// profile has the highest priority, then the timeout of the filter
+// definition and then the "filter_timeout" from the user configuration.
+// Zero means that the filter has no timeout.
+func getFilterTimeout(filter FilterRunner, context RunContext) (time.Duration, error) {
+	if timeout := filter.GetTimeout(); timeout > 0 {
+		return timeout, nil
+	}
+	if definition, ok := context.Config.FilterDefinitions[filter.GetId()]; ok {
+		if timeout := definition.GetTimeout(); timeout > 0 {
+			return timeout, nil
+		}
+	}
+	userConfig, err := getCombinedUserConfig()
+	if err != nil {
+		return 0, burrito.WrapError(err, getUserConfigError)
+	}
+	if userConfig.FilterTimeout == nil || *userConfig.FilterTimeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(*userConfig.FilterTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, burrito.WrappedErrorf(
+			invalidTimeoutError, *userConfig.FilterTimeout)
+	}
+	return timeout, nil
+}
+
+// runFilter runs the filter using runFilterWithTimeout and records it in the
+// report of the run.
+func runFilter(filter FilterRunner, context RunContext) (bool, error) {
+	span := context.report.startSpan(
+		context, reportFilterName(filter), reportFilterCategory)
+	context.reportSpan = span
+	interrupted, err := runFilterWithTimeout(filter, context)
+	span.end(context, interrupted, err)
+	return interrupted, err
+}
+
+// reportFilterName returns the name of the filter used in the report of the
+// run. The nested profiles and the async filters don't have IDs.
+func reportFilterName(filter FilterRunner) string {
+	if id := filter.GetId(); id != "" {
+		return id
+	}
+	switch filter := filter.(type) {
+	case *ProfileFilter:
+		return "profile " + filter.Profile
+	case *AsyncFilter:
+		return "asyncFilters"
+	}
+	return ""
+}
+
+// runFilterWithTimeout runs the filter and cancels it when it exceeds its
+// timeout, when its parent filter is cancelled or, in the watch mode, when
+// the source files change. It returns true if the filter was interrupted by
+// the changes in the source files.
+func runFilterWithTimeout(filter FilterRunner, context RunContext) (bool, error) {
+	timeout, err := getFilterTimeout(filter, context)
+	if err != nil {
+		return false, burrito.PassError(err)
+	}
+	// Only the top level filters listen to the changes of the source files.
+	// The nested filters are cancelled by their parents.
+	var interruption chan string
+	if context.cancel == nil {
+		interruption = context.interruption
+	}
+	if timeout == 0 && interruption == nil {
+		return filter.Run(context)
+	}
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+	parentCancel := context.cancel
+	cancel := make(chan struct{})
+	finished := make(chan struct{})
+	stopped := make(chan struct{})
+	var timedOut, interrupted bool
+	go func() {
+		defer close(stopped)
+		select {
+		case <-timer:
+			timedOut = true
+		case <-interruption:
+			interrupted = true
+		case <-parentCancel:
+		case <-finished:
+			return
+		}
+		close(cancel)
+	}()
+	context.cancel = cancel
+	filterInterrupted, err := filter.Run(context)
+	close(finished)
+	<-stopped
+	if interrupted {
+		Logger.Warn("Source files changed, the filter was stopped.")
+		return true, nil
+	}
+	// The timer can fire after the filter finished, so the timeout is
+	// reported only if it stopped the filter. Other cancellations (e.g. of
+	// the parent filter) are reported by the filter itself.
+	if timedOut && err != nil {
+		return false, burrito.WrappedErrorf(filterTimeoutError, timeout)
+	}
+	return filterInterrupted, err
+}
+
 // subfilterCollection returns a collection of filters from a
 // "filter.json" file of a remote filter.
 func (f *RemoteFilter) subfilterCollection(dotRegolithPath string) (*FilterCollection, error) {
@@ -565,6 +825,15 @@ type Profile struct {
 	ExportTarget ExportTargets `json:"export,omitzero"`
 	PreShell     ShellCommands `json:"preShell,omitzero"`
 	PostShell    ShellCommands `json:"postShell,omitzero"`
+	// Variables override the variables of the project when the profile runs.
+	Variables map[string]any `json:"variables,omitempty"`
+	// Manifest rewrites the manifests of the packs before the export.
+	Manifest *ManifestOptions `json:"manifest,omitempty"`
+	// TransactionalExport makes the export stage the files of all export
+	// targets in the .regolith directory and swap them in only after all of
+	// them are ready. If any part of the export fails, all of the export
+	// paths and the exported data are restored.
+	TransactionalExport bool `json:"transactionalExport,omitempty"`
 }
 
 func (p Profile) exportTargets() ExportTargets {
@@ -650,10 +919,19 @@ func shellCommandsFromObject(obj map[string]any, key string) (ShellCommands, err
 	return result, nil
 }
 
+// ProfileFromObject creates a "Profile" object from map[string]interface{}.
+// The profiles map contains the objects of all profiles of the project. It's
+// used for resolving the "extends" property of the profile and can be nil
+// if the profile doesn't extend other profiles.
 func ProfileFromObject(
 	obj map[string]any, filterDefinitions map[string]FilterInstaller,
+	profiles map[string]any,
 ) (Profile, error) {
 	result := Profile{}
+	obj, err := resolveProfileExtends(obj, profiles, nil)
+	if err != nil {
+		return result, burrito.PassError(err)
+	}
 	// Filters
 	if _, ok := obj["filters"]; !ok {
 		return result, burrito.WrappedErrorf(jsonPathMissingError, "filters")
@@ -698,6 +976,37 @@ func ProfileFromObject(
 		return result, burrito.PassError(err)
 	}
 	result.PostShell = postShell
+	// Variables
+	if variables, ok := obj["variables"]; ok {
+		variables, ok := variables.(map[string]any)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPathTypeError, "variables", "object")
+		}
+		result.Variables = variables
+	}
+	// Manifest
+	if manifest, ok := obj["manifest"]; ok {
+		manifest, ok := manifest.(map[string]any)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPathTypeError, "manifest", "object")
+		}
+		manifestOptions, err := ManifestOptionsFromObject(manifest)
+		if err != nil {
+			return result, burrito.WrapErrorf(err, jsonPathParseError, "manifest")
+		}
+		result.Manifest = &manifestOptions
+	}
+	// TransactionalExport
+	if transactionalExport, ok := obj["transactionalExport"]; ok {
+		transactionalExport, ok := transactionalExport.(bool)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPathTypeError, "transactionalExport", "boolean")
+		}
+		result.TransactionalExport = transactionalExport
+	}
 
 	return result, nil
 }
@@ -749,3 +1058,209 @@ func (p *Profile) ForeachFilter(
 	}
 	return nil
 }
+
+// profileInheritedProperties are the properties of a profile inherited from
+// the profile that it extends. The properties other than "filters" and
+// "variables" are replaced if the child profile defines them.
+var profileInheritedProperties = []string{
+	"filters", "export", "preShell", "postShell", "variables", "manifest",
+	"transactionalExport"}
+
+// resolveProfileExtends returns the object of the profile with the
+// properties inherited from the profile named in its "extends" property.
+// The profiles are resolved recursively. The chain contains the names of the
+// profiles that are already being resolved and is used for finding cycles.
+//
+// The "filters" property of the child profile can be an array, which
+// replaces the filters of the parent, or an object with the following
+// optional properties:
+//   - "prepend" - the filters added before the filters of the parent
+//   - "append" - the filters added after the filters of the parent
+//   - "replace" - a map of the IDs of the filters of the parent to the
+//     filters that replace them
+//   - "override" - a map of the IDs of the filters of the parent to the
+//     properties that are changed. The "settings" are merged with the
+//     settings of the parent filter.
+//
+// The ID of a filter is the value of its "filter" or "profile" property.
+//
+// The "variables" of the child profile are merged with the variables of the
+// parent profile.
+func resolveProfileExtends(
+	obj map[string]any, profiles map[string]any, chain []string,
+) (map[string]any, error) {
+	extendsObj, ok := obj["extends"]
+	if !ok {
+		return obj, nil
+	}
+	extends, ok := extendsObj.(string)
+	if !ok {
+		return nil, burrito.WrappedErrorf(jsonPropertyTypeError, "extends", "string")
+	}
+	if slices.Contains(chain, extends) {
+		cycle := append(slices.Clone(chain[slices.Index(chain, extends):]), extends)
+		return nil, burrito.WrappedErrorf(
+			profileExtendsCycleError, strings.Join(cycle, " -> "))
+	}
+	parentObj, ok := profiles[extends].(map[string]any)
+	if !ok {
+		return nil, burrito.WrappedErrorf(profileExtendsMissingError, extends)
+	}
+	parent, err := resolveProfileExtends(
+		parentObj, profiles, append(slices.Clone(chain), extends))
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, profileExtendsError, extends)
+	}
+	result := make(map[string]any, len(obj))
+	for _, key := range profileInheritedProperties {
+		if value, ok := parent[key]; ok {
+			result[key] = value
+		}
+	}
+	for key, value := range obj {
+		if key != "extends" && key != "filters" {
+			result[key] = value
+		}
+	}
+	if variables, ok := obj["variables"].(map[string]any); ok {
+		if parentVariables, ok := parent["variables"].(map[string]any); ok {
+			merged := maps.Clone(parentVariables)
+			maps.Copy(merged, variables)
+			result["variables"] = merged
+		}
+	}
+	if filtersObj, ok := obj["filters"]; ok {
+		filters, err := extendFilters(parent["filters"], filtersObj)
+		if err != nil {
+			return nil, burrito.WrapErrorf(err, jsonPathParseError, "filters")
+		}
+		result["filters"] = filters
+	}
+	return result, nil
+}
+
+// extendFilters applies the "filters" property of a profile that extends
+// another profile to the filters of the parent profile. See
+// resolveProfileExtends for the description of the format.
+func extendFilters(parentObj, childObj any) (any, error) {
+	child, ok := childObj.(map[string]any)
+	if !ok {
+		// Arrays and invalid values replace the filters of the parent. The
+		// invalid values are reported by the ProfileFromObject function.
+		return childObj, nil
+	}
+	parent, ok := parentObj.([]any)
+	if !ok {
+		parent = []any{}
+	}
+	listProperty := func(key string) ([]any, error) {
+		value, ok := child[key]
+		if !ok {
+			return nil, nil
+		}
+		list, ok := value.([]any)
+		if !ok {
+			return nil, burrito.WrappedErrorf(jsonPathTypeError, key, "array")
+		}
+		return list, nil
+	}
+	mapProperty := func(key string) (map[string]any, error) {
+		value, ok := child[key]
+		if !ok {
+			return map[string]any{}, nil
+		}
+		result, ok := value.(map[string]any)
+		if !ok {
+			return nil, burrito.WrappedErrorf(jsonPathTypeError, key, "object")
+		}
+		return result, nil
+	}
+	prepend, err1 := listProperty("prepend")
+	appended, err2 := listProperty("append")
+	replace, err3 := mapProperty("replace")
+	override, err4 := mapProperty("override")
+	if err := firstErr(err1, err2, err3, err4); err != nil {
+		return nil, err
+	}
+	for key := range child {
+		if !slices.Contains([]string{"prepend", "append", "replace", "override"}, key) {
+			return nil, burrito.WrappedErrorf(profileExtendsFiltersKeyError, key)
+		}
+	}
+	// Every ID from "replace" and "override" must match some filter
+	used := map[string]bool{}
+	result := slices.Clone(prepend)
+	for _, filter := range parent {
+		filterMap, ok := filter.(map[string]any)
+		if !ok {
+			result = append(result, filter)
+			continue
+		}
+		id := profileFilterEntryId(filterMap)
+		if replacement, ok := replace[id]; ok {
+			used["replace->"+id] = true
+			result = append(result, replacement)
+			continue
+		}
+		if overrideObj, ok := override[id]; ok {
+			used["override->"+id] = true
+			overrideMap, ok := overrideObj.(map[string]any)
+			if !ok {
+				return nil, burrito.WrappedErrorf(
+					jsonPathTypeError, "override->"+id, "object")
+			}
+			result = append(result, overrideFilterEntry(filterMap, overrideMap))
+			continue
+		}
+		result = append(result, filter)
+	}
+	result = append(result, appended...)
+	for _, group := range []struct {
+		name string
+		ids  map[string]any
+	}{{"replace", replace}, {"override", override}} {
+		ids := make([]string, 0, len(group.ids))
+		for id := range group.ids {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			if !used[group.name+"->"+id] {
+				return nil, burrito.WrappedErrorf(
+					profileExtendsUnknownFilterError, id,
+					fmt.Sprintf("%s->%s", group.name, id))
+			}
+		}
+	}
+	return result, nil
+}
+
+// profileFilterEntryId returns the ID of an entry of the "filters" list of a
+// profile, used by the "replace" and "override" properties of the profiles
+// that extend other profiles.
+func profileFilterEntryId(filter map[string]any) string {
+	if id, ok := filter["filter"].(string); ok {
+		return id
+	}
+	id, _ := filter["profile"].(string)
+	return id
+}
+
+// overrideFilterEntry returns a copy of the filter entry with the properties
+// from the override. The settings are merged instead of being replaced.
+func overrideFilterEntry(filter, override map[string]any) map[string]any {
+	result := maps.Clone(filter)
+	for key, value := range override {
+		if key == "settings" {
+			parentSettings, ok1 := filter["settings"].(map[string]any)
+			settings, ok2 := value.(map[string]any)
+			if ok1 && ok2 {
+				merged := maps.Clone(parentSettings)
+				maps.Copy(merged, settings)
+				value = merged
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
@@ -59,7 +59,7 @@ func DownloadResolverMaps(forceUpdate bool) ([]string, []string, error) {
 	if err != nil {
 		return nil, nil, burrito.WrapErrorf(err, "Failed to parse resolver cache update cooldown.\nCooldown: %s", *config.ResolverCacheUpdateCooldown)
 	}
-	MeasureStart("Prepare for resolvers download")
+	measure := MeasureStart("Prepare for resolvers download")
 	targetPath, err := getResolverCache(globalUserConfig.Resolvers[0])
 	if err != nil {
 		return nil, nil, burrito.WrapErrorf(err, resolverPathCacheError, globalUserConfig.Resolvers[0])
@@ -70,6 +70,7 @@ func DownloadResolverMaps(forceUpdate bool) ([]string, []string, error) {
 	if err != nil {
 		return nil, nil, burrito.WrapErrorf(err, osMkdirError, targetPath)
 	}
+	measure.End()
 	resolverFilePaths := make([]string, len(globalUserConfig.Resolvers))
 	// Download the resolvers to the cache path
 	for i, shortUrl := range globalUserConfig.Resolvers {
@@ -103,9 +104,9 @@ func DownloadResolverMaps(forceUpdate bool) ([]string, []string, error) {
 			info, _ := os.Stat(cachePath)
 			if forceUpdate || info.ModTime().Before(time.Now().Add(cooldown*-1)) {
 				Logger.Infof("Updating resolver %s", shortUrl)
-				MeasureStart("Pull repository %s", shortUrl)
+				measure := MeasureStart("Pull repository %s", shortUrl)
 				output, err := RunGitProcess([]string{"pull"}, cachePath)
-				MeasureEnd()
+				measure.End()
 				err = os.Chtimes(cachePath, time.Now(), time.Now())
 				if err != nil {
 					Logger.Debugf(osChtimesError, cachePath)
@@ -135,13 +136,13 @@ func DownloadResolverMaps(forceUpdate bool) ([]string, []string, error) {
 			return nil, nil, burrito.WrapErrorf(err, osMkdirError, cachePath)
 		}
 		Logger.Infof("Downloading resolver %s", shortUrl)
-		MeasureStart("Clone repository %s", shortUrl)
+		measure := MeasureStart("Clone repository %s", shortUrl)
 		output, err := RunGitProcess([]string{"clone", url, ".", "--depth", "1"}, cachePath)
 		if err != nil {
 			Logger.Error(strings.Join(output, "\n"))
 			return nil, nil, burrito.WrapErrorf(err, "Failed to clone repository.\nURL: %s", url)
 		}
-		MeasureEnd()
+		measure.End()
 		err = os.Chtimes(cachePath, time.Now(), time.Now())
 		if err != nil {
 			Logger.Debugf("Failed to update cache file modification time.\nPath: %s", cachePath)
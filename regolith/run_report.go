@@ -0,0 +1,415 @@
+package regolith
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// The report and the trace are written by "regolith run --report <path>"
+// and "regolith run --trace <path>". Both are created from the spans of the
+// run recorded by runReport. The spans are the parts of the run (the
+// filters, the export, the shell commands), which may be nested and may run
+// at the same time (the async filters).
+
+// The categories of the spans.
+const (
+	reportFilterCategory = "filter"
+	reportStepCategory   = "step"
+)
+
+// RunReport is the content of the file created with the --report flag.
+type RunReport struct {
+	Profile    string    `json:"profile"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs float64   `json:"durationMs"`
+
+	// Filters are the filters in the order in which they started,
+	// including the filters of the nested profiles and the subfilters of
+	// the async filters.
+	Filters []RunReportFilter `json:"filters"`
+
+	// Export is the export step, or nil if the run failed before
+	// exporting the files.
+	Export *RunReportStep `json:"export"`
+}
+
+// RunReportFilter is a filter in the RunReport.
+type RunReportFilter struct {
+	Id      string `json:"id"`
+	Profile string `json:"profile"`
+
+	// Depth is the number of the filters that contain this filter (nested
+	// profiles, async filters and remote filters).
+	Depth int `json:"depth"`
+
+	// StartMs is the time from the start of the run.
+	StartMs    float64 `json:"startMs"`
+	DurationMs float64 `json:"durationMs"`
+
+	// Status is "succeeded", "failed", "cached" or "interrupted".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	// FilesChanged lists the files of the tmp directory changed by the
+	// filter. The changes of the filters that run at the same time in the
+	// same directory (the async filters that aren't isolated) can't be
+	// told apart, so they're reported by all of these filters.
+	FilesChanged *FilesChanged `json:"filesChanged,omitempty"`
+
+	// Events are the filter events sent by the filter (see
+	// FilterEventPrefix).
+	Events []FilterEvent `json:"events,omitempty"`
+}
+
+// RunReportStep is a step of the run other than a filter.
+type RunReportStep struct {
+	StartMs    float64 `json:"startMs"`
+	DurationMs float64 `json:"durationMs"`
+	Status     string  `json:"status"`
+}
+
+// FilesChanged lists the paths relative to the tmp directory of the files
+// added, modified and removed by a filter.
+type FilesChanged struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// runReport records the spans of a run. All of its methods can be called on
+// nil, which does nothing.
+type runReport struct {
+	mutex sync.Mutex
+
+	// trackFiles enables comparing the files before and after running each
+	// filter, which is only needed for the report.
+	trackFiles bool
+
+	start time.Time
+	spans []*reportSpan
+
+	// lanes are the spans at the top of each lane (a "thread" of the
+	// trace), or nil for the free lanes. The spans that run at the same
+	// time are put in different lanes.
+	lanes []*reportSpan
+
+	// status and err are set by finish.
+	status string
+	err    string
+}
+
+// reportSpan is a part of the run recorded by runReport.
+type reportSpan struct {
+	report   *runReport
+	name     string
+	category string
+	profile  string
+	parent   *reportSpan
+	depth    int
+
+	// lane is the lane of the span and previous is the span that was at
+	// the top of the lane before this span started.
+	lane     int
+	previous *reportSpan
+
+	start    time.Time
+	duration time.Duration
+	status   string
+	err      string
+
+	// before is the state of the files before running the filter, used
+	// for finding the changed files.
+	before map[string]reportFileState
+	files  *FilesChanged
+
+	// events are the filter events sent by the filter.
+	events []FilterEvent
+}
+
+// reportFileState is the state of a file used for finding the files changed
+// by a filter. The files are compared by their content, because the
+// modification times can have low resolution and the filters can keep them
+// unchanged.
+type reportFileState struct {
+	size int64
+	hash string
+}
+
+func newRunReport(trackFiles bool) *runReport {
+	return &runReport{trackFiles: trackFiles, start: time.Now()}
+}
+
+// startSpan starts a span of the part of the run in the context. The span
+// of the context is the parent of the new span.
+func (r *runReport) startSpan(context RunContext, name, category string) *reportSpan {
+	if r == nil {
+		return nil
+	}
+	span := &reportSpan{
+		report:   r,
+		name:     name,
+		category: category,
+		profile:  context.Profile,
+		parent:   context.reportSpan,
+	}
+	if span.parent != nil {
+		span.depth = span.parent.depth + 1
+	}
+	if r.trackFiles && category == reportFilterCategory {
+		if workingDir, err := context.GetAbsoluteWorkingDirectory(); err == nil {
+			span.before = readReportFileStates(workingDir)
+		}
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// Use the lane of the parent, unless another span already runs in it
+	span.lane = -1
+	if span.parent != nil && r.lanes[span.parent.lane] == span.parent {
+		span.lane = span.parent.lane
+	} else if i := slices.Index(r.lanes, nil); i != -1 {
+		span.lane = i
+	} else {
+		span.lane = len(r.lanes)
+		r.lanes = append(r.lanes, nil)
+	}
+	span.previous = r.lanes[span.lane]
+	r.lanes[span.lane] = span
+	span.start = time.Now()
+	r.spans = append(r.spans, span)
+	return span
+}
+
+// end ends the span. The status is "succeeded", "failed" (if err isn't nil)
+// or "interrupted".
+func (s *reportSpan) end(context RunContext, interrupted bool, err error) {
+	if s == nil {
+		return
+	}
+	status := "succeeded"
+	if err != nil {
+		status = "failed"
+	} else if interrupted {
+		status = "interrupted"
+	}
+	if s.before != nil {
+		if workingDir, err := context.GetAbsoluteWorkingDirectory(); err == nil {
+			s.files = compareReportFileStates(s.before, readReportFileStates(workingDir))
+		}
+		s.before = nil
+	}
+	s.endWithStatus(status, err)
+}
+
+// endWithStatus ends the span with the given status.
+func (s *reportSpan) endWithStatus(status string, err error) {
+	if s == nil {
+		return
+	}
+	r := s.report
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	s.duration = time.Since(s.start)
+	s.status = status
+	if err != nil {
+		s.err = strings.Join(burrito.GetAllMessages(err), "\n")
+	}
+	r.lanes[s.lane] = s.previous
+}
+
+// addEvent records the filter event sent by the filter of the span.
+func (s *reportSpan) addEvent(event FilterEvent) {
+	if s == nil {
+		return
+	}
+	s.report.mutex.Lock()
+	defer s.report.mutex.Unlock()
+	s.events = append(s.events, event)
+}
+
+// finish sets the result of the whole run.
+func (r *runReport) finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.status = "succeeded"
+	if err != nil {
+		r.status = "failed"
+		r.err = strings.Join(burrito.GetAllMessages(err), "\n")
+	}
+}
+
+// sinceStart returns the time from the start of the run in milliseconds.
+func (r *runReport) sinceStart(t time.Time) float64 {
+	return durationMs(t.Sub(r.start))
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeReport writes the report of the run of the profile to the file.
+func (r *runReport) writeReport(path, profile string) error {
+	r.mutex.Lock()
+	report := RunReport{
+		Profile:    profile,
+		Status:     r.status,
+		Error:      r.err,
+		StartedAt:  r.start,
+		DurationMs: durationMs(time.Since(r.start)),
+		Filters:    []RunReportFilter{},
+	}
+	for _, span := range r.spans {
+		switch {
+		case span.category == reportFilterCategory:
+			report.Filters = append(report.Filters, RunReportFilter{
+				Id:           span.name,
+				Profile:      span.profile,
+				Depth:        span.depth,
+				StartMs:      r.sinceStart(span.start),
+				DurationMs:   durationMs(span.duration),
+				Status:       span.status,
+				Error:        span.err,
+				FilesChanged: span.files,
+				Events:       span.events,
+			})
+		case span.name == "export":
+			report.Export = &RunReportStep{
+				StartMs:    r.sinceStart(span.start),
+				DurationMs: durationMs(span.duration),
+				Status:     span.status,
+			}
+		}
+	}
+	r.mutex.Unlock()
+	return writeReportJson(path, report)
+}
+
+// writeTrace writes the spans of the run of the profile to the file in the
+// Chrome trace event format, which can be opened in the trace viewers like
+// Perfetto or chrome://tracing.
+func (r *runReport) writeTrace(path, profile string) error {
+	type traceEvent struct {
+		Name      string         `json:"name"`
+		Category  string         `json:"cat,omitempty"`
+		Phase     string         `json:"ph"`
+		Timestamp float64        `json:"ts"`
+		Duration  float64        `json:"dur,omitempty"`
+		Pid       int            `json:"pid"`
+		Tid       int            `json:"tid"`
+		Args      map[string]any `json:"args,omitempty"`
+	}
+	r.mutex.Lock()
+	events := []traceEvent{{
+		Name:  "process_name",
+		Phase: "M",
+		Pid:   1,
+		Args:  map[string]any{"name": "regolith run " + profile},
+	}}
+	for _, span := range r.spans {
+		args := map[string]any{"profile": span.profile, "status": span.status}
+		if span.err != "" {
+			args["error"] = span.err
+		}
+		events = append(events, traceEvent{
+			Name:      span.name,
+			Category:  span.category,
+			Phase:     "X",
+			Timestamp: float64(span.start.Sub(r.start)) / float64(time.Microsecond),
+			Duration:  float64(span.duration) / float64(time.Microsecond),
+			Pid:       1,
+			Tid:       span.lane + 1,
+			Args:      args,
+		})
+	}
+	r.mutex.Unlock()
+	return writeReportJson(path, map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+func writeReportJson(path string, content any) error {
+	data, err := json.MarshalIndent(content, "", "\t")
+	if err != nil { // This should never happen.
+		return burrito.WrapError(err, "Failed to marshal the report JSON.")
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return burrito.WrapErrorf(err, osMkdirError, dir)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return burrito.WrapErrorf(err, fileWriteError, path)
+	}
+	return nil
+}
+
+// readReportFileStates returns the states of the files in the RP, BP and
+// data directories of the working directory. The errors are ignored, the
+// files that can't be read are missing from the result.
+func readReportFileStates(workingDir string) map[string]reportFileState {
+	result := map[string]reportFileState{}
+	for _, subpath := range filterCacheSubpaths {
+		root := filepath.Join(workingDir, subpath)
+		// The RP and BP can be links to the export targets
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+			h := sha256.New()
+			if err := hashFile(h, path); err != nil {
+				return nil
+			}
+			result[subpath+"/"+filepath.ToSlash(rel)] = reportFileState{
+				size: info.Size(), hash: hex.EncodeToString(h.Sum(nil))}
+			return nil
+		})
+	}
+	return result
+}
+
+// compareReportFileStates returns the files changed between the two states.
+func compareReportFileStates(before, after map[string]reportFileState) *FilesChanged {
+	result := &FilesChanged{Added: []string{}, Modified: []string{}, Removed: []string{}}
+	for path, state := range after {
+		previous, ok := before[path]
+		if !ok {
+			result.Added = append(result.Added, path)
+		} else if previous != state {
+			result.Modified = append(result.Modified, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			result.Removed = append(result.Removed, path)
+		}
+	}
+	slices.Sort(result.Added)
+	slices.Sort(result.Modified)
+	slices.Sort(result.Removed)
+	return result
+}
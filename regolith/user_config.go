@@ -80,6 +80,29 @@ type UserConfig struct {
 	// filters. When nil, Regolith tries the platform-specific executable names
 	// (e.g. python3, python).
 	PythonRunner *string `json:"python_runner,omitempty"`
+
+	// FilterTimeout is an optional default timeout of the filters. The filters
+	// that run longer are stopped. The timeout can be overridden with the
+	// "timeout" property of the filter or its definition.
+	FilterTimeout *string `json:"filter_timeout,omitempty"`
+
+	// WatchIgnore is a list of gitignore-style patterns of the paths that
+	// don't trigger a rebuild in the watch mode. They're used together with
+	// the "ignore" patterns from the project configuration.
+	WatchIgnore []string `json:"watch_ignore,omitempty"`
+
+	// WatchDebounce is the time that the watch mode waits after the last
+	// change before rebuilding the project.
+	WatchDebounce *string `json:"watch_debounce,omitempty"`
+
+	// WatchPolling is a flag that makes the watch mode check the files
+	// periodically instead of using the file system notifications, which
+	// don't work on some network drives and in some containers.
+	WatchPolling *bool `json:"watch_polling,omitempty"`
+
+	// WatchPollingInterval is the time between the checks of the files when
+	// using the polling in the watch mode.
+	WatchPollingInterval *string `json:"watch_polling_interval,omitempty"`
 }
 
 func NewUserConfig() *UserConfig {
@@ -100,6 +123,11 @@ func NewUserConfig() *UserConfig {
 		NodeRunner:                  nil,
 		NpmRunner:                   nil,
 		PythonRunner:                nil,
+		FilterTimeout:               nil,
+		WatchIgnore:                 []string{},
+		WatchDebounce:               nil,
+		WatchPolling:                nil,
+		WatchPollingInterval:        nil,
 	}
 }
 
@@ -135,6 +163,16 @@ func (u *UserConfig) String() string {
 	result += "\n" + extra
 	extra, _ = u.stringPropertyValue("python_runner")
 	result += "\n" + extra
+	extra, _ = u.stringPropertyValue("filter_timeout")
+	result += "\n" + extra
+	extra, _ = u.stringPropertyValue("watch_ignore")
+	result += "\n" + extra
+	extra, _ = u.stringPropertyValue("watch_debounce")
+	result += "\n" + extra
+	extra, _ = u.stringPropertyValue("watch_polling")
+	result += "\n" + extra
+	extra, _ = u.stringPropertyValue("watch_polling_interval")
+	result += "\n" + extra
 	return result
 }
 
@@ -253,6 +291,39 @@ func (u *UserConfig) stringPropertyValue(name string) (string, error) {
 			value = fmt.Sprintf("%v", *u.PythonRunner)
 		}
 		return fmt.Sprintf("python_runner: %v", value), nil
+	case "filter_timeout":
+		value := "null"
+		if u.FilterTimeout != nil {
+			value = fmt.Sprintf("%v", *u.FilterTimeout)
+		}
+		return fmt.Sprintf("filter_timeout: %v", value), nil
+	case "watch_ignore":
+		if len(u.WatchIgnore) == 0 {
+			return "watch_ignore: []", nil
+		}
+		result := "watch_ignore: \n"
+		for i, pattern := range u.WatchIgnore {
+			result += fmt.Sprintf("\t- [%v] %s\n", i, pattern)
+		}
+		return result, nil
+	case "watch_debounce":
+		value := "null"
+		if u.WatchDebounce != nil {
+			value = fmt.Sprintf("%v", *u.WatchDebounce)
+		}
+		return fmt.Sprintf("watch_debounce: %v", value), nil
+	case "watch_polling":
+		value := "null"
+		if u.WatchPolling != nil {
+			value = fmt.Sprintf("%v", *u.WatchPolling)
+		}
+		return fmt.Sprintf("watch_polling: %v", value), nil
+	case "watch_polling_interval":
+		value := "null"
+		if u.WatchPollingInterval != nil {
+			value = fmt.Sprintf("%v", *u.WatchPollingInterval)
+		}
+		return fmt.Sprintf("watch_polling_interval: %v", value), nil
 	}
 	return "", burrito.WrapErrorf(nil, invalidUserConfigPropertyError, name)
 }
@@ -282,6 +353,21 @@ func (u *UserConfig) fillDefaults() {
 	if u.NodeRunnerOverride == nil {
 		u.NodeRunnerOverride = map[string]string{}
 	}
+	if u.WatchIgnore == nil {
+		u.WatchIgnore = []string{}
+	}
+	if u.WatchDebounce == nil {
+		u.WatchDebounce = new(string)
+		*u.WatchDebounce = defaultWatchDebounce.String()
+	}
+	if u.WatchPolling == nil {
+		u.WatchPolling = new(bool)
+		*u.WatchPolling = false
+	}
+	if u.WatchPollingInterval == nil {
+		u.WatchPollingInterval = new(string)
+		*u.WatchPollingInterval = defaultWatchPollingInterval.String()
+	}
 	if u.Resolvers == nil {
 		u.Resolvers = []string{}
 	}
@@ -298,8 +384,8 @@ func (u *UserConfig) fillWithFileData(path string) error {
 		if err != nil {
 			return burrito.WrapErrorf(err, fileReadError, path)
 		}
-		if err = json.Unmarshal(file, u); err != nil {
-			return burrito.WrapErrorf(err, jsonUnmarshalError, path)
+		if err = unmarshalJSONC(file, u, path); err != nil {
+			return burrito.PassError(err)
 		}
 	} else if !os.IsNotExist(err) {
 		return burrito.WrapErrorf(err, osStatErrorAny, path)
@@ -4,16 +4,20 @@ import (
 	"bufio"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Bedrock-OSS/go-burrito/burrito"
@@ -148,19 +152,44 @@ func CreateEnvironmentVariables(filterDir string) ([]string, error) {
 	if err != nil {
 		return nil, burrito.WrapErrorf(err, osGetwdError)
 	}
-	return append(os.Environ(), fmt.Sprintf("FILTER_DIR=%s", filterDir), fmt.Sprintf("ROOT_DIR=%s", projectDir), fmt.Sprintf("DEBUG=%t", burrito.PrintStackTrace)), nil
+	return append(os.Environ(), fmt.Sprintf("FILTER_DIR=%s", filterDir), fmt.Sprintf("ROOT_DIR=%s", projectDir), fmt.Sprintf("DEBUG=%t", burrito.PrintStackTrace), fmt.Sprintf("REGOLITH_EVENT_PREFIX=%s", FilterEventPrefix)), nil
 }
 
 // RunSubProcess runs a sub-process with specified arguments and working
 // directory
 func RunSubProcess(command string, args []string, filterDir string, workingDir string, outputLabel string) error {
+	return runSubProcess(nil, nil, command, args, filterDir, workingDir, outputLabel)
+}
+
+// runFilterSubProcess runs a sub-process of a filter. Unlike RunSubProcess,
+// the process and all of its child processes are killed when the filter is
+// cancelled using the RunContext, and its filter events are recorded in the
+// report of the run.
+func runFilterSubProcess(context RunContext, command string, args []string, filterDir string, workingDir string, outputLabel string) error {
+	return runSubProcess(context.cancel, context.reportSpan, command, args, filterDir, workingDir, outputLabel)
+}
+
+// subProcessOutputWaitDelay is how long Regolith waits for the output of a
+// sub-process after the process exits. The output can stay open longer if
+// the process started a background process that inherited it.
+const subProcessOutputWaitDelay = 5 * time.Second
+
+// runSubProcess runs a sub-process. The process and all of its child
+// processes are killed when Regolith receives an interrupt signal, or when
+// the cancel channel is closed if it's not nil. The filter events of the
+// process are recorded in the span, which can be nil.
+func runSubProcess(cancel <-chan struct{}, span *reportSpan, command string, args []string, filterDir string, workingDir string, outputLabel string) error {
 	Logger.Debugf("Exec: %s %s", command, strings.Join(args, " "))
 	cmd := exec.Command(command, args...)
 	cmd.Dir = workingDir
-	out, _ := cmd.StdoutPipe()
-	err, _ := cmd.StderrPipe()
-	go LogStd(out, Logger.Infof, outputLabel)
-	go LogStd(err, Logger.Errorf, outputLabel)
+	// The output is copied to the pipes by the exec package, so that Wait
+	// can stop waiting for it after WaitDelay, even if a background process
+	// keeps it open.
+	outReader, outWriter := io.Pipe()
+	errReader, errWriter := io.Pipe()
+	cmd.Stdout = outWriter
+	cmd.Stderr = errWriter
+	cmd.WaitDelay = subProcessOutputWaitDelay
 	env, err1 := CreateEnvironmentVariables(filterDir)
 	if err1 != nil {
 		return burrito.WrapErrorf(
@@ -168,8 +197,61 @@ func RunSubProcess(command string, args []string, filterDir string, workingDir s
 			"Failed to create FILTER_DIR and ROOT_DIR environment variables.")
 	}
 	cmd.Env = env
-
-	return cmd.Run()
+	prepareProcessTree(cmd)
+
+	if err1 := cmd.Start(); err1 != nil {
+		return err1
+	}
+	// Kill the process tree on cancellation
+	var stopReason string
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer close(stopped)
+		defer signal.Stop(sigChan)
+		select {
+		case <-cancel: // Never selected if cancel is nil
+			stopReason = subProcessCancelledError
+		case <-sigChan:
+			stopReason = subProcessStoppedByUserError
+		case <-finished:
+			return
+		}
+		if err := killProcessTree(cmd); err != nil {
+			Logger.Debugf("Failed to kill the process tree: %s", err.Error())
+		}
+	}()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		logStd(outReader, Logger.Infof, outputLabel, span)
+	}()
+	go func() {
+		defer wg.Done()
+		logStd(errReader, Logger.Errorf, outputLabel, span)
+	}()
+	// Wait returns after the output is copied to the pipes, or after
+	// WaitDelay if the output is still open when the process exits. Closing
+	// the pipes lets the loggers read the rest of the output and finish.
+	err1 = cmd.Wait()
+	outWriter.Close()
+	errWriter.Close()
+	wg.Wait()
+	close(finished)
+	<-stopped
+	if stopReason != "" {
+		return burrito.WrappedError(stopReason)
+	}
+	if errors.Is(err1, exec.ErrWaitDelay) {
+		Logger.Debugf(
+			"[%s] The output of the process is still open after it exited. "+
+				"It may be used by a background process.", outputLabel)
+		return nil
+	}
+	return err1
 }
 
 // RunGitProcess runs a git command with specified arguments and working
@@ -190,11 +272,32 @@ func RunGitProcess(args []string, workingDir string) ([]string, error) {
 	return completeOutput, cmd.Run()
 }
 
-// LogStd logs the output of a sub-process
+// LogStd logs the output of a sub-process. The lines that start with
+// FilterEventPrefix are handled as filter events instead of being logged
+// directly.
 func LogStd(in io.ReadCloser, logFunc func(template string, args ...any), outputLabel string) {
+	logStd(in, logFunc, outputLabel, nil)
+}
+
+// logStd is LogStd that records the filter events in the report span, which
+// can be nil.
+func logStd(in io.ReadCloser, logFunc func(template string, args ...any), outputLabel string, span *reportSpan) {
 	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		logFunc("[%s] %s", outputLabel, scanner.Text())
+		line := scanner.Text()
+		event, isEvent, err := ParseFilterEvent(line)
+		if !isEvent {
+			logFunc("[%s] %s", outputLabel, line)
+			continue
+		}
+		if err != nil {
+			Logger.Warnf(
+				"[%s] Invalid filter event: %s\n\tLine: %s",
+				outputLabel, err.Error(), line)
+			continue
+		}
+		handleFilterEvent(event, outputLabel, span)
 	}
 }
 
@@ -350,43 +453,47 @@ func ResolvePath(path string) (string, error) {
 	return filepath.Clean(path), nil
 }
 
-type measure struct {
+// Measure is a measure of the time of a part of a function, started with
+// MeasureStart. All of its methods can be called on nil, which does nothing.
+type Measure struct {
 	// Name of the measure
 	Name string
 	// Location of the measure
 	Location string
 	// Start time of the measure
 	StartTime time.Time
+
+	endOnce sync.Once
 }
 
-var lastMeasure *measure
 var EnableTimings = false
 
-func MeasureStart(name string, args ...any) {
+// MeasureStart starts measuring the time of a part of the calling function.
+// The measure ends when the End method of the returned measure is called.
+// Every call returns a separate measure, so the functions that run at the
+// same time (for example the async filters) don't end each other's
+// measures. It returns nil if the timings are disabled.
+func MeasureStart(name string, args ...any) *Measure {
 	if !EnableTimings {
-		return
-	}
-	if lastMeasure != nil {
-		MeasureEnd()
+		return nil
 	}
 	_, fn, line, _ := runtime.Caller(1)
-	lastMeasure = &measure{
+	return &Measure{
 		Name:      fmt.Sprintf(name, args...),
 		StartTime: time.Now(),
 		Location:  fmt.Sprintf("%s:%d", filepath.Base(fn), line),
 	}
 }
 
-func MeasureEnd() {
-	if !EnableTimings {
+// End logs the time of the measure. Only the first call logs the time.
+func (m *Measure) End() {
+	if m == nil {
 		return
 	}
-	if lastMeasure == nil {
-		return
-	}
-	duration := time.Since(lastMeasure.StartTime)
-	Logger.Infof("%s took %s (%s)", lastMeasure.Name, duration, lastMeasure.Location)
-	lastMeasure = nil
+	m.endOnce.Do(func() {
+		duration := time.Since(m.StartTime)
+		Logger.Infof("%s took %s (%s)", m.Name, duration, m.Location)
+	})
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -0,0 +1,404 @@
+package regolith
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// schemas contains the JSON schemas of the configuration files bundled with
+// Regolith, used by the "regolith validate" command.
+//
+//go:embed schemas/*.schema.json
+var schemas embed.FS
+
+// filterSettingsSchemaFile is the name of the optional file with the JSON
+// schema of the settings of a remote filter. The file is placed next to the
+// "filter.json" file of the filter.
+const filterSettingsSchemaFile = "settings.schema.json"
+
+// ValidationProblem is a single problem found by the "regolith validate"
+// command.
+type ValidationProblem struct {
+	// Location is the location of the invalid value in the
+	// "path:line:column" format, or just the path to the file if the
+	// location is unknown.
+	Location string
+	// Message describes the problem.
+	Message string
+}
+
+// Validate handles the "regolith validate" command. It checks the
+// config.json file, the filter.json files of the installed filters and the
+// user configuration against the bundled JSON schemas. The settings of the
+// filters are checked against the schemas shipped by the filters. All
+// problems are printed at once, and the function returns an error if any
+// problems were found.
+func Validate(debug bool, env string) error {
+	InitLogging(debug)
+	defer ShutdownLogging()
+	if err := loadEnvFileFromArg(env); err != nil {
+		return burrito.WrapErrorf(err, loadEnvFileFromArgError, env)
+	}
+	problems, err := ValidateProject()
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	if len(problems) == 0 {
+		Logger.Info("No problems found.")
+		return nil
+	}
+	printValidationProblems(os.Stdout, problems)
+	return burrito.WrappedErrorf(validationFailedError, len(problems))
+}
+
+// ValidateProject returns the problems found in the configuration files of
+// the project in the current working directory. The errors are returned
+// only if the validation can't be performed, for example, when the
+// config.json file doesn't exist.
+func ValidateProject() ([]ValidationProblem, error) {
+	if err := CheckSuspiciousLocation(); err != nil {
+		return nil, burrito.PassError(err)
+	}
+	var problems []ValidationProblem
+	// config.json
+	configData, err := os.ReadFile(ConfigFilePath)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, fileReadError, ConfigFilePath)
+	}
+	configProblems, configJson, err := validateJsonFile(
+		ConfigFilePath, configData, "config.schema.json")
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	problems = append(problems, configProblems...)
+	if configJson == nil {
+		// The file isn't valid JSON, there is nothing more to check
+		return problems, nil
+	}
+	// The schema doesn't check everything, for example, if the filters
+	// use existing filter definitions. Run the normal parser, but only if
+	// the schema didn't find any problems to not report them twice.
+	if len(configProblems) == 0 {
+		if _, err := ConfigFromObject(configJson); err != nil {
+			location := ConfigFilePath
+			if jsonPath, ok := jsonErrorPath(err); ok {
+				location = problemLocation(ConfigFilePath, configData, jsonPath)
+			}
+			problems = append(problems, ValidationProblem{
+				Location: location,
+				Message:  errorSummary(err),
+			})
+		}
+	}
+	// filter.json and the settings of the installed filters
+	dotRegolithPath, err := GetDotRegolith(".")
+	if err != nil {
+		return nil, burrito.WrapError(
+			err, "Unable to get the path to regolith cache folder.")
+	}
+	filterProblems, err := validateInstalledFilters(
+		configJson, configData, dotRegolithPath)
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	problems = append(problems, filterProblems...)
+	// user_config.json
+	userConfigPath, err := getGlobalUserConfigPath()
+	if err != nil {
+		return nil, burrito.WrapError(err, getGlobalUserConfigPathError)
+	}
+	if userConfigData, err := os.ReadFile(userConfigPath); err == nil {
+		userConfigProblems, _, err := validateJsonFile(
+			userConfigPath, userConfigData, "user_config.schema.json")
+		if err != nil {
+			return nil, burrito.PassError(err)
+		}
+		problems = append(problems, userConfigProblems...)
+	} else if !os.IsNotExist(err) {
+		return nil, burrito.WrapErrorf(err, fileReadError, userConfigPath)
+	}
+	return problems, nil
+}
+
+// validateInstalledFilters validates the filter.json files of the installed
+// remote filters used by the project and the settings passed to them in
+// the profiles.
+func validateInstalledFilters(
+	configJson map[string]any, configData []byte, dotRegolithPath string,
+) ([]ValidationProblem, error) {
+	var problems []ValidationProblem
+	filterDefinitions, _ := filterDefinitionsFromConfigMap(configJson)
+	names := make([]string, 0, len(filterDefinitions))
+	for name := range filterDefinitions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	// The schemas of the settings of the installed filters
+	settingsSchemas := map[string]*jsonschema.Schema{}
+	for _, name := range names {
+		definition, ok := filterDefinitions[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		if runWith, _ := definition["runWith"].(string); runWith != "" {
+			continue // Not a remote filter
+		}
+		remoteDefinition := &RemoteFilterDefinition{
+			FilterDefinition: FilterDefinition{Id: name}}
+		downloadPath := remoteDefinition.GetDownloadPath(dotRegolithPath)
+		filterJsonPath := filepath.Join(downloadPath, "filter.json")
+		filterJsonData, err := os.ReadFile(filterJsonPath)
+		if os.IsNotExist(err) {
+			Logger.Warnf(
+				"The %q filter is not installed, skipping its validation.", name)
+			continue
+		} else if err != nil {
+			return nil, burrito.WrapErrorf(err, fileReadError, filterJsonPath)
+		}
+		filterJsonProblems, _, err := validateJsonFile(
+			filterJsonPath, filterJsonData, "filter.schema.json")
+		if err != nil {
+			return nil, burrito.PassError(err)
+		}
+		problems = append(problems, filterJsonProblems...)
+		// The schema of the settings
+		schemaPath := filepath.Join(downloadPath, filterSettingsSchemaFile)
+		schemaData, err := os.ReadFile(schemaPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, burrito.WrapErrorf(err, fileReadError, schemaPath)
+		}
+		schema, err := compileJsonSchema(schemaPath, schemaData)
+		if err != nil {
+			problems = append(problems, ValidationProblem{
+				Location: schemaPath,
+				Message:  errorSummary(err),
+			})
+			continue
+		}
+		settingsSchemas[name] = schema
+	}
+	if len(settingsSchemas) == 0 {
+		return problems, nil
+	}
+	// The settings of the filters in the profiles
+	strippedConfig, err := stripJSONC(configData)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, ConfigFilePath)
+	}
+	configInstance, err := jsonschema.UnmarshalJSON(bytes.NewReader(strippedConfig))
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, ConfigFilePath)
+	}
+	profiles, _ := FindByJSONPath[map[string]any](configInstance, "regolith/profiles")
+	profileNames := make([]string, 0, len(profiles))
+	for name := range profiles {
+		profileNames = append(profileNames, name)
+	}
+	slices.Sort(profileNames)
+	for _, profileName := range profileNames {
+		profile, _ := profiles[profileName].(map[string]any)
+		path := []string{"regolith", "profiles", profileName, "filters"}
+		switch filters := profile["filters"].(type) {
+		case []any:
+			problems = append(problems, validateFilterSettings(
+				filters, path, settingsSchemas, configData)...)
+		case map[string]any:
+			// The filters added to the filters of the extended profile
+			for _, key := range []string{"prepend", "append"} {
+				list, _ := filters[key].([]any)
+				problems = append(problems, validateFilterSettings(
+					list, append(slices.Clone(path), key), settingsSchemas,
+					configData)...)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// validateFilterSettings validates the settings of the filters from the list
+// of the filters of a profile or an async filter. The path is the JSON path
+// to the list.
+func validateFilterSettings(
+	filters []any, path []string, settingsSchemas map[string]*jsonschema.Schema,
+	configData []byte,
+) []ValidationProblem {
+	var problems []ValidationProblem
+	for i, filter := range filters {
+		filter, ok := filter.(map[string]any)
+		if !ok {
+			continue
+		}
+		filterPath := append(slices.Clone(path), strconv.Itoa(i))
+		if asyncFilters, ok := filter["asyncFilters"].([]any); ok {
+			problems = append(problems, validateFilterSettings(
+				asyncFilters, append(filterPath, "asyncFilters"),
+				settingsSchemas, configData)...)
+			continue
+		}
+		name, _ := filter["filter"].(string)
+		schema, ok := settingsSchemas[name]
+		if !ok {
+			continue
+		}
+		settings, ok := filter["settings"]
+		if !ok {
+			settings = map[string]any{}
+		}
+		settingsPath := append(filterPath, "settings")
+		for _, problem := range schemaProblems(schema.Validate(settings)) {
+			problem.path = append(slices.Clone(settingsPath), problem.path...)
+			problems = append(problems, ValidationProblem{
+				Location: problemLocation(
+					ConfigFilePath, configData, strings.Join(problem.path, "->")),
+				Message: fmt.Sprintf(
+					"Invalid settings of the %q filter: %s", name, problem.message),
+			})
+		}
+	}
+	return problems
+}
+
+// validateJsonFile validates the JSONC file against one of the bundled
+// schemas. It returns the problems and the parsed content of the file, or
+// nil if the file isn't valid JSONC.
+func validateJsonFile(
+	path string, data []byte, schemaName string,
+) ([]ValidationProblem, map[string]any, error) {
+	var parsed map[string]any
+	stripped, err := stripJSONC(data)
+	if err == nil {
+		err = json.Unmarshal(stripped, &parsed)
+	}
+	if err != nil {
+		location := path
+		if offset, ok := jsonErrorOffset(err); ok {
+			location = jsonOffsetLocation(path, data, offset)
+		}
+		return []ValidationProblem{{Location: location, Message: err.Error()}}, nil, nil
+	}
+	schemaData, err := schemas.ReadFile("schemas/" + schemaName)
+	if err != nil {
+		return nil, nil, burrito.WrapErrorf(err, fileReadError, schemaName)
+	}
+	schema, err := compileJsonSchema("regolith:///schemas/"+schemaName, schemaData)
+	if err != nil {
+		return nil, nil, burrito.PassError(err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, nil, burrito.WrapErrorf(err, jsonUnmarshalError, path)
+	}
+	var problems []ValidationProblem
+	for _, problem := range schemaProblems(schema.Validate(instance)) {
+		problems = append(problems, ValidationProblem{
+			Location: problemLocation(path, data, strings.Join(problem.path, "->")),
+			Message:  problem.message,
+		})
+	}
+	return problems, parsed, nil
+}
+
+// compileJsonSchema compiles the JSON schema. The url is used to identify
+// the schema in the error messages.
+func compileJsonSchema(url string, data []byte) (*jsonschema.Schema, error) {
+	stripped, err := stripJSONC(data)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, url)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonUnmarshalError, url)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, burrito.WrapErrorf(err, jsonSchemaCompileError, url)
+	}
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, jsonSchemaCompileError, url)
+	}
+	return schema, nil
+}
+
+// schemaProblem is a single problem reported by the JSON schema validator.
+type schemaProblem struct {
+	// path is the path to the invalid value.
+	path    []string
+	message string
+}
+
+// schemaProblems returns the problems from the error returned by the JSON
+// schema validator. Only the most specific problems are returned, except
+// for "anyOf" and "oneOf" which are reported as a single problem, because
+// the problems of every alternative would be confusing.
+func schemaProblems(err error) []schemaProblem {
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		if err != nil {
+			return []schemaProblem{{message: err.Error()}}
+		}
+		return nil
+	}
+	var result []schemaProblem
+	var visit func(e *jsonschema.ValidationError)
+	visit = func(e *jsonschema.ValidationError) {
+		switch e.ErrorKind.(type) {
+		case *kind.AnyOf, *kind.OneOf:
+			e = &jsonschema.ValidationError{
+				SchemaURL:        e.SchemaURL,
+				InstanceLocation: e.InstanceLocation,
+				ErrorKind:        e.ErrorKind,
+			}
+		}
+		if len(e.Causes) == 0 {
+			path := e.InstanceLocation
+			// Point to the unexpected property instead of its parent
+			if additional, ok := e.ErrorKind.(*kind.AdditionalProperties); ok &&
+				len(additional.Properties) == 1 {
+				path = append(slices.Clone(path), additional.Properties[0])
+			}
+			result = append(result, schemaProblem{
+				path:    path,
+				message: e.BasicOutput().Error.String(),
+			})
+			return
+		}
+		for _, cause := range e.Causes {
+			visit(cause)
+		}
+	}
+	visit(validationErr)
+	return result
+}
+
+// problemLocation returns the location of the value with the given JSON
+// path, or just the path to the file if the location can't be found.
+func problemLocation(path string, data []byte, jsonPath string) string {
+	if location, ok := jsonValueLocation(path, data, jsonPath); ok {
+		return location
+	}
+	return path
+}
+
+// printValidationProblems prints the problems found by the "regolith
+// validate" command.
+func printValidationProblems(w io.Writer, problems []ValidationProblem) {
+	for _, problem := range problems {
+		fmt.Fprintf(w, "%s: %s\n", problem.Location, problem.Message)
+	}
+	fmt.Fprintf(w, "\nFound %d problems.\n", len(problems))
+}
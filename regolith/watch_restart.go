@@ -0,0 +1,240 @@
+package regolith
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+)
+
+// Functions for restarting the profile in the watch mode from the first
+// filter affected by the changes in the source files.
+//
+// The filters can declare the paths of the tmp directory that they use with
+// the "inputs" property, for example ["RP/textures/**"]. A filter must not
+// modify the files that don't match its inputs. A filter without the
+// "inputs" property can use any file.
+//
+// Before running the filters, Regolith saves the snapshots of the tmp
+// directory. When the source files change, the changed files are copied into
+// the snapshots of the filters that run before the first filter that uses
+// them, and the profile restarts from the snapshot of that filter. The
+// snapshots are only saved before the filters that can be the first affected
+// filter. These are the filters that run before the first filter without
+// the "inputs" property.
+
+// watchChanges collects the changes of the source files reported by the
+// DirWatcher.
+type watchChanges struct {
+	mutex sync.Mutex
+
+	// paths maps the changed paths, relative to the tmp directory, to true
+	// if the path may be a directory.
+	paths map[string]bool
+
+	// all is true if there is a change that affects all filters.
+	all bool
+}
+
+// add adds the changed path, relative to the tmp directory.
+func (c *watchChanges) add(path string, maybeDir bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.paths == nil {
+		c.paths = map[string]bool{}
+	}
+	c.paths[path] = c.paths[path] || maybeDir
+}
+
+// addAll adds a change that affects all filters.
+func (c *watchChanges) addAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.all = true
+}
+
+// take returns the collected changes and clears them.
+func (c *watchChanges) take() (paths map[string]bool, all bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	paths, all = c.paths, c.all
+	c.paths, c.all = nil, false
+	return paths, all
+}
+
+// watchState stores the changes of the source files and the information
+// about the snapshots of the tmp directory in the watch mode.
+type watchState struct {
+	changes watchChanges
+
+	// snapshots is the number of the valid snapshots. The snapshot with
+	// index i (starting from 1) is the content of the tmp directory before
+	// running the filter with index i of the profile.
+	snapshots int
+
+	// firstFilter is the index of the filter from which the profile runs.
+	firstFilter int
+}
+
+// getWatchSnapshotPath returns the path to the snapshot of the tmp directory
+// saved before running the filter with the given index.
+func getWatchSnapshotPath(dotRegolithPath string, index int) string {
+	return filepath.Join(
+		dotRegolithPath, "cache", "watch_snapshots", strconv.Itoa(index))
+}
+
+// watchSnapshotLimit returns the index of the last filter of the profile
+// before which the snapshot can be saved. The profiles that use the data
+// export don't use the snapshots, because the data exported after running
+// the profile would be replaced with the data from the snapshots.
+func watchSnapshotLimit(profile Profile, context RunContext) (int, error) {
+	dataExportFilters, err := dataExportFilterNames(profile, context)
+	if err != nil {
+		return 0, burrito.PassError(err)
+	}
+	if len(dataExportFilters) > 0 {
+		return 0, nil
+	}
+	for i, filter := range profile.Filters {
+		if filter.GetInputs() == nil {
+			return i, nil
+		}
+	}
+	return len(profile.Filters), nil
+}
+
+// firstAffectedFilter returns the index of the first filter of the profile
+// that uses any of the changed paths. It returns the number of the filters if
+// none of them uses the changed paths.
+func firstAffectedFilter(profile Profile, paths map[string]bool) int {
+	for i, filter := range profile.Filters {
+		inputs := filter.GetInputs()
+		if inputs == nil {
+			return i
+		}
+		for path, maybeDir := range paths {
+			for _, input := range inputs {
+				if matchGlob(input, path) || maybeDir && matchGlobDir(input, path) {
+					return i
+				}
+			}
+		}
+	}
+	return len(profile.Filters)
+}
+
+// restoreSnapshot restores the tmp directory from the snapshot of the first
+// filter affected by the changes of the source files. It returns false if
+// there is no such snapshot and the tmp directory must be set up from the
+// source files.
+func (s *watchState) restoreSnapshot(context RunContext, profile Profile) (bool, error) {
+	paths, all := s.changes.take()
+	s.firstFilter = 0
+	limit, err := watchSnapshotLimit(profile, context)
+	if err != nil {
+		return false, burrito.PassError(err)
+	}
+	first := min(firstAffectedFilter(profile, paths), s.snapshots, limit)
+	if all || len(paths) == 0 || first == 0 {
+		s.snapshots = 0
+		return false, nil
+	}
+	// Invalidate the snapshots that are updated below in case of an error
+	s.snapshots = 0
+	// The files don't change before the first filter that uses them, so
+	// the changed files are the same in all of the earlier snapshots.
+	for i := 1; i <= first; i++ {
+		snapshot := getWatchSnapshotPath(context.DotRegolithPath, i)
+		for path := range paths {
+			target := filepath.Join(snapshot, filepath.FromSlash(path))
+			if err := syncSourcePath(context.Config, path, target); err != nil {
+				return false, burrito.WrapErrorf(err, watchSnapshotError, snapshot)
+			}
+		}
+	}
+	snapshot := getWatchSnapshotPath(context.DotRegolithPath, first)
+	absTmpPath, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	if err != nil {
+		return false, burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	for _, subpath := range filterCacheSubpaths {
+		source := filepath.Join(snapshot, subpath)
+		target := filepath.Join(absTmpPath, subpath)
+		if err := SyncDirectories(source, target, false); err != nil {
+			return false, burrito.WrapErrorf(err, watchSnapshotError, snapshot)
+		}
+	}
+	s.snapshots = first
+	s.firstFilter = first
+	if first < len(profile.Filters) {
+		Logger.Infof(
+			"Restarting from the %s filter (%s).",
+			nth(first), profile.Filters[first].GetId())
+	} else {
+		Logger.Info("The changed files aren't used by any filter.")
+	}
+	return true, nil
+}
+
+// saveSnapshot saves the content of the tmp directory as the snapshot
+// before running the filter with the given index, unless the snapshot is
+// still valid or it can't be used because its index exceeds the limit
+// returned by watchSnapshotLimit. It does nothing if the state is nil.
+func (s *watchState) saveSnapshot(context RunContext, index, limit int) error {
+	if s == nil || index <= s.snapshots || index > limit {
+		return nil
+	}
+	snapshot := getWatchSnapshotPath(context.DotRegolithPath, index)
+	absTmpPath, err := GetAbsoluteWorkingDirectory(context.DotRegolithPath)
+	if err != nil {
+		return burrito.WrapError(err, getAbsoluteWorkingDirectoryError)
+	}
+	for _, subpath := range filterCacheSubpaths {
+		source := filepath.Join(absTmpPath, subpath)
+		target := filepath.Join(snapshot, subpath)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			if err := os.MkdirAll(source, 0755); err != nil {
+				return burrito.WrapErrorf(err, osMkdirError, source)
+			}
+		}
+		if err := SyncDirectories(source, target, false); err != nil {
+			return burrito.WrapErrorf(err, watchSnapshotError, snapshot)
+		}
+	}
+	s.snapshots = index
+	return nil
+}
+
+// syncSourcePath copies the source file or directory of the path relative
+// to the tmp directory to the target path. If the source doesn't exist, the
+// target is removed.
+func syncSourcePath(config *Config, path, target string) error {
+	root, rest, _ := strings.Cut(path, "/")
+	var sourceRoot string
+	switch root {
+	case "RP":
+		sourceRoot = config.ResourceFolder
+	case "BP":
+		sourceRoot = config.BehaviorFolder
+	case "data":
+		sourceRoot = config.DataPath
+	}
+	source := filepath.Join(sourceRoot, filepath.FromSlash(rest))
+	stat, err := os.Stat(source)
+	if err != nil && !os.IsNotExist(err) {
+		return burrito.WrapErrorf(err, osStatErrorAny, source)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return burrito.WrapErrorf(err, osRemoveError, target)
+	}
+	if stat == nil {
+		return nil
+	}
+	if stat.IsDir() {
+		return SyncDirectories(source, target, false)
+	}
+	return CopyFile(source, target)
+}
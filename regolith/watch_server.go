@@ -0,0 +1,341 @@
+package regolith
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/coder/websocket"
+)
+
+// The build event server is started by "regolith watch --serve <address>".
+// It lets the other tools (editor extensions, in-game debug tools,
+// dashboards) react to the builds of the watch mode. It serves two
+// endpoints:
+//   - /events - a WebSocket stream of the build events as JSON text messages
+//     (see BuildEvent).
+//   - /status - a JSON object with the result of the last finished build and
+//     a flag that tells if a build is running (see BuildStatus).
+
+// The types of the build events.
+const (
+	buildStartedEvent        = "started"
+	buildFilterStartedEvent  = "filterStarted"
+	buildFilterFinishedEvent = "filterFinished"
+	buildExportedEvent       = "exported"
+	buildFinishedEvent       = "finished"
+	buildFailedEvent         = "failed"
+)
+
+// buildEventWriteTimeout is the time limit for sending a build event to a
+// client.
+const buildEventWriteTimeout = 10 * time.Second
+
+// BuildEvent is an event sent by the build event server.
+type BuildEvent struct {
+	// Type is the type of the event: "started", "filterStarted",
+	// "filterFinished", "exported", "finished" or "failed".
+	Type string `json:"type"`
+
+	// Time is the time when the event happened.
+	Time time.Time `json:"time"`
+
+	// Profile is the name of the profile. For the filters of the nested
+	// profiles, it's the name of the nested profile.
+	Profile string `json:"profile,omitempty"`
+
+	// Filter is the ID of the filter of the filter events.
+	Filter string `json:"filter,omitempty"`
+
+	// DurationMs is the duration of the filter, the export or the whole build
+	// in milliseconds.
+	DurationMs int64 `json:"durationMs,omitempty"`
+
+	// Cached is true if the output of the filter was restored from the cache.
+	Cached bool `json:"cached,omitempty"`
+
+	// Error is the text of the error of the failed build.
+	Error string `json:"error,omitempty"`
+}
+
+// BuildResult is the summary of a build served by the /status endpoint.
+type BuildResult struct {
+	Profile    string       `json:"profile,omitempty"`
+	Status     string       `json:"status"`
+	StartedAt  time.Time    `json:"startedAt,omitzero"`
+	FinishedAt time.Time    `json:"finishedAt,omitzero"`
+	DurationMs int64        `json:"durationMs"`
+	Exported   bool         `json:"exported"`
+	Filters    []BuildEvent `json:"filters"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// BuildStatus is the response of the /status endpoint.
+type BuildStatus struct {
+	// Running is true while a build is running.
+	Running bool `json:"running"`
+
+	// LastBuild is the result of the last finished build, or null if no
+	// build finished yet.
+	LastBuild *BuildResult `json:"lastBuild"`
+}
+
+// buildEventServer is the HTTP server that sends the build events to its
+// clients. All of its methods can be called on nil, which does nothing.
+type buildEventServer struct {
+	mutex sync.Mutex
+
+	// clients are the connected WebSocket clients.
+	clients map[*buildEventClient]bool
+
+	// current is the result of the running build, or nil if no build is
+	// running.
+	current *BuildResult
+
+	// last is the result of the last finished build.
+	last *BuildResult
+
+	// host is the host of the address passed to "--serve". The server
+	// accepts only the requests for this host and the loopback hosts.
+	host string
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// buildEventClient is a WebSocket connection of the build event server.
+type buildEventClient struct {
+	conn *websocket.Conn
+
+	// messages are the messages waiting to be sent to the client.
+	messages chan []byte
+
+	// done is closed when the server disconnects the client.
+	done chan struct{}
+
+	// closeOnce makes sure that done is closed only once.
+	closeOnce sync.Once
+}
+
+// startBuildEventServer starts the build event server on the address. If the
+// address doesn't specify the host (for example ":8080"), the server only
+// listens on localhost.
+func startBuildEventServer(address string) (*buildEventServer, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, invalidServeAddressError, address)
+	}
+	if host == "" {
+		address = net.JoinHostPort("localhost", port)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, burrito.WrapErrorf(err, buildEventServerError, address)
+	}
+	s := &buildEventServer{
+		clients:  map[*buildEventClient]bool{},
+		host:     host,
+		listener: listener,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", s.checkHost(s.handleEvents))
+	mux.HandleFunc("/status", s.checkHost(s.handleStatus))
+	s.server = &http.Server{Handler: mux}
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			Logger.Errorf("The build event server stopped: %s", err.Error())
+		}
+	}()
+	Logger.Infof("Serving the build events on http://%s", listener.Addr())
+	return s, nil
+}
+
+// Close stops the server and disconnects the clients.
+func (s *buildEventServer) Close() {
+	if s == nil {
+		return
+	}
+	s.server.Close()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for client := range s.clients {
+		client.close()
+	}
+	s.clients = map[*buildEventClient]bool{}
+}
+
+// emit updates the build result and sends the event to the clients. The
+// time of the event is set by emit.
+func (s *buildEventServer) emit(event BuildEvent) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	event.Time = time.Now()
+	s.updateResult(&event)
+	message, err := json.Marshal(event)
+	if err != nil {
+		Logger.Debugf("Failed to encode the build event: %s", err.Error())
+		return
+	}
+	for client := range s.clients {
+		select {
+		case client.messages <- message:
+		default:
+			// The client doesn't read the events fast enough
+			delete(s.clients, client)
+			client.close()
+		}
+	}
+}
+
+// updateResult updates the result of the running build with the event. The
+// duration of the build is added to the events that finish the build.
+func (s *buildEventServer) updateResult(event *BuildEvent) {
+	if event.Type == buildStartedEvent || s.current == nil {
+		s.current = &BuildResult{
+			Profile:   event.Profile,
+			Status:    "running",
+			StartedAt: event.Time,
+			Filters:   []BuildEvent{},
+		}
+	}
+	switch event.Type {
+	case buildFilterFinishedEvent:
+		s.current.Filters = append(s.current.Filters, *event)
+	case buildExportedEvent:
+		s.current.Exported = true
+	case buildFinishedEvent, buildFailedEvent:
+		s.current.Status = "succeeded"
+		if event.Type == buildFailedEvent {
+			s.current.Status = "failed"
+			s.current.Error = event.Error
+		}
+		s.current.FinishedAt = event.Time
+		s.current.DurationMs = event.Time.Sub(s.current.StartedAt).Milliseconds()
+		event.DurationMs = s.current.DurationMs
+		s.last, s.current = s.current, nil
+	}
+}
+
+func (s *buildEventServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	status := BuildStatus{Running: s.current != nil, LastBuild: s.last}
+	response, err := json.Marshal(status)
+	s.mutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// checkHost wraps the handler, so that it rejects the requests with the Host
+// header other than a loopback host or the host of the server address. It
+// prevents the websites from reading the endpoints with DNS rebinding.
+func (s *buildEventServer) checkHost(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if !isLoopbackHost(host) && (s.host == "" || !strings.EqualFold(host, s.host)) {
+			http.Error(w, "The host isn't allowed.", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleEvents accepts the WebSocket connection and sends the build events to
+// the client until the connection is closed. The connections from the web
+// pages are accepted only if the pages are served from the loopback
+// addresses, so other websites opened in the browser can't read the events.
+func (s *buildEventServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !isLoopbackOrigin(origin) {
+		http.Error(w, "The origin isn't allowed.", http.StatusForbidden)
+		return
+	}
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		// The origin is already checked above
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		// Accept has already written the error response
+		Logger.Debugf("Failed to accept the WebSocket connection: %s", err.Error())
+		return
+	}
+	client := &buildEventClient{
+		conn:     conn,
+		messages: make(chan []byte, 64),
+		done:     make(chan struct{}),
+	}
+	s.mutex.Lock()
+	s.clients[client] = true
+	s.mutex.Unlock()
+	// The server doesn't expect any messages. CloseRead handles the control
+	// frames and cancels the context when the connection is closed.
+	client.write(conn.CloseRead(context.Background()))
+	s.mutex.Lock()
+	delete(s.clients, client)
+	s.mutex.Unlock()
+}
+
+// isLoopbackOrigin returns true if the value of the Origin header points to
+// localhost or to a loopback IP address.
+func isLoopbackOrigin(origin string) bool {
+	originUrl, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return isLoopbackHost(originUrl.Hostname())
+}
+
+// isLoopbackHost returns true if the host is localhost or a loopback IP
+// address.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// close makes the client disconnect after sending the current message.
+func (c *buildEventClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// write sends the messages of the client until the connection is closed or
+// the server disconnects the client.
+func (c *buildEventClient) write(ctx context.Context) {
+	defer c.conn.CloseNow()
+	for {
+		select {
+		case message := <-c.messages:
+			writeCtx, cancel := context.WithTimeout(ctx, buildEventWriteTimeout)
+			err := c.conn.Write(writeCtx, websocket.MessageText, message)
+			cancel()
+			if err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.Close(websocket.StatusGoingAway, "")
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
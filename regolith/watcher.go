@@ -1,6 +1,9 @@
 package regolith
 
 import (
+	"cmp"
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -9,6 +12,229 @@ import (
 	"github.com/arexon/fsnotify"
 )
 
+const (
+	// defaultWatchDebounce is the default time that the watch mode waits
+	// after the last change before rebuilding the project.
+	defaultWatchDebounce = 100 * time.Millisecond
+
+	// defaultWatchPollingInterval is the default time between the checks of
+	// the files when the watch mode uses polling.
+	defaultWatchPollingInterval = time.Second
+)
+
+// WatchConfig is the configuration of the watch mode from the "watch"
+// property of the project configuration. The empty values are replaced with
+// the values from the user configuration.
+type WatchConfig struct {
+	// Ignore is a list of gitignore-style patterns of the paths that don't
+	// trigger a rebuild.
+	Ignore []string `json:"ignore,omitempty"`
+
+	// Debounce is the time that the watch mode waits after the last change
+	// before rebuilding the project.
+	Debounce string `json:"debounce,omitempty"`
+
+	// Polling makes the watch mode check the files periodically instead of
+	// using the file system notifications.
+	Polling *bool `json:"polling,omitempty"`
+
+	// PollingInterval is the time between the checks of the files when
+	// using polling.
+	PollingInterval string `json:"pollingInterval,omitempty"`
+}
+
+// IsZero lets json:",omitzero" omit an empty watch configuration.
+func (w WatchConfig) IsZero() bool {
+	return len(w.Ignore) == 0 && w.Debounce == "" && w.Polling == nil &&
+		w.PollingInterval == ""
+}
+
+// WatchConfigFromObject creates a "WatchConfig" object from
+// map[string]interface{}
+func WatchConfigFromObject(obj map[string]any) (WatchConfig, error) {
+	result := WatchConfig{}
+	// Ignore
+	if ignore, ok := obj["ignore"]; ok {
+		ignore, ok := ignore.([]any)
+		if !ok {
+			return result, burrito.WrappedErrorf(jsonPropertyTypeError, "ignore", "array")
+		}
+		for i, pattern := range ignore {
+			pattern, ok := pattern.(string)
+			path := fmt.Sprintf("ignore->%d", i)
+			if !ok {
+				return result, burrito.WrappedErrorf(jsonPathTypeError, path, "string")
+			}
+			if !isValidGlob(strings.TrimPrefix(pattern, "!")) {
+				return result, burrito.WrappedErrorf(invalidGlobError, pattern, path)
+			}
+			result.Ignore = append(result.Ignore, pattern)
+		}
+	}
+	// Debounce and PollingInterval
+	for _, property := range []struct {
+		name      string
+		value     *string
+		allowZero bool
+	}{
+		{"debounce", &result.Debounce, true},
+		{"pollingInterval", &result.PollingInterval, false},
+	} {
+		value, ok := obj[property.name]
+		if !ok {
+			continue
+		}
+		duration, ok := value.(string)
+		if !ok {
+			return result, burrito.WrappedErrorf(
+				jsonPropertyTypeError, property.name, "string")
+		}
+		if _, err := parseWatchDuration(
+			duration, property.name, property.allowZero); err != nil {
+			return result, burrito.PassError(err)
+		}
+		*property.value = duration
+	}
+	// Polling
+	if polling, ok := obj["polling"]; ok {
+		polling, ok := polling.(bool)
+		if !ok {
+			return result, burrito.WrappedErrorf(jsonPropertyTypeError, "polling", "boolean")
+		}
+		result.Polling = &polling
+	}
+	return result, nil
+}
+
+// parseWatchDuration parses the duration from the watch mode configuration.
+// The duration can't be negative, and it can be zero only if allowZero is
+// true.
+func parseWatchDuration(value, property string, allowZero bool) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 || duration == 0 && !allowZero {
+		return 0, burrito.WrappedErrorf(invalidDurationError, value, property)
+	}
+	return duration, nil
+}
+
+// watchOptions are the options of the watch mode combined from the project
+// configuration, the user configuration and the default values.
+type watchOptions struct {
+	ignore          []ignorePattern
+	debounce        time.Duration
+	polling         bool
+	pollingInterval time.Duration
+}
+
+// getWatchOptions returns the options of the watch mode. The ignore patterns
+// from the project configuration and the user configuration are combined.
+// For the other options, the project configuration has priority over the user
+// configuration.
+func getWatchOptions(config *Config) (watchOptions, error) {
+	result := watchOptions{}
+	userConfig, err := getCombinedUserConfig()
+	if err != nil {
+		return result, burrito.WrapError(err, getUserConfigError)
+	}
+	for _, pattern := range append(userConfig.WatchIgnore, config.Watch.Ignore...) {
+		if pattern != "" {
+			result.ignore = append(result.ignore, parseIgnorePattern(pattern))
+		}
+	}
+	durations := []struct {
+		project, user, property string
+		allowZero               bool
+		fallback                time.Duration
+		value                   *time.Duration
+	}{
+		{config.Watch.Debounce, *userConfig.WatchDebounce, "watch_debounce",
+			true, defaultWatchDebounce, &result.debounce},
+		{config.Watch.PollingInterval, *userConfig.WatchPollingInterval,
+			"watch_polling_interval", false, defaultWatchPollingInterval,
+			&result.pollingInterval},
+	}
+	for _, d := range durations {
+		*d.value = d.fallback
+		value := cmp.Or(d.project, d.user)
+		if value == "" {
+			continue
+		}
+		if *d.value, err = parseWatchDuration(value, d.property, d.allowZero); err != nil {
+			return result, burrito.PassError(err)
+		}
+	}
+	if config.Watch.Polling != nil {
+		result.polling = *config.Watch.Polling
+	} else {
+		result.polling = *userConfig.WatchPolling
+	}
+	return result, nil
+}
+
+// ignorePattern is a parsed gitignore-style pattern of the watch mode.
+type ignorePattern struct {
+	// glob is the pattern of the path relative to the project directory.
+	glob string
+
+	// negated is true for the patterns starting with "!", which include
+	// the paths ignored by the previous patterns again.
+	negated bool
+
+	// dirOnly is true for the patterns ending with "/", which only match
+	// the directories.
+	dirOnly bool
+}
+
+// parseIgnorePattern parses the gitignore-style pattern. The patterns without
+// a slash (other than the trailing slash) match the files and directories
+// with matching names at any depth, for example "*.swp" or ".git". The other
+// patterns are relative to the project directory. The files in the matched
+// directories are also ignored.
+func parseIgnorePattern(pattern string) ignorePattern {
+	result := ignorePattern{}
+	if strings.HasPrefix(pattern, "!") {
+		result.negated = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		result.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		pattern = pattern[1:]
+	} else if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	result.glob = pattern
+	return result
+}
+
+// isIgnoredPath returns true if the slash-separated path relative to the
+// project directory is ignored by the patterns. Like in gitignore, the last
+// pattern that matches the path decides whether it's ignored, and the paths
+// in the ignored directories can't be included again.
+func isIgnoredPath(patterns []ignorePattern, path string, isDir bool) bool {
+	segments := strings.Split(path, "/")
+	for i := 1; i <= len(segments); i++ {
+		prefix := strings.Join(segments[:i], "/")
+		// All of the prefixes except the full path are directories
+		prefixIsDir := isDir || i < len(segments)
+		ignored := false
+		for _, pattern := range patterns {
+			if pattern.dirOnly && !prefixIsDir {
+				continue
+			}
+			if matchGlob(pattern.glob, prefix) {
+				ignored = !pattern.negated
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
+
 // DirWatcher handles watching for changes in a multiple root directories.
 //
 // fsnotify doesn't *officially* support recursive file watching yet. Windows
@@ -16,10 +242,18 @@ import (
 // a custom fork with patches to manually enable it.
 //
 // Fork patch: https://github.com/arexon/fsnotify/blob/main/fsnotify.go#L481
+//
+// The file system notifications don't work on some network drives and in some
+// containers, so the DirWatcher can also use polling (see pollingWatcher).
 type DirWatcher struct {
-	// watcher is the underlying fsnotify watcher that notifies about the
-	// changes in the files.
-	watcher *fsnotify.Watcher
+	// events and watchErrors are the channels of the backend (fsnotify or
+	// polling) that notify about the changes in the files and about the
+	// errors. They're nil while the DirWatcher is paused.
+	events      <-chan fsnotify.Event
+	watchErrors <-chan error
+
+	// closeBackend stops the backend that sends the events.
+	closeBackend func() error
 
 	// roots is a list of directories to watch.
 	// TODO: Currently, all of the information needed to determine the roots
@@ -30,9 +264,16 @@ type DirWatcher struct {
 	// config is a reference to the configuration of the project.
 	config *Config
 
-	debounce *time.Timer
+	// options are the options of the watch mode.
+	options watchOptions
+
+	// projectRoot is the absolute path to the project directory, used for
+	// matching the ignore patterns.
+	projectRoot string
+
 	// interruption channel is used to notify the main thread about the kind of
-	// interruption that was detected by 'watcher', it can be 'rp', 'bp' or 'data'.
+	// interruption that was detected by 'watcher', it can be 'rp', 'bp', 'data'
+	// or 'extras'.
 	interruption chan string
 
 	// errors is a channel used by DirWatcher to inform the main thread about
@@ -42,6 +283,10 @@ type DirWatcher struct {
 	// stage is a channel used for receiving commands from the main thread, to
 	// pause or restart the watcher.
 	stage <-chan string
+
+	// changes collects the paths of all changed files, including the changes
+	// that don't send an interruption because of the debounce.
+	changes *watchChanges
 }
 
 func NewDirWatcher(
@@ -49,6 +294,7 @@ func NewDirWatcher(
 	interruption chan string,
 	errors chan error,
 	stage <-chan string,
+	changes *watchChanges,
 ) error {
 	var roots []string
 	if config.ResourceFolder != "" {
@@ -63,14 +309,25 @@ func NewDirWatcher(
 	if config.WatchPaths != nil {
 		roots = append(roots, config.WatchPaths...)
 	}
+	options, err := getWatchOptions(config)
+	if err != nil {
+		return burrito.PassError(err)
+	}
+	projectRoot, err := os.Getwd()
+	if err != nil {
+		return burrito.WrapError(err, osGetwdError)
+	}
 	d := &DirWatcher{
 		roots:        roots,
 		config:       config,
+		options:      options,
+		projectRoot:  projectRoot,
 		interruption: interruption,
 		errors:       errors,
 		stage:        stage,
+		changes:      changes,
 	}
-	err := d.watch()
+	err = d.watch()
 	if err != nil {
 		return err
 	}
@@ -79,85 +336,166 @@ func NewDirWatcher(
 }
 
 func (d *DirWatcher) watch() error {
+	if d.options.polling {
+		poller, err := newPollingWatcher(
+			d.roots, d.options.pollingInterval, d.isIgnored)
+		if err != nil {
+			return burrito.WrapError(err, "Could not initialize directory polling")
+		}
+		d.events, d.watchErrors, d.closeBackend = poller.Events, poller.Errors, poller.Close
+		return nil
+	}
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return burrito.WrapError(err, "Could not initialize directory watching")
 	}
-	d.watcher = watcher
 	for _, root := range d.roots {
 		// We have to manually signal to fsnotify that it should recursively watch this
 		// path by using "/..." or "\...".
 		recursiveRoot := filepath.Join(root, "...")
-		if err := d.watcher.Add(recursiveRoot); err != nil {
+		if err := watcher.Add(recursiveRoot); err != nil {
+			watcher.Close()
 			return burrito.WrapErrorf(err, "Could not start watching `%s`", root)
 		}
 	}
+	d.events, d.watchErrors, d.closeBackend = watcher.Events, watcher.Errors, watcher.Close
 	return nil
 }
 
 func (d *DirWatcher) start() {
-	paused := false
+	var debounce *time.Timer
+	var debounceC <-chan time.Time
+	// source is the source of the interruption sent when the debounce time
+	// passes. The changes of the data have the lowest priority because
+	// some interruptions from the data are ignored.
+	source := ""
 	for {
-		var debounce <-chan time.Time
-		if d.debounce != nil {
-			debounce = d.debounce.C
-		}
 		select {
-		case err, ok := <-d.watcher.Errors:
+		case err, ok := <-d.watchErrors:
 			if !ok {
-				if paused {
-					continue
-				}
 				return
 			}
 			d.errors <- err
 			return
-		case event, ok := <-d.watcher.Events:
+		case event, ok := <-d.events:
 			if !ok {
-				if paused {
-					continue
-				}
 				return
 			}
-			if d.debounce != nil || event.Op.Has(fsnotify.Chmod) {
+			if event.Op.Has(fsnotify.Chmod) {
 				continue
 			}
-			if isInDir(event.Name, d.config.ResourceFolder) {
-				d.interruption <- "rp"
-			} else if isInDir(event.Name, d.config.BehaviorFolder) {
-				d.interruption <- "bp"
-			} else if isInDir(event.Name, d.config.DataPath) {
-				d.interruption <- "data"
-			} else {
-				for _, path := range d.config.WatchPaths {
-					if isInDir(event.Name, path) {
-						d.interruption <- "extras"
-					}
-				}
+			eventSource := d.eventSource(event.Name)
+			if eventSource == "" || d.isIgnored(event.Name) {
+				continue
 			}
-			if d.debounce == nil {
-				d.debounce = time.NewTimer(100 * time.Millisecond)
-			} else {
-				d.debounce.Reset(100 * time.Millisecond)
+			d.recordChange(event.Name)
+			if source == "" || source == "data" {
+				source = eventSource
 			}
-		case <-debounce:
-			if d.debounce != nil {
-				d.debounce.Stop()
-				d.debounce = nil
+			if debounce == nil {
+				debounce = time.NewTimer(d.options.debounce)
+			} else {
+				debounce.Reset(d.options.debounce)
 			}
-		case stage := <-d.stage:
-			switch stage {
-			case "pause":
-				d.watcher.Close()
-				paused = true
-			case "restart":
-				if err := d.watch(); err != nil {
-					d.errors <- err
+			debounceC = debounce.C
+		case <-debounceC:
+			debounceC = nil
+			// Keep handling the commands from the main thread, which can
+			// pause the watcher before reading the interruption.
+			for sent := false; !sent; {
+				select {
+				case d.interruption <- source:
+					sent = true
+				case stage := <-d.stage:
+					d.handleStage(stage)
 				}
-				paused = false
 			}
+			source = ""
+		case stage := <-d.stage:
+			d.handleStage(stage)
+		}
+	}
+}
+
+// handleStage pauses or restarts the watcher on the command from the main
+// thread.
+func (d *DirWatcher) handleStage(stage string) {
+	switch stage {
+	case "pause":
+		if d.closeBackend != nil {
+			d.closeBackend()
+		}
+		d.events, d.watchErrors, d.closeBackend = nil, nil, nil
+	case "restart":
+		if err := d.watch(); err != nil {
+			d.errors <- err
+		}
+	}
+}
+
+// eventSource returns the name of the source of the changed path used for
+// the interruptions: "rp", "bp", "data" or "extras". It returns an empty
+// string if the path isn't in any of the watched directories.
+func (d *DirWatcher) eventSource(path string) string {
+	if isInDir(path, d.config.ResourceFolder) {
+		return "rp"
+	} else if isInDir(path, d.config.BehaviorFolder) {
+		return "bp"
+	} else if isInDir(path, d.config.DataPath) {
+		return "data"
+	}
+	for _, watchPath := range d.config.WatchPaths {
+		if isInDir(path, watchPath) {
+			return "extras"
+		}
+	}
+	return ""
+}
+
+// isIgnored returns true if the path is ignored by the ignore patterns of
+// the watch mode.
+func (d *DirWatcher) isIgnored(path string) bool {
+	if len(d.options.ignore) == 0 {
+		return false
+	}
+	relPath := path
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(d.projectRoot, path); err == nil {
+			relPath = rel
+		}
+	}
+	stat, err := os.Stat(path)
+	isDir := err == nil && stat.IsDir()
+	return isIgnoredPath(
+		d.options.ignore, filepath.ToSlash(filepath.Clean(relPath)), isDir)
+}
+
+// recordChange adds the changed path to the changes as a path relative to
+// the tmp directory. The changes of the paths that aren't copied to the tmp
+// directory (the watchPaths) affect all filters.
+func (d *DirWatcher) recordChange(path string) {
+	if d.changes == nil {
+		return
+	}
+	for _, root := range []struct{ path, tmpPath string }{
+		{d.config.ResourceFolder, "RP"},
+		{d.config.BehaviorFolder, "BP"},
+		{d.config.DataPath, "data"},
+	} {
+		if root.path == "" || !isInDir(path, root.path) {
+			continue
+		}
+		rel, err := filepath.Rel(root.path, path)
+		if err != nil || rel == "." {
+			break
 		}
+		// The removed paths may be directories
+		stat, err := os.Stat(path)
+		maybeDir := err != nil || stat.IsDir()
+		d.changes.add(root.tmpPath+"/"+filepath.ToSlash(rel), maybeDir)
+		return
 	}
+	d.changes.addAll()
 }
 
 func isInDir(path, root string) bool {
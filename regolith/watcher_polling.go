@@ -0,0 +1,167 @@
+package regolith
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Bedrock-OSS/go-burrito/burrito"
+	"github.com/arexon/fsnotify"
+)
+
+// pollingWatcher is the backend of the DirWatcher used instead of fsnotify
+// when the watch mode uses polling. It walks the watched directories
+// periodically and sends the same events as fsnotify.Watcher for the files
+// that were created, modified or removed since the previous check.
+type pollingWatcher struct {
+	// Events is the channel of the changes in the files.
+	Events chan fsnotify.Event
+
+	// Errors is the channel of the errors of walking the directories.
+	Errors chan error
+
+	// roots is a list of directories to watch.
+	roots []string
+
+	// interval is the time between the checks of the files.
+	interval time.Duration
+
+	// isIgnored returns true for the paths that aren't checked. The files
+	// in the ignored directories aren't checked either.
+	isIgnored func(path string) bool
+
+	// files maps the paths of the files and directories to their state
+	// from the previous check.
+	files map[string]polledFile
+
+	// done is closed to stop the polling.
+	done chan struct{}
+}
+
+// polledFile is the state of a file used for detecting the changes.
+type polledFile struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// newPollingWatcher creates a pollingWatcher and starts polling the
+// directories.
+func newPollingWatcher(
+	roots []string, interval time.Duration, isIgnored func(string) bool,
+) (*pollingWatcher, error) {
+	w := &pollingWatcher{
+		Events:    make(chan fsnotify.Event),
+		Errors:    make(chan error),
+		roots:     roots,
+		interval:  interval,
+		isIgnored: isIgnored,
+		done:      make(chan struct{}),
+	}
+	files, err := w.scan()
+	if err != nil {
+		return nil, burrito.PassError(err)
+	}
+	w.files = files
+	go w.poll()
+	return w, nil
+}
+
+// Close stops the polling.
+func (w *pollingWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollingWatcher) poll() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		files, err := w.scan()
+		if err != nil {
+			select {
+			case w.Errors <- err:
+			case <-w.done:
+			}
+			return
+		}
+		for _, event := range w.diff(files) {
+			select {
+			case w.Events <- event:
+			case <-w.done:
+				return
+			}
+		}
+		w.files = files
+	}
+}
+
+// scan returns the current state of the files in the watched directories.
+// The roots that don't exist are skipped.
+func (w *pollingWatcher) scan() (map[string]polledFile, error) {
+	files := map[string]polledFile{}
+	for _, root := range w.roots {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// The files can be removed while walking the directory
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if path != root && w.isIgnored(path) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			files[path] = polledFile{
+				size:    info.Size(),
+				modTime: info.ModTime(),
+				isDir:   info.IsDir(),
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, burrito.WrapErrorf(err, "Could not poll `%s`", root)
+		}
+	}
+	return files, nil
+}
+
+// diff returns the events of the changes between the previous state of the
+// files and the current state. The changes of the modification time of the
+// directories are ignored, because they're caused by the changes of the
+// files inside them.
+func (w *pollingWatcher) diff(files map[string]polledFile) []fsnotify.Event {
+	var events []fsnotify.Event
+	for path, file := range files {
+		previous, ok := w.files[path]
+		if !ok {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+		} else if previous.isDir != file.isDir ||
+			!file.isDir && (previous.size != file.size ||
+				!previous.modTime.Equal(file.modTime)) {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+		}
+	}
+	return events
+}
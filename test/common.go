@@ -4,12 +4,15 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Bedrock-OSS/regolith/regolith"
 	"github.com/otiai10/copy"
 )
 
@@ -51,6 +54,10 @@ const (
 	versionedRemoteFilterProjectAfterRun = "testdata/versioned_remote_filter_project_after_run"
 	exeFilterPath                        = "testdata/exe_filter"
 
+	// wasmFilterPath is a directory that contains files for testing the
+	// WebAssembly filter. It contains a project and an expected result.
+	wasmFilterPath = "testdata/wasm_filter"
+
 	// profileFilterPath is a directory that contains files for testing
 	// ProfileFilter. It contains a project and an expected result. The
 	// projects have both valid and invalid profiles.
@@ -121,7 +128,7 @@ func getPathHashes(root string) (map[string]string, error) {
 			if err != nil {
 				return err
 			}
-			if data.Name() == ".ignoreme" || data.Name() == "lockfile.txt" { // Ignored file
+			if data.Name() == ".ignoreme" || data.Name() == "lockfile.txt" || data.Name() == "regolith.lock" { // Ignored file
 				return nil
 			}
 			relPath, err := filepath.Rel(root, path)
@@ -198,8 +205,9 @@ func comparePathMaps(
 }
 
 // comparePaths compares the paths created by the test with the expected paths
-// and runs t.Fatal in case of finding a difference.
-func comparePaths(expectedPath, createdPath string, t *testing.T) {
+// and runs t.Fatal in case of finding a difference. The ignored paths, relative
+// to expectedPath and createdPath, aren't compared.
+func comparePaths(expectedPath, createdPath string, t *testing.T, ignored ...string) {
 	t.Log("Loading the expected results...")
 	expectedPaths, err := getPathHashes(expectedPath)
 	if err != nil {
@@ -215,10 +223,41 @@ func comparePaths(expectedPath, createdPath string, t *testing.T) {
 			"Created path: %v\nError: %v",
 			createdPath, err)
 	}
+	for _, path := range ignored {
+		delete(expectedPaths, filepath.FromSlash(path))
+		delete(createdPaths, filepath.FromSlash(path))
+	}
 	t.Log("Comparing created and expected paths...")
 	comparePathMaps(expectedPaths, createdPaths, t)
 }
 
+// editedFilesPath is the path to the edited_files.json file relative to the
+// project. The file contains the modification times of the exported files, so
+// it should be compared with compareEditedFilesLists instead of comparePaths.
+const editedFilesPath = ".regolith/cache/edited_files.json"
+
+// compareEditedFilesLists compares the lists of the files exported to each
+// export path in the edited_files.json files of the expected and the created
+// projects and runs t.Fatal in case of finding a difference.
+func compareEditedFilesLists(expectedPath, createdPath string, t *testing.T) {
+	getLists := func(projectPath string) map[string][]string {
+		editedFiles := regolith.LoadEditedFiles(filepath.Join(projectPath, ".regolith"))
+		result := map[string][]string{}
+		for path, files := range editedFiles.Rp {
+			result["rp: "+path] = slices.Sorted(maps.Keys(files))
+		}
+		for path, files := range editedFiles.Bp {
+			result["bp: "+path] = slices.Sorted(maps.Keys(files))
+		}
+		return result
+	}
+	expectedLists, createdLists := getLists(expectedPath), getLists(createdPath)
+	if !maps.EqualFunc(expectedLists, createdLists, slices.Equal) {
+		t.Fatalf("The lists of the exported files are different than expected.\n"+
+			"Expected: %v\nCreated: %v", expectedLists, createdLists)
+	}
+}
+
 // prepareTestDirectory prepares the test directory by removing all of its files
 // or creating it if necessary and returns the path to the directory.
 // Exits with t.Fatal in case of error.
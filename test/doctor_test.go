@@ -0,0 +1,48 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestDoctorChecks tests if the "regolith doctor" checks run all of the
+// checks without modifying the project.
+func TestDoctorChecks(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestDoctorChecks", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	os.Chdir(tmpDir)
+
+	checks := regolith.RunDoctorChecks()
+	results := map[string]string{}
+	for _, check := range checks {
+		switch check.Status {
+		case regolith.DoctorPass, regolith.DoctorWarn, regolith.DoctorFail:
+		default:
+			t.Fatalf("Invalid status of the %q check: %q", check.Name, check.Status)
+		}
+		results[check.Name] = check.Status
+	}
+	expected := []string{
+		"git", "shell", "runner: python", "runner: node",
+		"com.mojang: standard", "project location", ".regolith", "tmp",
+		"session lock",
+	}
+	for _, name := range expected {
+		if _, ok := results[name]; !ok {
+			t.Fatalf("Missing the %q check", name)
+		}
+	}
+	for _, name := range []string{".regolith", "tmp", "session lock"} {
+		if results[name] != regolith.DoctorPass {
+			t.Fatalf("Expected the %q check to pass, got %q", name, results[name])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".regolith")); !os.IsNotExist(err) {
+		t.Fatal("The checks shouldn't create the .regolith directory")
+	}
+}
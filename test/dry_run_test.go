@@ -0,0 +1,266 @@
+package test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestDryRun tests if "regolith run --dry-run" runs the filters without
+// modifying the files of the export target.
+func TestDryRun(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestDryRun", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"hello": {
+					"runWith": "shell",
+					"command": "echo hello > BP/hello.txt"
+				}
+			},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {
+						"target": "local"
+					}
+				},
+				"hello": {
+					"filters": [{"filter": "hello"}],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	// Export the project once and remember the exported files
+	err := regolith.Run("default", []string{}, true, "", false, false, false)
+	if err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	buildPath := filepath.Join(tmpDir, "build")
+	before := listFilesOrFatal(buildPath, t)
+
+	// The dry run of the profile that adds a file shouldn't export it
+	err = regolith.DryRun("hello", []string{}, true, "", false, false, true)
+	if err != nil {
+		t.Fatal("'regolith run --dry-run' failed:", err)
+	}
+	after := listFilesOrFatal(buildPath, t)
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("The dry run modified the export target.\nBefore: %v\nAfter: %v",
+			before, after)
+	}
+}
+
+// TestCompareDirectories tests if the changes found by CompareDirectories
+// match the changes that the export would make.
+func TestCompareDirectories(t *testing.T) {
+	tmpDir := prepareTestDirectory("TestCompareDirectories", t)
+	source := filepath.Join(tmpDir, "source")
+	destination := filepath.Join(tmpDir, "destination")
+	files := map[string]string{
+		"source/same.json":               `{"a": 1}`,
+		"destination/same.json":          `{"a": 1}`,
+		"source/changed.json":            `{"a": 1}`,
+		"destination/changed.json":       `{"a": 2}`,
+		"source/nested/added.txt":        "added",
+		"destination/nested/deleted.txt": "deleted",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal("Unable to create the directory:", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal("Unable to write the file:", err)
+		}
+	}
+
+	changes, err := regolith.CompareDirectories(source, destination)
+	if err != nil {
+		t.Fatal("Unable to compare the directories:", err)
+	}
+	expected := []regolith.FileChange{
+		{Path: "changed.json", Kind: regolith.FileModified},
+		{Path: "nested/added.txt", Kind: regolith.FileAdded},
+		{Path: "nested/deleted.txt", Kind: regolith.FileDeleted},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Unexpected changes.\nExpected: %v\nActual: %v", expected, changes)
+	}
+
+	// A missing destination means that all files would be added
+	changes, err = regolith.CompareDirectories(
+		source, filepath.Join(tmpDir, "missing"))
+	if err != nil {
+		t.Fatal("Unable to compare the directories:", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 added files, got: %v", changes)
+	}
+}
+
+// listFilesOrFatal returns the relative paths and contents of all
+// files in the directory. It stops the test on failure.
+func listFilesOrFatal(root string, t *testing.T) map[string]string {
+	result := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(root, path)
+		result[filepath.ToSlash(relPath)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Unable to list the files:", err)
+	}
+	return result
+}
+
+// captureStdoutOrFatal returns the text printed to the standard output by
+// the function.
+func captureStdoutOrFatal(f func() error, t *testing.T) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal("Unable to create a pipe:", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- data
+	}()
+	err = f()
+	os.Stdout = stdout
+	writer.Close()
+	result := string(<-output)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// TestDryRunServer tests if "regolith run --dry-run" shows the changes of
+// the files of the server that would register the packs of the "server"
+// export target, without modifying them.
+func TestDryRunServer(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestDryRunServer", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {"target": "server", "serverPath": "../server"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(workingDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+
+	// Prepare the server with a world that already has a pack
+	serverPath := filepath.Join(tmpDir, "server")
+	worldPath := filepath.Join(serverPath, "worlds", "My world")
+	if err := os.MkdirAll(worldPath, 0755); err != nil {
+		t.Fatal("Unable to create the world:", err)
+	}
+	worldPacksPath := filepath.Join(worldPath, "world_behavior_packs.json")
+	worldPacks := `[{"pack_id": "00000000-0000-0000-0000-000000000000", "version": [2, 0, 0]}]`
+	files := map[string]string{
+		filepath.Join(serverPath, "server.properties"): "level-name=My world\n",
+		worldPacksPath: worldPacks,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal("Unable to write the server file:", err)
+		}
+	}
+	os.Chdir(workingDir)
+
+	output := captureStdoutOrFatal(func() error {
+		return regolith.DryRun("default", []string{}, true, "", false, false, true)
+	}, t)
+	// The server path of the export target is relative to the working
+	// directory
+	relServerPath := filepath.Join("..", "server")
+	relWorldPath := filepath.Join(relServerPath, "worlds", "My world")
+	for _, expected := range []string{
+		"Server pack registration: " +
+			filepath.Join(relWorldPath, "world_behavior_packs.json") +
+			"\n  The file would be updated",
+		"Server pack registration: " +
+			filepath.Join(relWorldPath, "world_resource_packs.json") +
+			"\n  The file would be created",
+		"Server pack registration: " +
+			filepath.Join(relServerPath, "valid_known_packs.json") +
+			"\n  The file would be created",
+		`+		"pack_id": "96b53fd2-b7a1-4d26-b74f-1b9394c8d0bc",`,
+		`+		"path": "development_resource_packs/regolith_test_project_rp",`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("The dry run output doesn't contain %q:\n%s", expected, output)
+		}
+	}
+	if strings.Count(output, "Server pack registration: "+
+		filepath.Join(relServerPath, "valid_known_packs.json")) != 1 {
+		t.Fatalf("The changes of valid_known_packs.json are shown more than once:\n%s", output)
+	}
+
+	// The server files are unchanged
+	content, err := os.ReadFile(worldPacksPath)
+	if err != nil || string(content) != worldPacks {
+		t.Fatalf("The dry run modified world_behavior_packs.json: %q, %v", content, err)
+	}
+	for _, path := range []string{
+		filepath.Join(worldPath, "world_resource_packs.json"),
+		filepath.Join(serverPath, "valid_known_packs.json"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("The dry run created %q", path)
+		}
+	}
+}
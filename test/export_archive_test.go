@@ -0,0 +1,176 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// readArchiveNamesOrFatal returns the names of the entries of the zip archive
+// or exits with t.Fatal in case of error.
+func readArchiveNamesOrFatal(path string, t *testing.T) []string {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Unable to open archive %q: %v", path, err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// TestPackageMcaddon tests if the "regolith package" command creates a
+// reproducible .mcaddon file with both packs.
+func TestPackageMcaddon(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestPackageMcaddon", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	os.Chdir(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "build", "regolith_test_project.mcaddon")
+	if err := regolith.Package("dev", "mcaddon", "", true, ""); err != nil {
+		t.Fatal("'regolith package' failed:", err)
+	}
+	first, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal("Unable to read the created archive:", err)
+	}
+	expectedNames := []string{
+		"regolith_test_project_bp/",
+		"regolith_test_project_bp/manifest.json",
+		"regolith_test_project_rp/",
+		"regolith_test_project_rp/manifest.json",
+	}
+	names := readArchiveNamesOrFatal(archivePath, t)
+	if !slices.Equal(names, expectedNames) {
+		t.Fatalf("Unexpected archive entries.\nExpected: %v\nGot: %v", expectedNames, names)
+	}
+
+	// Touch the source files, the archive should stay the same
+	manifestPath := filepath.Join(tmpDir, "packs", "BP", "manifest.json")
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("Unable to read the manifest:", err)
+	}
+	if err := os.WriteFile(manifestPath, content, 0644); err != nil {
+		t.Fatal("Unable to write the manifest:", err)
+	}
+	if err := regolith.Package("dev", "mcaddon", "", true, ""); err != nil {
+		t.Fatal("Second 'regolith package' failed:", err)
+	}
+	second, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal("Unable to read the created archive:", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("Packaging the same files twice produced different archives")
+	}
+}
+
+// TestMcpackExportTarget tests if the "mcpack" export target creates a
+// separate archive for each pack at the path created from the template.
+func TestMcpackExportTarget(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestMcpackExportTarget", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"profiles": {
+				"release": {
+					"filters": [],
+					"export": {
+						"target": "mcpack",
+						"path": "dist/{packName}-{name}.mcpack"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	if err := regolith.Run("release", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	for _, name := range []string{"regolith_test_project_bp", "regolith_test_project_rp"} {
+		archivePath := filepath.Join(tmpDir, "dist", name+"-regolith_test_project.mcpack")
+		names := readArchiveNamesOrFatal(archivePath, t)
+		if !slices.Equal(names, []string{"manifest.json"}) {
+			t.Fatalf("Unexpected entries of %q: %v", archivePath, names)
+		}
+	}
+}
+
+// TestArchiveSamePackNames tests if the "mcaddon" export target stores the
+// packs with the same names in separate folders and if the "mcpack" export
+// target refuses to write both packs to the same file.
+func TestArchiveSamePackNames(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestArchiveSamePackNames", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"profiles": {
+				"addon": {
+					"filters": [],
+					"export": {"target": "mcaddon", "bpName": "'addon'", "rpName": "'addon'"}
+				},
+				"packs": {
+					"filters": [],
+					"export": {"target": "mcpack", "bpName": "'addon'", "rpName": "'addon'"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	if err := regolith.Run("addon", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	expectedNames := []string{
+		"addon_bp/",
+		"addon_bp/manifest.json",
+		"addon_rp/",
+		"addon_rp/manifest.json",
+	}
+	archivePath := filepath.Join(tmpDir, "build", "regolith_test_project.mcaddon")
+	names := readArchiveNamesOrFatal(archivePath, t)
+	if !slices.Equal(names, expectedNames) {
+		t.Fatalf("Unexpected archive entries.\nExpected: %v\nGot: %v", expectedNames, names)
+	}
+
+	if err := regolith.Run("packs", []string{}, true, "", false, false, false); err == nil {
+		t.Fatal("Expected 'regolith run' to fail because both packs use the same archive")
+	}
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestServerExportTarget tests if the "server" export target exports the
+// packs to the dedicated server and registers them in the world of the
+// server and in the valid_known_packs.json file.
+func TestServerExportTarget(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestServerExportTarget", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {},
+			"profiles": {
+				"development": {
+					"filters": [],
+					"export": {"target": "server", "serverPath": "../server"}
+				},
+				"world": {
+					"filters": [],
+					"export": {"target": "server", "serverPath": "../server", "worldName": "Other world"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(workingDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+
+	// Prepare the server with a world that already has some packs
+	const bpUuid = "96b53fd2-b7a1-4d26-b74f-1b9394c8d0bc"
+	const rpUuid = "6f6e3f0b-1627-488d-a9aa-2d1430ba368a"
+	serverPath := filepath.Join(tmpDir, "server")
+	worldPath := filepath.Join(serverPath, "worlds", "My world")
+	if err := os.MkdirAll(worldPath, 0755); err != nil {
+		t.Fatal("Unable to create the world:", err)
+	}
+	files := map[string]string{
+		filepath.Join(serverPath, "server.properties"): "server-name=Test\nlevel-name=My world\n",
+		filepath.Join(worldPath, "world_behavior_packs.json"): `[
+			{"pack_id": "00000000-0000-0000-0000-000000000000", "version": [2, 0, 0]},
+			{"pack_id": "` + bpUuid + `", "version": [0, 1, 0]}
+		]`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal("Unable to write the server file:", err)
+		}
+	}
+	os.Chdir(workingDir)
+
+	// Export to the development packs of the server twice. The second run
+	// shouldn't add the packs again.
+	for range 2 {
+		if err := regolith.Run("development", []string{}, true, "", false, false, false); err != nil {
+			t.Fatal("'regolith run' failed:", err)
+		}
+	}
+	comparePaths(
+		filepath.Join(workingDir, "packs", "BP"),
+		filepath.Join(serverPath, "development_behavior_packs", "regolith_test_project_bp"), t)
+	comparePaths(
+		filepath.Join(workingDir, "packs", "RP"),
+		filepath.Join(serverPath, "development_resource_packs", "regolith_test_project_rp"), t)
+
+	var worldPacks []map[string]any
+	readJsonOrFatal(filepath.Join(worldPath, "world_behavior_packs.json"), &worldPacks, t)
+	if len(worldPacks) != 2 || worldPacks[1]["pack_id"] != bpUuid ||
+		!reflect.DeepEqual(worldPacks[1]["version"], []any{1.0, 0.0, 0.0}) {
+		t.Fatalf("Unexpected world_behavior_packs.json: %v", worldPacks)
+	}
+	readJsonOrFatal(filepath.Join(worldPath, "world_resource_packs.json"), &worldPacks, t)
+	if len(worldPacks) != 1 || worldPacks[0]["pack_id"] != rpUuid {
+		t.Fatalf("Unexpected world_resource_packs.json: %v", worldPacks)
+	}
+	var knownPacks []map[string]any
+	readJsonOrFatal(filepath.Join(serverPath, "valid_known_packs.json"), &knownPacks, t)
+	if len(knownPacks) != 3 || knownPacks[0]["file_version"] != 2.0 ||
+		knownPacks[1]["path"] != "development_behavior_packs/regolith_test_project_bp" ||
+		knownPacks[1]["uuid"] != bpUuid || knownPacks[1]["version"] != "1.0.0" ||
+		knownPacks[2]["uuid"] != rpUuid {
+		t.Fatalf("Unexpected valid_known_packs.json: %v", knownPacks)
+	}
+
+	// Export to the packs of a world
+	if err := regolith.Run("world", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	otherWorldPath := filepath.Join(serverPath, "worlds", "Other world")
+	comparePaths(
+		filepath.Join(workingDir, "packs", "BP"),
+		filepath.Join(otherWorldPath, "behavior_packs", "regolith_test_project_bp"), t)
+	readJsonOrFatal(filepath.Join(otherWorldPath, "world_behavior_packs.json"), &worldPacks, t)
+	if len(worldPacks) != 1 || worldPacks[0]["pack_id"] != bpUuid {
+		t.Fatalf("Unexpected world_behavior_packs.json of the other world: %v", worldPacks)
+	}
+
+	// Change the UUID of the BP. The entries of the old UUID are replaced.
+	const newBpUuid = "11111111-2222-3333-4444-555555555555"
+	manifestPath := filepath.Join(workingDir, "packs", "BP", "manifest.json")
+	manifest, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal("Unable to read the manifest:", err)
+	}
+	manifest = []byte(strings.ReplaceAll(string(manifest), bpUuid, newBpUuid))
+	if err := os.WriteFile(manifestPath, manifest, 0644); err != nil {
+		t.Fatal("Unable to write the manifest:", err)
+	}
+	if err := regolith.Run("development", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	readJsonOrFatal(filepath.Join(worldPath, "world_behavior_packs.json"), &worldPacks, t)
+	if len(worldPacks) != 2 || worldPacks[1]["pack_id"] != newBpUuid {
+		t.Fatalf("Unexpected world_behavior_packs.json after changing the UUID: %v", worldPacks)
+	}
+	readJsonOrFatal(filepath.Join(serverPath, "valid_known_packs.json"), &knownPacks, t)
+	if len(knownPacks) != 3 || knownPacks[1]["uuid"] != newBpUuid ||
+		knownPacks[2]["uuid"] != rpUuid {
+		t.Fatalf("Unexpected valid_known_packs.json after changing the UUID: %v", knownPacks)
+	}
+}
+
+// TestServerExportRevert tests if a failed registration of the packs in the
+// world of the server restores the server files that were already changed.
+func TestServerExportRevert(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestServerExportRevert", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {"target": "server", "serverPath": "../server"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(workingDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	// The world files can be written, but valid_known_packs.json can't be
+	// read, because it's a directory.
+	serverPath := filepath.Join(tmpDir, "server")
+	worldPath := filepath.Join(serverPath, "worlds", "Bedrock level")
+	for _, path := range []string{
+		worldPath, filepath.Join(serverPath, "valid_known_packs.json"),
+	} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal("Unable to create the server directories:", err)
+		}
+	}
+	worldPacksPath := filepath.Join(worldPath, "world_behavior_packs.json")
+	worldPacks := `[{"pack_id": "00000000-0000-0000-0000-000000000000", "version": [2, 0, 0]}]`
+	if err := os.WriteFile(worldPacksPath, []byte(worldPacks), 0644); err != nil {
+		t.Fatal("Unable to write the server file:", err)
+	}
+	os.Chdir(workingDir)
+
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err == nil {
+		t.Fatal("Expected 'regolith run' to fail")
+	}
+	data, err := os.ReadFile(worldPacksPath)
+	if err != nil || string(data) != worldPacks {
+		t.Fatalf("The world_behavior_packs.json file wasn't restored: %s", data)
+	}
+}
@@ -183,6 +183,7 @@ func TestProfileFromObject_SingleExportSetsExportTarget(t *testing.T) {
 			},
 		},
 		map[string]regolith.FilterInstaller{},
+		nil,
 	)
 	if err != nil {
 		t.Fatal(err)
@@ -99,3 +99,88 @@ func TestTriggerFileProtection(t *testing.T) {
 		t.Fatal("Expected RunProfile to fail on second attempt to export to A")
 	}
 }
+
+// TestModifiedFileProtection tests if the file protection system will get
+// triggered when a file exported by Regolith was modified outside of
+// Regolith, and if the --backup flag backs up the modified file before
+// exporting. It performs the following:
+// 1. Runs Regolith to export something to a target directory.
+// 2. Modifies an exported file in the target directory.
+// 3. Runs Regolith to export again, expects failure.
+// 4. Runs Regolith with the backup option, expects the file to be backed up.
+func TestModifiedFileProtection(t *testing.T) {
+	// Switch to current working directory at the end of the test
+	defer os.Chdir(getWdOrFatal(t))
+	// TEST PREPARATION
+	t.Log("Clearing the testing directory...")
+	tmpDir := prepareTestDirectory("TestModifiedFileProtection", t)
+
+	t.Log("Copying the project files into the testing directory...")
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(multitargetProjectPath, workingDir, t)
+
+	// Switch to the working directory
+	os.Chdir(workingDir)
+
+	// THE TEST
+	// 1. Run Regolith (export to A)
+	t.Log("Running Regolith...")
+	err := regolith.Run("exact_export_A", []string{}, true, "", false, false, false)
+	if err != nil {
+		t.Fatal("Unable RunProfile failed on first attempt to export to A:", err)
+	}
+
+	// 2. Modify an exported file (simulate user action).
+	t.Log("Modifying an exported file (simulating user action)...")
+	modifiedFile := filepath.Join(tmpDir, "target-a/BP/manifest.json")
+	modifiedContent := []byte(`{"modified": true}`)
+	if err := os.WriteFile(modifiedFile, modifiedContent, 0644); err != nil {
+		t.Fatal("Unable to modify the exported file:", err)
+	}
+
+	// 3. Run Regolith (export to A), expect failure.
+	t.Log("Running Regolith (this should be stopped by file protection system)...")
+	err = regolith.Run("exact_export_A", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected RunProfile to fail on second attempt to export to A")
+	}
+	if content, _ := os.ReadFile(modifiedFile); string(content) != string(modifiedContent) {
+		t.Fatal("The modified file was overwritten")
+	}
+
+	// 4. Run Regolith with the backup option, expect the modified file in
+	// the backup.
+	t.Log("Running Regolith with the backup option...")
+	err = regolith.RunWithReport(
+		"exact_export_A", []string{}, true, "", false, false, false, true, "", "")
+	if err != nil {
+		t.Fatal("Unable RunProfile failed on attempt to export to A with backup:", err)
+	}
+	backups, err := filepath.Glob(
+		filepath.Join(workingDir, ".regolith", "backups", "*", "1", "BP", "manifest.json"))
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Expected one backup of the modified file, found: %v", backups)
+	}
+	if content, _ := os.ReadFile(backups[0]); string(content) != string(modifiedContent) {
+		t.Fatalf("Unexpected content of the backup: %s", content)
+	}
+	comparePaths(
+		filepath.Join(workingDir, "packs", "BP"), filepath.Join(tmpDir, "target-a", "BP"), t)
+
+	// 5. Modify the file and run Regolith with the backup option again,
+	// expect a separate backup even if the runs started in the same second.
+	t.Log("Running Regolith with the backup option again...")
+	if err := os.WriteFile(modifiedFile, modifiedContent, 0644); err != nil {
+		t.Fatal("Unable to modify the exported file:", err)
+	}
+	err = regolith.RunWithReport(
+		"exact_export_A", []string{}, true, "", false, false, false, true, "", "")
+	if err != nil {
+		t.Fatal("Unable RunProfile failed on second attempt to export to A with backup:", err)
+	}
+	backups, err = filepath.Glob(
+		filepath.Join(workingDir, ".regolith", "backups", "*", "1", "BP", "manifest.json"))
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("Expected two backups of the modified file, found: %v", backups)
+	}
+}
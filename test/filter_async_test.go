@@ -3,6 +3,7 @@ package test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,3 +43,216 @@ func TestAsyncFilter(t *testing.T) {
 	t.Log("Evaluating the test results...")
 	comparePaths(expectedBuildResult, filepath.Join(tmpDir, "build"), t)
 }
+
+// TestIsolatedAsyncFilter tests if the isolated async filter merges the
+// changes of its subfilters and reports the conflicts between them.
+func TestIsolatedAsyncFilter(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestIsolatedAsyncFilter", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"write_a": {"runWith": "shell", "command": "echo a > BP/a.txt"},
+				"write_b": {"runWith": "shell", "command": "echo b > BP/a.txt"},
+				"write_c": {"runWith": "shell", "command": "echo c > BP/c.txt"},
+				"write_c_again": {"runWith": "shell", "command": "echo c > BP/c.txt"},
+				"remove_manifest": {"runWith": "shell", "command": "rm RP/manifest.json"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{
+							"isolated": true,
+							"asyncFilters": [
+								{"filter": "write_a"},
+								{"filter": "write_c"},
+								{"filter": "write_c_again"},
+								{"filter": "remove_manifest"}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"conflict": {
+					"filters": [
+						{
+							"isolated": true,
+							"asyncFilters": [
+								{"filter": "write_a"},
+								{"filter": "write_b"}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	// The changes of all subfilters are merged
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	build := filepath.Join(tmpDir, "build")
+	for _, path := range []string{"regolith_test_project_bp/a.txt", "regolith_test_project_bp/c.txt"} {
+		if _, err := os.Stat(filepath.Join(build, path)); err != nil {
+			t.Fatalf("The file %q created by a subfilter is missing", path)
+		}
+	}
+	removed := filepath.Join(build, "regolith_test_project_rp", "manifest.json")
+	if _, err := os.Stat(removed); !os.IsNotExist(err) {
+		t.Fatal("The file removed by a subfilter wasn't removed")
+	}
+
+	// Writing different content to the same file is a conflict
+	err := regolith.Run("conflict", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of a conflict")
+	}
+	if !strings.Contains(err.Error(), "BP/a.txt (write_a, write_b)") {
+		t.Fatal("The error doesn't describe the conflict:", err)
+	}
+}
+
+// TestAsyncFilterDependencies tests if the subfilters of the async filter
+// wait for the subfilters listed in their "after" property and if the
+// dependency cycles are rejected.
+func TestAsyncFilterDependencies(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestAsyncFilterDependencies", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"write_a": {"runWith": "shell", "command": "sleep 1 && echo a > BP/a.txt"},
+				"copy_a": {"runWith": "shell", "command": "cp BP/a.txt BP/b.txt"},
+				"overwrite_a": {"runWith": "shell", "command": "echo c > BP/a.txt"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{
+							"maxParallel": 1,
+							"asyncFilters": [
+								{"filter": "copy_a", "after": ["write_a"]},
+								{"filter": "write_a"}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"isolated": {
+					"filters": [
+						{
+							"isolated": true,
+							"asyncFilters": [
+								{"filter": "overwrite_a", "after": ["copy_a"]},
+								{"filter": "copy_a", "after": ["write_a"]},
+								{"filter": "write_a"}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"cycle": {
+					"filters": [
+						{
+							"asyncFilters": [
+								{"filter": "write_a", "after": ["copy_a"]},
+								{"filter": "copy_a", "after": ["write_a"]}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"selfCycle": {
+					"filters": [
+						{
+							"asyncFilters": [
+								{"filter": "write_a", "after": ["write_a"]},
+								{"filter": "copy_a"}
+							]
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+	bp := filepath.Join(tmpDir, "build", "regolith_test_project_bp")
+
+	// "copy_a" fails if it runs before "write_a"
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	if _, err := os.Stat(filepath.Join(bp, "b.txt")); err != nil {
+		t.Fatal("The file copied by the dependent subfilter is missing")
+	}
+
+	// The isolated subfilters see the changes of their dependencies and can
+	// overwrite them without conflicts
+	if err := regolith.Run("isolated", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	content, err := os.ReadFile(filepath.Join(bp, "a.txt"))
+	if err != nil || strings.TrimSpace(string(content)) != "c" {
+		t.Fatalf("Unexpected content of the overwritten file: %q, %v", content, err)
+	}
+
+	// The dependency cycles are rejected
+	err = regolith.Run("cycle", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of a dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "write_a -> copy_a -> write_a") {
+		t.Fatal("The error doesn't describe the cycle:", err)
+	}
+
+	// A subfilter that waits for itself is a cycle too
+	err = regolith.Run("selfCycle", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of a dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "write_a -> write_a") {
+		t.Fatal("The error doesn't describe the cycle:", err)
+	}
+}
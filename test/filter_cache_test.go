@@ -0,0 +1,145 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestFilterCache tests if the filters with the "cache" property enabled
+// are skipped when their inputs don't change and if the cached output is
+// exported instead.
+func TestFilterCache(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFilterCache", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"count_runs": {
+					"runWith": "shell",
+					"command": "echo run >> ../../runs.txt; echo generated > BP/generated.txt"
+				}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{
+							"filter": "count_runs",
+							"cache": true
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	countRuns := func() int {
+		content, err := os.ReadFile(filepath.Join(tmpDir, "runs.txt"))
+		if err != nil {
+			t.Fatal("Unable to read the runs counter:", err)
+		}
+		return strings.Count(string(content), "run")
+	}
+	generatedPath := filepath.Join(
+		tmpDir, "build", "regolith_test_project_bp", "generated.txt")
+
+	t.Log("Running Regolith for the first time...")
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	if runs := countRuns(); runs != 1 {
+		t.Fatalf("Expected the filter to run once, got %d runs", runs)
+	}
+
+	t.Log("Running Regolith with unchanged inputs...")
+	if err := os.RemoveAll(filepath.Join(tmpDir, "build")); err != nil {
+		t.Fatal("Unable to remove the build directory:", err)
+	}
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	if runs := countRuns(); runs != 1 {
+		t.Fatalf("Expected the cached output to be used, got %d runs", runs)
+	}
+	if _, err := os.Stat(generatedPath); err != nil {
+		t.Fatal("The cached output of the filter wasn't exported:", err)
+	}
+
+	t.Log("Running Regolith with changed inputs...")
+	newFilePath := filepath.Join(tmpDir, "packs", "BP", "new_file.json")
+	if err := os.WriteFile(newFilePath, []byte("{}"), 0644); err != nil {
+		t.Fatal("Unable to add a file to the behavior pack:", err)
+	}
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	if runs := countRuns(); runs != 2 {
+		t.Fatalf("Expected the filter to run again, got %d runs", runs)
+	}
+}
+
+// TestFilterCacheUnsupported tests if the "cache" property is rejected when
+// it's used with the filters that can't be cached.
+func TestFilterCacheUnsupported(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFilterCacheUnsupported", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	os.Chdir(tmpDir)
+
+	for _, filter := range []string{
+		`{"profile": "other", "cache": true}`,
+		`{"asyncFilters": [{"filter": "hello"}], "cache": true}`,
+		`{"asyncFilters": [{"filter": "hello", "cache": true}]}`,
+	} {
+		config := []byte(`{
+			"name": "regolith_test_project",
+			"author": "Bedrock-OSS",
+			"packs": {
+				"behaviorPack": "./packs/BP",
+				"resourcePack": "./packs/RP"
+			},
+			"regolith": {
+				"formatVersion": "1.4.0",
+				"filterDefinitions": {
+					"hello": {"runWith": "shell", "command": "echo hello"}
+				},
+				"profiles": {
+					"default": {"filters": [` + filter + `], "export": {"target": "local"}},
+					"other": {"filters": [], "export": {"target": "local"}}
+				},
+				"dataPath": "./packs/data"
+			}
+		}`)
+		if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+			t.Fatal("Unable to write config:", err)
+		}
+		err := regolith.Run("default", []string{}, true, "", false, false, false)
+		if err == nil {
+			t.Fatalf("Expected 'regolith run' to fail with filter %s", filter)
+		}
+		if !strings.Contains(err.Error(), "The \"cache\" property can't be used") {
+			t.Fatalf("Unexpected error with filter %s:\n%s", filter, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestParseFilterEvent tests if the lines printed by the filters are
+// correctly recognized and parsed as filter events.
+func TestParseFilterEvent(t *testing.T) {
+	// Regular output
+	_, isEvent, err := regolith.ParseFilterEvent("Hello world")
+	if isEvent || err != nil {
+		t.Fatalf("Regular line recognized as an event (error: %v)", err)
+	}
+
+	// Valid events
+	event, isEvent, err := regolith.ParseFilterEvent(
+		regolith.FilterEventPrefix +
+			`{"type": "warning", "message": "Bad", "file": "BP/a.json", "line": 3}`)
+	if !isEvent || err != nil {
+		t.Fatalf("Failed to parse a warning event (error: %v)", err)
+	}
+	if event.Type != regolith.FilterEventWarning || event.File != "BP/a.json" ||
+		event.Line != 3 || event.Message != "Bad" {
+		t.Fatalf("Unexpected warning event: %+v", event)
+	}
+	event, _, err = regolith.ParseFilterEvent(
+		regolith.FilterEventPrefix + `{"type": "log", "message": "Text"}`)
+	if err != nil {
+		t.Fatal("Failed to parse a log event:", err)
+	}
+	if event.Level != "info" {
+		t.Fatalf("Expected the default log level to be \"info\", got %q", event.Level)
+	}
+
+	// Invalid events
+	invalid := []string{
+		`{"type": "log", "level": "verbose"}`,
+		`{"type": "progress"}`,
+		`{"type": "unknown"}`,
+		`not json`,
+	}
+	for _, data := range invalid {
+		_, isEvent, err := regolith.ParseFilterEvent(regolith.FilterEventPrefix + data)
+		if !isEvent || err == nil {
+			t.Fatalf("Expected an error for the event: %s", data)
+		}
+	}
+}
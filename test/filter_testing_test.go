@@ -0,0 +1,104 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestFilterTesting tests if "regolith test-filter" compares the output of
+// the filter with the expected output and if the "--update" flag replaces
+// the expected output.
+func TestFilterTesting(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFilterTesting", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"hello": {
+					"runWith": "shell",
+					"command": "echo hello > BP/hello.txt"
+				}
+			},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	files := map[string]string{
+		"config.json": string(config),
+		"filter_tests/hello/passing/input/BP/keep.txt":     "keep",
+		"filter_tests/hello/passing/expected/BP/keep.txt":  "keep",
+		"filter_tests/hello/passing/expected/BP/hello.txt": "hello\n",
+		"filter_tests/hello/failing/input/RP/keep.txt":     "keep",
+		"filter_tests/hello/failing/expected/BP/hello.txt": "goodbye\n",
+		// The files of the last run of the project
+		".regolith/tmp/BP/previous.txt": "previous",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal("Unable to create the directory:", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal("Unable to write the file:", err)
+		}
+	}
+	os.Chdir(tmpDir)
+
+	// The "failing" test case expects a different output and a missing file
+	err := regolith.TestFilter("hello", []string{}, "", false, true, "")
+	if err == nil {
+		t.Fatal("Expected 'regolith test-filter' to fail")
+	}
+	if !strings.Contains(err.Error(), "1 of 2 test cases failed: failing") {
+		t.Fatal("Unexpected error message:", err)
+	}
+
+	// Update the expected output and run the tests again
+	err = regolith.TestFilter("hello", []string{}, "", true, true, "")
+	if err != nil {
+		t.Fatal("'regolith test-filter --update' failed:", err)
+	}
+	content, err := os.ReadFile(
+		filepath.Join("filter_tests", "hello", "failing", "expected", "RP", "keep.txt"))
+	if err != nil || string(content) != "keep" {
+		t.Fatal("The expected output wasn't updated:", err)
+	}
+	err = regolith.TestFilter("hello", []string{}, "", false, true, "")
+	if err != nil {
+		t.Fatal("'regolith test-filter' failed after the update:", err)
+	}
+
+	// The source files of the project shouldn't be modified
+	if _, err := os.Stat(filepath.Join("packs", "BP", "hello.txt")); !os.IsNotExist(err) {
+		t.Fatal("The filter test modified the project files")
+	}
+	// The tests run in their own working directories, which are removed
+	content, err = os.ReadFile(filepath.Join(".regolith", "tmp", "BP", "previous.txt"))
+	if err != nil || string(content) != "previous" {
+		t.Fatal("The filter test modified the tmp directory:", err)
+	}
+	workingDirs, err := filepath.Glob(filepath.Join(".regolith", "filter-test-*"))
+	if err != nil || len(workingDirs) != 0 {
+		t.Fatalf("The working directories of the tests weren't removed: %v", workingDirs)
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestFilterTimeout tests if a filter that runs longer than its timeout is
+// stopped and if Regolith reports a clear error.
+func TestFilterTimeout(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFilterTimeout", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"hang": {
+					"runWith": "shell",
+					"command": "sleep 30",
+					"timeout": "1m"
+				}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{
+							"filter": "hang",
+							"timeout": "500ms"
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	start := time.Now()
+	err := regolith.Run("default", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("The filter wasn't stopped after the timeout, run took %s", elapsed)
+	}
+	if !strings.Contains(err.Error(), "didn't finish in 500ms") {
+		t.Fatal("Unexpected error message:", err)
+	}
+}
+
+// TestFilterTimeoutJSON tests if the timeout of a filter definition is saved
+// to JSON in the same format as it's read from the config file.
+func TestFilterTimeoutJSON(t *testing.T) {
+	obj := map[string]any{"runWith": "shell", "command": "echo a", "timeout": "1m30s"}
+	definition, err := regolith.FilterInstallerFromObject("hello", "", obj)
+	if err != nil {
+		t.Fatal("Unable to parse the filter definition:", err)
+	}
+	data, err := json.Marshal(definition)
+	if err != nil {
+		t.Fatal("Unable to marshal the filter definition:", err)
+	}
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal("Unable to unmarshal the filter definition:", err)
+	}
+	if result["timeout"] != "1m30s" {
+		t.Fatalf("Unexpected timeout in the JSON of the filter definition: %s", data)
+	}
+	result["runWith"] = "shell"
+	definition, err = regolith.FilterInstallerFromObject("hello", "", result)
+	if err != nil {
+		t.Fatal("Unable to parse the marshaled filter definition:", err)
+	}
+	if definition.GetTimeout() != 90*time.Second {
+		t.Fatal("Unexpected timeout of the filter definition:", definition.GetTimeout())
+	}
+}
+
+// TestFilterBackgroundProcess tests if Regolith doesn't wait for a
+// background process started by a filter, which keeps the output of the
+// filter open after the filter exits.
+func TestFilterBackgroundProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test uses the shell syntax of Unix")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFilterBackgroundProcess", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"daemon": {"runWith": "shell", "command": "sleep 30 & echo started"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [{"filter": "daemon"}],
+					"export": {"target": "local"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	start := time.Now()
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Fatalf("Regolith waited for the background process, run took %s", elapsed)
+	}
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestJsoncConfig tests if the config.json file can contain comments and
+// trailing commas, and if the errors point to the location of the invalid
+// value in the file.
+func TestJsoncConfig(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestJsoncConfig", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		// The name of the project
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		/* The paths to the packs,
+		   relative to the project */
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP", // "/*" in a string is not a comment
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {
+						"target": "local",
+					},
+				},
+			},
+			"dataPath": "./packs/data",
+		},
+	}`)
+	configPath := filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(configPath, config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+
+	// The errors of ConfigFromObject point to the invalid value
+	invalid := strings.Replace(
+		string(config), `"filters": [],`, `"filters": [42],`, 1)
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	err := regolith.Run("default", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of an invalid filter")
+	}
+	if !strings.Contains(err.Error(), "config.json:16:18") {
+		t.Fatal("The error doesn't contain the location of the invalid value:", err)
+	}
+
+	// The syntax errors point to the invalid character
+	invalid = strings.Replace(
+		string(config), `"author": "Bedrock-OSS",`, `"author": "Bedrock-OSS";`, 1)
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	err = regolith.Run("default", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of a syntax error")
+	}
+	if !strings.Contains(err.Error(), "config.json:4:26") {
+		t.Fatal("The error doesn't contain the location of the syntax error:", err)
+	}
+
+	// The unterminated block comments are syntax errors
+	invalid = string(config) + "\n/* comment"
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	err = regolith.Run("default", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail because of an unterminated comment")
+	}
+	location := fmt.Sprintf("config.json:%d:1", strings.Count(invalid, "\n")+1)
+	if !strings.Contains(err.Error(), location) {
+		t.Fatal("The error doesn't contain the location of the comment:", err)
+	}
+
+	// ConfigFromObject doesn't read the config.json file of the working
+	// directory to locate the errors, because the object doesn't have to come
+	// from that file
+	_, err = regolith.ConfigFromObject(map[string]any{
+		"name": "regolith_test_project", "author": "Bedrock-OSS", "packs": 42})
+	if err == nil {
+		t.Fatal("Expected ConfigFromObject to fail because of invalid packs")
+	}
+	if strings.Contains(err.Error(), "config.json:") {
+		t.Fatal("The error of ConfigFromObject points to config.json:", err)
+	}
+}
@@ -66,7 +66,7 @@ func TestLocalRequirementsInstallAndRun(t *testing.T) {
 
 	// THE TEST
 	t.Log("Testing the 'regolith install-all' command...")
-	err := regolith.InstallAll(false, false, true, false, "")
+	err := regolith.InstallAll(false, false, true, false, false, "")
 	if err != nil {
 		t.Fatal("'regolith install-all' failed", err.Error())
 	}
@@ -103,6 +103,34 @@ func TestExeFilterRun(t *testing.T) {
 	comparePaths(expectedBuildResult, filepath.Join(tmpDir, "build"), t)
 }
 
+// TestWasmFilterRun tests if the WebAssembly filter runs the module with the
+// tmp directory pre-opened and with the settings and arguments of the filter.
+func TestWasmFilterRun(t *testing.T) {
+	// Switch to current working directory at the end of the test
+	defer os.Chdir(getWdOrFatal(t))
+	// TEST PREPARATION
+	t.Log("Clearing the testing directory...")
+	tmpDir := prepareTestDirectory("TestWasmFilterRun", t)
+
+	t.Log("Copying the project files into the testing directory...")
+	project := absOrFatal(filepath.Join(wasmFilterPath, "project"), t)
+	copyFilesOrFatal(project, tmpDir, t)
+
+	// Load abs path of the expected result and switch to the working directory
+	expectedBuildResult := absOrFatal(
+		filepath.Join(wasmFilterPath, "expected_build_result"), t)
+	os.Chdir(tmpDir)
+
+	// THE TEST
+	t.Log("Testing the 'regolith run' command...")
+	if err := regolith.Run("dev", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err.Error())
+	}
+	// TEST EVALUATION
+	t.Log("Evaluating the test results...")
+	comparePaths(expectedBuildResult, filepath.Join(tmpDir, "build"), t)
+}
+
 // TestProfileFilterRun tests valid and invalid profile filters. The invalid
 // profile filter has circular dependencies and should fail, the valid profile
 // filter runs the same exe file as the TestExeFilterRun test.
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestLockFileRoundTrip tests if the lock file can be saved and loaded back
+// without losing any information.
+func TestLockFileRoundTrip(t *testing.T) {
+	tmpDir := prepareTestDirectory("TestLockFileRoundTrip", t)
+	lockPath := filepath.Join(tmpDir, regolith.LockFilePath)
+
+	// Missing lock file loads as empty and isn't created when empty
+	lockFile, err := regolith.LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal("Unable to load a missing lock file:", err)
+	}
+	if len(lockFile.Filters) != 0 {
+		t.Fatal("Expected an empty lock file, got:", lockFile.Filters)
+	}
+	if err := lockFile.Dump(lockPath); err != nil {
+		t.Fatal("Unable to save an empty lock file:", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatal("An empty lock file shouldn't be created")
+	}
+
+	expected := regolith.LockedFilter{
+		Url:     "github.com/Bedrock-OSS/regolith-test-filters",
+		Version: "1.0.0",
+		Commit:  "0123456789abcdef0123456789abcdef01234567",
+		Hash:    "sha256:00",
+	}
+	lockFile.Filters["hello_version"] = expected
+	if err := lockFile.Dump(lockPath); err != nil {
+		t.Fatal("Unable to save the lock file:", err)
+	}
+	loaded, err := regolith.LoadLockFile(lockPath)
+	if err != nil {
+		t.Fatal("Unable to load the lock file:", err)
+	}
+	if loaded.Filters["hello_version"] != expected {
+		t.Fatalf("Unexpected lock file entry.\nExpected: %v\nGot: %v",
+			expected, loaded.Filters["hello_version"])
+	}
+}
+
+// TestHashFilterDirectoryLineEndings tests if the hash of the filter files
+// doesn't depend on the line endings of the files.
+func TestHashFilterDirectoryLineEndings(t *testing.T) {
+	tmpDir := prepareTestDirectory("TestHashFilterDirectoryLineEndings", t)
+	lfDir := filepath.Join(tmpDir, "lf")
+	crlfDir := filepath.Join(tmpDir, "crlf")
+	for dir, content := range map[string]string{
+		lfDir:   "print('a')\nprint('b')\n",
+		crlfDir: "print('a')\r\nprint('b')\r\n",
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal("Unable to create the filter directory:", err)
+		}
+		err := os.WriteFile(filepath.Join(dir, "main.py"), []byte(content), 0644)
+		if err != nil {
+			t.Fatal("Unable to write the filter file:", err)
+		}
+	}
+	lfHash, err := regolith.HashFilterDirectory(lfDir)
+	if err != nil {
+		t.Fatal("Unable to hash the filter directory:", err)
+	}
+	crlfHash, err := regolith.HashFilterDirectory(crlfDir)
+	if err != nil {
+		t.Fatal("Unable to hash the filter directory:", err)
+	}
+	if lfHash != crlfHash {
+		t.Fatalf("Hashes differ: %s != %s", lfHash, crlfHash)
+	}
+}
+
+// TestFrozenInstallOutdatedLockFile tests if "regolith install-all --frozen"
+// fails when the version or the URL of a remote filter in the config file
+// doesn't match the lock file.
+func TestFrozenInstallOutdatedLockFile(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestFrozenInstallOutdatedLockFile", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	os.Chdir(tmpDir)
+
+	lockFile := regolith.NewLockFile()
+	lockFile.Filters["hello_version"] = regolith.LockedFilter{
+		Url:     "github.com/Bedrock-OSS/regolith-test-filters",
+		Version: "1.0.0",
+		Commit:  "0123456789abcdef0123456789abcdef01234567",
+		Hash:    "sha256:00",
+	}
+	if err := lockFile.Dump(regolith.LockFilePath); err != nil {
+		t.Fatal("Unable to save the lock file:", err)
+	}
+	for _, definition := range []string{
+		`{"url": "github.com/Bedrock-OSS/regolith-test-filters", "version": "1.0.1"}`,
+		`{"url": "github.com/Bedrock-OSS/other-filters", "version": "1.0.0"}`,
+	} {
+		config := []byte(`{
+			"name": "regolith_test_project",
+			"author": "Bedrock-OSS",
+			"packs": {
+				"behaviorPack": "./packs/BP",
+				"resourcePack": "./packs/RP"
+			},
+			"regolith": {
+				"formatVersion": "1.4.0",
+				"filterDefinitions": {"hello_version": ` + definition + `},
+				"profiles": {"default": {"filters": [], "export": {"target": "local"}}},
+				"dataPath": "./packs/data"
+			}
+		}`)
+		if err := os.WriteFile("config.json", config, 0644); err != nil {
+			t.Fatal("Unable to write config:", err)
+		}
+		err := regolith.InstallAll(false, false, true, false, true, "")
+		if err == nil {
+			t.Fatalf("Expected the frozen installation to fail with %s", definition)
+		}
+		if !strings.Contains(err.Error(), "The lock file is out of date.") {
+			t.Fatalf("Unexpected error with %s:\n%s", definition, err)
+		}
+	}
+}
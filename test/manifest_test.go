@@ -0,0 +1,152 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestManifestOptions tests if the "manifest" property of a profile sets the
+// version of the exported packs, links the packs with dependencies and gives
+// them new UUIDs without changing the source files.
+func TestManifestOptions(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestManifestOptions", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.9.0",
+			"filterDefinitions": {},
+			"variables": {"version": "2.3.4"},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"export": {"target": "exact", "rpPath": "../build/RP", "bpPath": "../build/BP"},
+					"manifest": {"version": "v${variables.version}", "linkPacks": true}
+				},
+				"release": {
+					"extends": "default",
+					"manifest": {"version": "${variables.version}", "newUuids": true}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(workingDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	// The characters escaped by json.Marshal must be written unchanged
+	rpManifestPath := filepath.Join(workingDir, "packs", "RP", "manifest.json")
+	rpManifest, err := os.ReadFile(rpManifestPath)
+	if err != nil {
+		t.Fatal("Unable to read the manifest of the RP:", err)
+	}
+	rpManifest = bytes.Replace(rpManifest, []byte("Regolith Test RP"), []byte("Regolith <Test> RP & more"), 1)
+	if err := os.WriteFile(rpManifestPath, rpManifest, 0644); err != nil {
+		t.Fatal("Unable to write the manifest of the RP:", err)
+	}
+	os.Chdir(workingDir)
+
+	const bpUuid = "96b53fd2-b7a1-4d26-b74f-1b9394c8d0bc"
+	const rpUuid = "6f6e3f0b-1627-488d-a9aa-2d1430ba368a"
+	version := []any{2.0, 3.0, 4.0}
+	readManifests := func() (bp, rp map[string]any) {
+		readJsonOrFatal(filepath.Join(tmpDir, "build", "BP", "manifest.json"), &bp, t)
+		readJsonOrFatal(filepath.Join(tmpDir, "build", "RP", "manifest.json"), &rp, t)
+		return bp, rp
+	}
+	header := func(manifest map[string]any) map[string]any {
+		return manifest["header"].(map[string]any)
+	}
+	dependencies := func(manifest map[string]any) []any {
+		dependencies, _ := manifest["dependencies"].([]any)
+		return dependencies
+	}
+
+	// The version is set and the RP gets the dependency on the BP
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	bp, rp := readManifests()
+	for _, manifest := range []map[string]any{bp, rp} {
+		if !reflect.DeepEqual(header(manifest)["version"], version) {
+			t.Fatalf("Unexpected version of the pack: %v", header(manifest)["version"])
+		}
+		module := manifest["modules"].([]any)[0].(map[string]any)
+		if !reflect.DeepEqual(module["version"], version) {
+			t.Fatalf("Unexpected version of the module: %v", module["version"])
+		}
+	}
+	if header(bp)["uuid"] != bpUuid || header(rp)["uuid"] != rpUuid {
+		t.Fatal("The UUIDs of the packs were changed")
+	}
+	expected := []any{map[string]any{"uuid": rpUuid, "version": version}}
+	if !reflect.DeepEqual(dependencies(bp), expected) {
+		t.Fatalf("Unexpected dependencies of the BP: %v", dependencies(bp))
+	}
+	expected = []any{map[string]any{"uuid": bpUuid, "version": version}}
+	if !reflect.DeepEqual(dependencies(rp), expected) {
+		t.Fatalf("Unexpected dependencies of the RP: %v", dependencies(rp))
+	}
+	// The source files don't change
+	var source map[string]any
+	readJsonOrFatal(filepath.Join(workingDir, "packs", "RP", "manifest.json"), &source, t)
+	if dependencies(source) != nil || header(source)["uuid"] != rpUuid {
+		t.Fatalf("The source manifest was changed: %v", source)
+	}
+
+	// The release profile gives new UUIDs to the packs. The dependency of the
+	// BP uses the new UUID of the RP.
+	if err := regolith.Run("release", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	bp, rp = readManifests()
+	newRpUuid, _ := header(rp)["uuid"].(string)
+	if header(bp)["uuid"] == bpUuid || newRpUuid == rpUuid || len(newRpUuid) != 36 {
+		t.Fatalf("The packs didn't get new UUIDs: %v, %v", header(bp)["uuid"], newRpUuid)
+	}
+	module := rp["modules"].([]any)[0].(map[string]any)
+	if module["uuid"] == "65b1ba69-462d-4199-aa3b-a0f161ed0bde" {
+		t.Fatal("The module didn't get a new UUID")
+	}
+	expected = []any{map[string]any{"uuid": newRpUuid, "version": version}}
+	if !reflect.DeepEqual(dependencies(bp), expected) {
+		t.Fatalf("Unexpected dependencies of the BP: %v", dependencies(bp))
+	}
+	if dependencies(rp) != nil {
+		t.Fatalf("Unexpected dependencies of the RP: %v", dependencies(rp))
+	}
+	// The properties keep their order and the strings aren't escaped
+	data, err := os.ReadFile(filepath.Join(tmpDir, "build", "RP", "manifest.json"))
+	if err != nil {
+		t.Fatal("Unable to read the exported manifest of the RP:", err)
+	}
+	if !bytes.Contains(data, []byte(`"Regolith <Test> RP & more"`)) {
+		t.Fatalf("The name of the RP was escaped:\n%s", data)
+	}
+	if bytes.Index(data, []byte(`"version"`)) > bytes.Index(data, []byte(`"min_engine_version"`)) {
+		t.Fatalf("The properties of the manifest were reordered:\n%s", data)
+	}
+
+	// The next runs of the profile reuse the generated UUIDs
+	if err := regolith.Run("release", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	bp2, rp2 := readManifests()
+	if header(bp2)["uuid"] != header(bp)["uuid"] || header(rp2)["uuid"] != newRpUuid {
+		t.Fatalf("The packs got different UUIDs in the second run: %v, %v",
+			header(bp2)["uuid"], header(rp2)["uuid"])
+	}
+}
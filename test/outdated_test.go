@@ -0,0 +1,88 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestFindFilterUpdate tests if FindFilterUpdate selects the newest version
+// of the filter allowed by the upgrade level.
+func TestFindFilterUpdate(t *testing.T) {
+	tags := []string{
+		"name_ninja-1.2.3", "name_ninja-1.2.5", "name_ninja-1.4.0",
+		"name_ninja-2.0.0-beta", "name_ninja-2.0.0", "other-9.9.9",
+	}
+	tests := []struct {
+		current  string
+		level    string
+		expected string
+	}{
+		{"1.2.3", regolith.UpgradeMajor, "2.0.0"},
+		{"1.2.3", regolith.UpgradeMinor, "1.4.0"},
+		{"1.2.3", regolith.UpgradePatch, "1.2.5"},
+		{"1.4.0", regolith.UpgradePatch, ""},
+		{"2.0.0", regolith.UpgradeMajor, ""},
+		{"2.0.0-alpha", regolith.UpgradeMajor, "2.0.0"},
+		{"HEAD", regolith.UpgradeMajor, ""},
+	}
+	for _, test := range tests {
+		result := regolith.FindFilterUpdate(tags, "name_ninja", test.current, test.level)
+		if result != test.expected {
+			t.Errorf(
+				"Unexpected update of version %q (%s): %q, expected %q",
+				test.current, test.level, result, test.expected)
+		}
+	}
+}
+
+// TestUpdateInvalidFilters tests if "regolith update" rejects the filters
+// that aren't remote filters of the project without changing the config.
+func TestUpdateInvalidFilters(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestUpdateInvalidFilters", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"local": {"runWith": "shell", "command": "echo a"}
+			},
+			"profiles": {"default": {"filters": [], "export": {"target": "local"}}},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	configPath := filepath.Join(workingDir, "config.json")
+	if err := os.WriteFile(configPath, config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(workingDir)
+
+	for _, filter := range []string{"local", "missing"} {
+		err := regolith.Update([]string{filter}, false, false, true, "")
+		if err == nil {
+			t.Fatalf("Expected 'regolith update %s' to fail", filter)
+		}
+	}
+	// The project doesn't use remote filters so there is nothing to update
+	if err := regolith.Update(nil, false, true, true, ""); err != nil {
+		t.Fatal("'regolith update' failed:", err)
+	}
+	if err := regolith.Outdated(true, ""); err != nil {
+		t.Fatal("'regolith outdated' failed:", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil || string(data) != string(config) {
+		t.Fatal("The config file was changed")
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestProfileExtends tests if a profile inherits the filters and the export
+// target of the profile that it extends, and if it can modify the inherited
+// filters.
+func TestProfileExtends(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestProfileExtends", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"write_a": {"runWith": "shell", "command": "echo a > BP/a.txt"},
+				"write_b": {"runWith": "shell", "command": "echo b > BP/b.txt"},
+				"write_c": {"runWith": "shell", "command": "echo c > BP/c.txt"},
+				"write_d": {"runWith": "shell", "command": "echo d > BP/d.txt"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{"filter": "write_a"},
+						{"filter": "write_b"},
+						{"filter": "write_c"}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"child": {
+					"extends": "default",
+					"filters": {
+						"prepend": [{"filter": "write_d"}],
+						"override": {"write_b": {"disabled": true}},
+						"replace": {"write_c": {"filter": "write_d"}}
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	if err := regolith.Run("child", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	bp := filepath.Join(tmpDir, "build", "regolith_test_project_bp")
+	for file, exists := range map[string]bool{
+		"a.txt": true, "b.txt": false, "c.txt": false, "d.txt": true,
+	} {
+		_, err := os.Stat(filepath.Join(bp, file))
+		if exists && err != nil {
+			t.Errorf("The file %q should be exported", file)
+		} else if !exists && !os.IsNotExist(err) {
+			t.Errorf("The file %q shouldn't be exported", file)
+		}
+	}
+}
+
+// TestProfileExtendsCycle tests if ProfileFromObject rejects the profiles
+// that extend each other.
+func TestProfileExtendsCycle(t *testing.T) {
+	profiles := map[string]any{
+		"first":  map[string]any{"extends": "second"},
+		"second": map[string]any{"extends": "first"},
+	}
+	_, err := regolith.ProfileFromObject(
+		profiles["first"].(map[string]any),
+		map[string]regolith.FilterInstaller{},
+		profiles,
+	)
+	if err == nil {
+		t.Fatal("Expected an error because of the cycle")
+	}
+	if !strings.Contains(err.Error(), "second -> first -> second") {
+		t.Fatal("The error doesn't describe the cycle:", err)
+	}
+}
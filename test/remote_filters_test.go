@@ -32,7 +32,7 @@ func TestInstallAllAndRun(t *testing.T) {
 
 	// THE TEST
 	t.Log("Testing the 'regolith install-all' command...")
-	err := regolith.InstallAll(false, false, true, false, "")
+	err := regolith.InstallAll(false, false, true, false, false, "")
 	if err != nil {
 		t.Fatal("'regolith install-all' failed:", err)
 	}
@@ -45,7 +45,8 @@ func TestInstallAllAndRun(t *testing.T) {
 
 	// TEST EVALUATION
 	t.Log("Evaluating the test results...")
-	comparePaths(expectedPath, ".", t) // expected vs created paths
+	comparePaths(expectedPath, ".", t, editedFilesPath) // expected vs created paths
+	compareEditedFilesLists(expectedPath, ".", t)
 }
 
 // TestDataModifyRemoteFilter installs a project with one filter using
@@ -72,7 +73,7 @@ func TestDataModifyRemoteFilter(t *testing.T) {
 
 	// THE TEST
 	t.Log("Testing the 'regolith install-all' command...")
-	err := regolith.InstallAll(false, false, true, false, "")
+	err := regolith.InstallAll(false, false, true, false, false, "")
 	if err != nil {
 		t.Fatal("'regolith install-all' failed:", err)
 	}
@@ -84,7 +85,8 @@ func TestDataModifyRemoteFilter(t *testing.T) {
 	}
 	// TEST EVALUATION
 	t.Log("Evaluating the test results...")
-	comparePaths(expectedPath, ".", t)
+	comparePaths(expectedPath, ".", t, editedFilesPath)
+	compareEditedFilesLists(expectedPath, ".", t)
 }
 
 // TestInstall tests the 'regolith install' command. It forcefully installs
@@ -188,7 +190,7 @@ func TestInstallAll(t *testing.T) {
 
 		// Run 'regolith update' / 'regolith update-all'
 		t.Log("Running 'regolith update'...")
-		err = regolith.InstallAll(false, false, true, false, "")
+		err = regolith.InstallAll(false, false, true, false, false, "")
 		if err != nil {
 			t.Fatal("'regolith update' failed:", err)
 		}
@@ -210,7 +212,7 @@ func TestNestedRemoteFilter(t *testing.T) {
 	os.Chdir(tmpDir)
 
 	t.Log("Testing the 'regolith install-all' command (should fail)...")
-	err := regolith.InstallAll(false, false, true, false, "")
+	err := regolith.InstallAll(false, false, true, false, false, "")
 	if err == nil {
 		t.Fatal("Expected 'regolith install-all' to fail, but it succeeded")
 	}
@@ -46,3 +46,47 @@ func TestRevertibleDeleteDirRollback(t *testing.T) {
 		t.Fatalf("close failed: %v", err)
 	}
 }
+
+func TestRevertibleSwapRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	backupDir := filepath.Join(tmpDir, "backup")
+
+	// create the target and the staged directory that replaces it
+	target := filepath.Join(tmpDir, "target")
+	staged := filepath.Join(tmpDir, "staged")
+	for path, content := range map[string]string{target: "old", staged: "new"} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("failed to create test dir: %v", err)
+		}
+		err := os.WriteFile(filepath.Join(path, "file.txt"), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	ops, err := regolith.NewRevertibleFsOperations(backupDir)
+	if err != nil {
+		t.Fatalf("failed to init revertible ops: %v", err)
+	}
+
+	if err := ops.Swap(staged, target); err != nil {
+		t.Fatalf("swap failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(target, "file.txt")); err != nil || string(content) != "new" {
+		t.Fatalf("target not replaced: %q, %v", content, err)
+	}
+
+	if err := ops.Undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(target, "file.txt")); err != nil || string(content) != "old" {
+		t.Fatalf("target not restored: %q, %v", content, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(staged, "file.txt")); err != nil || string(content) != "new" {
+		t.Fatalf("staged directory not restored: %q, %v", content, err)
+	}
+
+	if err := ops.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+}
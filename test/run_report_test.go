@@ -0,0 +1,153 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestRunReport tests if "regolith run --report --trace" writes the report
+// with the filters and their changed files, and the Chrome trace with the
+// parallel async subfilters on separate tracks.
+func TestRunReport(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestRunReport", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"write": {
+					"runWith": "shell",
+					"command": "echo a > BP/a.txt && rm RP/manifest.json && tr 0 9 < BP/manifest.json > m.tmp && touch -r BP/manifest.json m.tmp && mv m.tmp BP/manifest.json && echo '__REGOLITH_EVENT__{\"type\": \"output\", \"files\": [\"BP/a.txt\"]}'"
+				},
+				"sleep_a": {"runWith": "shell", "command": "sleep 0.3"},
+				"sleep_b": {"runWith": "shell", "command": "sleep 0.3"},
+				"fail": {"runWith": "shell", "command": "exit 1"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{"filter": "write"},
+						{"asyncFilters": [{"filter": "sleep_a"}, {"filter": "sleep_b"}]}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"failing": {
+					"filters": [{"filter": "fail"}],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	reportPath := filepath.Join(tmpDir, "reports", "report.json")
+	tracePath := filepath.Join(tmpDir, "reports", "trace.json")
+	err := regolith.RunWithReport(
+		"default", []string{}, true, "", false, false, false, false, reportPath, tracePath)
+	if err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+
+	// The report
+	var report regolith.RunReport
+	readJsonOrFatal(reportPath, &report, t)
+	if report.Profile != "default" || report.Status != "succeeded" || report.Export == nil {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	var ids []string
+	for _, filter := range report.Filters {
+		ids = append(ids, filter.Id)
+	}
+	slices.Sort(ids[2:]) // The async subfilters may start in any order
+	expectedIds := []string{"write", "asyncFilters", "sleep_a", "sleep_b"}
+	if !slices.Equal(ids, expectedIds) {
+		t.Fatalf("Unexpected filters.\nExpected: %v\nActual: %v", expectedIds, ids)
+	}
+	write := report.Filters[0]
+	if write.Status != "succeeded" || write.FilesChanged == nil ||
+		!slices.Equal(write.FilesChanged.Added, []string{"BP/a.txt"}) ||
+		!slices.Equal(write.FilesChanged.Modified, []string{"BP/manifest.json"}) ||
+		!slices.Equal(write.FilesChanged.Removed, []string{"RP/manifest.json"}) {
+		t.Fatalf("Unexpected report of the \"write\" filter: %+v", write)
+	}
+	if len(write.Events) != 1 || write.Events[0].Type != regolith.FilterEventOutput ||
+		!slices.Equal(write.Events[0].Files, []string{"BP/a.txt"}) {
+		t.Fatalf("Unexpected events of the \"write\" filter: %+v", write.Events)
+	}
+	if len(report.Filters[1].Events) != 0 {
+		t.Fatalf("The events were added to another filter: %+v", report.Filters[1].Events)
+	}
+	if report.Filters[2].Depth != 1 || report.Filters[2].DurationMs < 300 {
+		t.Fatalf("Unexpected report of the async subfilter: %+v", report.Filters[2])
+	}
+
+	// The trace
+	var trace struct {
+		TraceEvents []struct {
+			Name  string  `json:"name"`
+			Phase string  `json:"ph"`
+			Dur   float64 `json:"dur"`
+			Tid   int     `json:"tid"`
+		} `json:"traceEvents"`
+	}
+	readJsonOrFatal(tracePath, &trace, t)
+	tids := map[string]int{}
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" {
+			tids[event.Name] = event.Tid
+		}
+	}
+	for _, name := range []string{"setup", "write", "sleep_a", "sleep_b", "export"} {
+		if _, ok := tids[name]; !ok {
+			t.Fatalf("The trace doesn't contain %q: %v", name, tids)
+		}
+	}
+	if tids["sleep_a"] == tids["sleep_b"] {
+		t.Fatalf("The parallel filters are on the same track: %v", tids)
+	}
+
+	// The report is written even if the run fails
+	err = regolith.RunWithReport(
+		"failing", []string{}, true, "", false, false, false, false, reportPath, "")
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail")
+	}
+	report = regolith.RunReport{}
+	readJsonOrFatal(reportPath, &report, t)
+	if report.Status != "failed" || report.Error == "" || report.Export != nil ||
+		len(report.Filters) != 1 || report.Filters[0].Status != "failed" {
+		t.Fatalf("Unexpected report of the failed run: %+v", report)
+	}
+}
+
+// readJsonOrFatal reads the JSON file into the value.
+func readJsonOrFatal(path string, value any, t *testing.T) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("Unable to read the file:", err)
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		t.Fatalf("Invalid JSON file %s: %s", path, err)
+	}
+}
@@ -55,7 +55,8 @@ func TestSizeTimeCheckOptimizationCorectness(t *testing.T) {
 	}
 	// TEST EVALUATION
 	t.Log("Evaluating the result...")
-	comparePaths(expectedBuildResult, tmpDir, t)
+	comparePaths(expectedBuildResult, tmpDir, t, editedFilesPath)
+	compareEditedFilesLists(expectedBuildResult, tmpDir, t)
 }
 
 // TestSizeTimeCheckOptimizationSpeed tests if running Regolith with the
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestStarlarkFilter tests if the Starlark filter can read and write the
+// files of the packs and if it can't access the files outside of them.
+func TestStarlarkFilter(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestStarlarkFilter", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"copy_name": {
+					"runWith": "starlark",
+					"script": "./filters/copy_name.star"
+				},
+				"escape": {
+					"runWith": "starlark",
+					"script": "./filters/escape.star"
+				}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{
+							"filter": "copy_name",
+							"settings": {"suffix": "!"}
+						}
+					],
+					"export": {
+						"target": "local"
+					}
+				},
+				"escape": {
+					"filters": [{"filter": "escape"}],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	scripts := map[string]string{
+		"copy_name.star": strings.Join([]string{
+			`manifest = json.decode(read("BP/manifest.json"))`,
+			`write("BP/name.txt", manifest["header"]["name"] + settings["suffix"])`,
+			`print("Files:", list("BP"))`,
+		}, "\n"),
+		"escape.star": `read("../config.json")`,
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	filtersDir := filepath.Join(tmpDir, "filters")
+	if err := os.MkdirAll(filtersDir, 0755); err != nil {
+		t.Fatal("Unable to create the filters directory:", err)
+	}
+	for name, script := range scripts {
+		err := os.WriteFile(filepath.Join(filtersDir, name), []byte(script), 0644)
+		if err != nil {
+			t.Fatal("Unable to write the script:", err)
+		}
+	}
+	os.Chdir(tmpDir)
+
+	err := regolith.Run("default", []string{}, true, "", false, false, false)
+	if err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	content, err := os.ReadFile(
+		filepath.Join(tmpDir, "build", "regolith_test_project_bp", "name.txt"))
+	if err != nil {
+		t.Fatal("Unable to read the file created by the filter:", err)
+	}
+	if !strings.HasSuffix(string(content), "!") {
+		t.Fatalf("Unexpected content of the file created by the filter: %q", content)
+	}
+
+	err = regolith.Run("escape", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("The filter was able to access a file outside of the packs")
+	}
+}
+
+// TestStarlarkFilterSymlink tests if the Starlark filter can't create new
+// files outside of the packs through a symlink to a directory.
+func TestStarlarkFilterSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test creates the symlink with a shell command")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestStarlarkFilterSymlink", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+	outsideDir := filepath.Join(tmpDir, "outside")
+	if err := os.MkdirAll(outsideDir, 0755); err != nil {
+		t.Fatal("Unable to create the outside directory:", err)
+	}
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"link": {"runWith": "shell", "command": "ln -s ` + outsideDir + ` BP/link"},
+				"escape": {"runWith": "starlark", "script": "./escape.star"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [{"filter": "link"}, {"filter": "escape"}],
+					"export": {"target": "local"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	files := map[string]string{
+		"config.json": string(config),
+		"escape.star": `write("BP/link/new/file.json", "{}")`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(workingDir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal("Unable to write the file:", err)
+		}
+	}
+	os.Chdir(workingDir)
+
+	err := regolith.Run("default", []string{}, true, "", false, false, false)
+	if err == nil {
+		t.Fatal("The filter was able to write a file through the symlink")
+	}
+	if _, err := os.Stat(filepath.Join(outsideDir, "new")); !os.IsNotExist(err) {
+		t.Fatal("The filter created a directory outside of the packs")
+	}
+}
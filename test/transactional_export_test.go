@@ -0,0 +1,94 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestTransactionalExport tests if the export of a profile with the
+// "transactionalExport" property updates all of the export targets, and if
+// it leaves all of them unchanged when one of them fails.
+func TestTransactionalExport(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestTransactionalExport", t)
+	workingDir := filepath.Join(tmpDir, "working-dir")
+	copyFilesOrFatal(minimalProjectPath, workingDir, t)
+
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"write": {"runWith": "shell", "command": "echo a > BP/a.txt"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [],
+					"transactionalExport": true,
+					"export": [
+						{"target": "exact", "rpPath": "../target-a/RP", "bpPath": "../target-a/BP"},
+						{"target": "exact", "rpPath": "../target-b/RP", "bpPath": "../target-b/BP"}
+					]
+				},
+				"broken": {
+					"filters": [{"filter": "write"}],
+					"transactionalExport": true,
+					"export": [
+						{"target": "exact", "rpPath": "../target-a/RP", "bpPath": "../target-a/BP"},
+						{"target": "exact", "rpPath": "../blocker/RP", "bpPath": "../target-b/BP"}
+					]
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(workingDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	// The "blocker" file makes exporting to the second target fail
+	if err := os.WriteFile(filepath.Join(tmpDir, "blocker"), []byte{}, 0644); err != nil {
+		t.Fatal("Unable to write the blocker file:", err)
+	}
+	os.Chdir(workingDir)
+
+	if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+		t.Fatal("'regolith run' failed:", err)
+	}
+	for _, target := range []string{"target-a", "target-b"} {
+		comparePaths(
+			filepath.Join(workingDir, "packs", "BP"), filepath.Join(tmpDir, target, "BP"), t)
+		comparePaths(
+			filepath.Join(workingDir, "packs", "RP"), filepath.Join(tmpDir, target, "RP"), t)
+	}
+
+	// The unsafe mode skips the safety check, which would reject the blocker
+	err := regolith.Run("broken", []string{}, true, "", true, false, false)
+	if err == nil {
+		t.Fatal("Expected 'regolith run' to fail")
+	}
+	// None of the targets is changed and the staged files are removed
+	for _, target := range []string{"target-a", "target-b"} {
+		comparePaths(
+			filepath.Join(workingDir, "packs", "BP"), filepath.Join(tmpDir, target, "BP"), t)
+		comparePaths(
+			filepath.Join(workingDir, "packs", "RP"), filepath.Join(tmpDir, target, "RP"), t)
+	}
+	// None of the files is staged or backed up next to the export targets
+	entries, err := filepath.Glob(filepath.Join(tmpDir, "target-*", "*"))
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("Unexpected files next to the export targets: %v", entries)
+	}
+	stagingPath := filepath.Join(workingDir, ".regolith", ".exportStaging")
+	if _, err := os.Stat(stagingPath); !os.IsNotExist(err) {
+		t.Fatal("The staged files weren't removed")
+	}
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestValidate tests if "regolith validate" reports all problems of the
+// config.json file, the filter.json files and the settings of the filters
+// with their locations.
+func TestValidate(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestValidate", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := `{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"remote": {"url": "github.com/Bedrock-OSS/example", "version": "1.0.0"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{"filter": "remote", "settings": {"greeting": "Hello"}}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`
+	files := map[string]string{
+		"config.json": config,
+		".regolith/cache/filters/remote/filter.json": `{
+			"filters": [{"runWith": "shell", "command": "echo"}]
+		}`,
+		".regolith/cache/filters/remote/settings.schema.json": `{
+			"type": "object",
+			"properties": {"greeting": {"type": "string"}},
+			"additionalProperties": false
+		}`,
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal("Unable to create the directory:", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal("Unable to write the file:", err)
+		}
+	}
+	os.Chdir(tmpDir)
+
+	problems, err := regolith.ValidateProject()
+	if err != nil {
+		t.Fatal("Unable to validate the project:", err)
+	}
+	for _, problem := range problems {
+		if strings.HasPrefix(problem.Location, "config.json") {
+			t.Fatal("Unexpected problem of the valid config:", problem)
+		}
+	}
+
+	// Break the config in multiple places
+	config = strings.Replace(config, `"target": "local"`, `"target": "lcoal"`, 1)
+	config = strings.Replace(config, `"settings": {"greeting": "Hello"}`,
+		`"settings": {"greting": "Hello"}, "disabeld": true`, 1)
+	if err := os.WriteFile("config.json", []byte(config), 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	err = os.WriteFile(
+		filepath.Join(".regolith", "cache", "filters", "remote", "filter.json"),
+		[]byte(`{"filters": [{"runWith": "pyhton"}]}`), 0644)
+	if err != nil {
+		t.Fatal("Unable to write filter.json:", err)
+	}
+	problems, err = regolith.ValidateProject()
+	if err != nil {
+		t.Fatal("Unable to validate the project:", err)
+	}
+	expected := []string{
+		"config.json:16:74", // the "disabeld" property
+		"config.json:19:17", // the invalid export target
+		"config.json:16:52", // the "greting" setting
+		"filter.json:1:26",  // the invalid runWith value
+	}
+	for _, location := range expected {
+		found := false
+		for _, problem := range problems {
+			if strings.HasSuffix(problem.Location, location) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Missing the problem at %s.\nProblems: %v", location, problems)
+		}
+	}
+}
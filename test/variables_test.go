@@ -0,0 +1,163 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestVariables tests if the "${expression}" templates in the settings and
+// the arguments of the filters and in the export paths use the variables of
+// the project overridden by the variables of the profile.
+func TestVariables(t *testing.T) {
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestVariables", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.9.0",
+			"variables": {"debug": false, "version": "dev"},
+			"filterDefinitions": {
+				"write": {"runWith": "shell", "command": "printf '%s|' > BP/out.txt"}
+			},
+			"profiles": {
+				"dev": {
+					"variables": {"debug": true},
+					"filters": [{
+						"filter": "write",
+						"settings": {
+							"debug": "${variables.debug}",
+							"label": "v${variables.version}"
+						},
+						"arguments": ["${variables.version}", "$${HOME}"]
+					}],
+					"export": {
+						"target": "exact",
+						"bpPath": "build/${variables.version}_bp",
+						"rpPath": "build/${variables.version}_rp"
+					}
+				},
+				"release": {
+					"extends": "dev",
+					"variables": {"debug": false, "version": "1.2.0"}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	for _, tc := range []struct{ profile, bpName, expected string }{
+		{"dev", "dev_bp", `{"debug":true,"label":"vdev"}|dev|${HOME}|`},
+		{"release", "1.2.0_bp", `{"debug":false,"label":"v1.2.0"}|1.2.0|${HOME}|`},
+	} {
+		if err := regolith.Run(tc.profile, []string{}, true, "", false, false, false); err != nil {
+			t.Fatalf("'regolith run %s' failed: %s", tc.profile, err)
+		}
+		outPath := filepath.Join(tmpDir, "build", tc.bpName, "out.txt")
+		content, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("The output of the %q profile wasn't exported: %s", tc.profile, err)
+		}
+		if string(content) != tc.expected {
+			t.Errorf(
+				"Unexpected output of the %q profile.\nExpected: %s\nActual: %s",
+				tc.profile, tc.expected, content)
+		}
+	}
+}
+
+// TestTemplatesFormatVersion tests if the "${expression}" templates are
+// evaluated only in the projects with the format version 1.9.0 or newer, so
+// the "${HOME}" of the older projects is still expanded by the shell in the
+// preShell commands and passed unchanged in the settings of the filters.
+func TestTemplatesFormatVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test uses the shell syntax of Unix")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestTemplatesFormatVersion", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	os.Chdir(tmpDir)
+	home, _ := os.LookupEnv("HOME")
+	settingsPath := filepath.Join(tmpDir, "settings.txt")
+
+	for _, tc := range []struct {
+		formatVersion, command, label   string
+		expectedShell, expectedSettings string
+	}{
+		{
+			"1.8.0", "echo ${HOME}", "${variables.version}-${HOME}",
+			home, `{"label":"${variables.version}-${HOME}"}`,
+		},
+		{
+			"1.9.0", "echo ${variables.version} $${HOME}", "${variables.version}-$${HOME}",
+			"dev " + home, `{"label":"dev-${HOME}"}`,
+		},
+	} {
+		config := []byte(`{
+			"name": "regolith_test_project",
+			"author": "Bedrock-OSS",
+			"packs": {
+				"behaviorPack": "./packs/BP",
+				"resourcePack": "./packs/RP"
+			},
+			"regolith": {
+				"formatVersion": "` + tc.formatVersion + `",
+				"variables": {"version": "dev"},
+				"filterDefinitions": {
+					"write": {"runWith": "shell", "command": "printf '%s' > ` + settingsPath + `"}
+				},
+				"profiles": {
+					"default": {
+						"filters": [{"filter": "write", "settings": {"label": "` + tc.label + `"}}],
+						"preShell": ["` + tc.command + ` > shell.txt"],
+						"export": {"target": "local"}
+					}
+				},
+				"dataPath": "./packs/data"
+			}
+		}`)
+		if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+			t.Fatal("Unable to write config:", err)
+		}
+		if err := regolith.Run("default", []string{}, true, "", false, false, false); err != nil {
+			t.Fatalf("'regolith run' failed with format version %s: %s", tc.formatVersion, err)
+		}
+		content, err := os.ReadFile(filepath.Join(tmpDir, "shell.txt"))
+		if err != nil {
+			t.Fatal("The preShell command didn't write the file:", err)
+		}
+		if strings.TrimSpace(string(content)) != tc.expectedShell {
+			t.Errorf(
+				"Unexpected output of the preShell command with format version %s.\n"+
+					"Expected: %s\nActual: %s",
+				tc.formatVersion, tc.expectedShell, content)
+		}
+		content, err = os.ReadFile(settingsPath)
+		if err != nil {
+			t.Fatal("The filter didn't write the file:", err)
+		}
+		if string(content) != tc.expectedSettings {
+			t.Errorf(
+				"Unexpected settings of the filter with format version %s.\n"+
+					"Expected: %s\nActual: %s",
+				tc.formatVersion, tc.expectedSettings, content)
+		}
+	}
+}
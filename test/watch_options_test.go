@@ -0,0 +1,90 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// TestWatchPollingIgnore tests if the watch mode with polling rebuilds the
+// project after a change of a source file, but not after a change of a file
+// matched by the ignore patterns.
+func TestWatchPollingIgnore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test stops the watch mode with the interrupt signal")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestWatchPollingIgnore", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	runsPath := filepath.Join(tmpDir, "runs.txt")
+	config := []byte(fmt.Sprintf(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"count": {"runWith": "shell", "command": %q}
+			},
+			"profiles": {
+				"default": {
+					"filters": [{"filter": "count"}],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data",
+			"watch": {
+				"ignore": ["*.swp", "/packs/RP/ignored/*", "!/packs/RP/ignored/kept.txt"],
+				"debounce": "50ms",
+				"polling": true,
+				"pollingInterval": "100ms"
+			}
+		}
+	}`, fmt.Sprintf("echo run >> '%s'", runsPath)))
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	ignoredDir := filepath.Join(tmpDir, "packs", "RP", "ignored")
+	if err := os.MkdirAll(ignoredDir, 0755); err != nil {
+		t.Fatal("Unable to create the ignored directory:", err)
+	}
+	os.Chdir(tmpDir)
+
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- regolith.Watch("default", []string{}, true, "", false, false, false, false, "")
+	}()
+	waitForFile(runsPath, "run\n", t)
+
+	// The ignored files don't trigger a rebuild
+	for _, path := range []string{
+		filepath.Join(tmpDir, "packs", "BP", ".file.json.swp"),
+		filepath.Join(ignoredDir, "file.txt"),
+	} {
+		if err := os.WriteFile(path, []byte("ignored"), 0644); err != nil {
+			t.Fatal("Unable to write the ignored file:", err)
+		}
+	}
+	time.Sleep(time.Second)
+	waitForFile(runsPath, "run\n", t)
+
+	// The negated pattern includes the file again
+	keptFile := filepath.Join(ignoredDir, "kept.txt")
+	if err := os.WriteFile(keptFile, []byte("kept"), 0644); err != nil {
+		t.Fatal("Unable to write the file:", err)
+	}
+	waitForFile(runsPath, "run\nrun\n", t)
+
+	stopWatch(watchErr, t)
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+)
+
+// waitForFile waits until the file exists and has the expected content.
+func waitForFile(path, expected string, t *testing.T) {
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		if content, err := os.ReadFile(path); err == nil && string(content) == expected {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	content, _ := os.ReadFile(path)
+	t.Fatalf("Timed out waiting for the file.\nPath: %s\nExpected: %q\nActual: %q",
+		path, expected, content)
+}
+
+// stopWatch stops the watch mode with the interrupt signal and waits until
+// it returns.
+func stopWatch(watchErr <-chan error, t *testing.T) {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal("Unable to find the test process:", err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal("Unable to stop the watch mode:", err)
+	}
+	select {
+	case err := <-watchErr:
+		if err != nil {
+			t.Fatal("'regolith watch' failed:", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("Timed out waiting for the watch mode to stop")
+	}
+}
+
+// TestWatchRestartFromAffectedFilter tests if the watch mode restarts the
+// profile from the first filter whose inputs are affected by the changed
+// files, instead of running all of the filters again.
+func TestWatchRestartFromAffectedFilter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test stops the watch mode with the interrupt signal")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestWatchRestartFromAffectedFilter", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	runsPath := filepath.Join(tmpDir, "runs.txt")
+	config := []byte(fmt.Sprintf(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"count_bp": {"runWith": "shell", "command": %q},
+				"count_rp": {"runWith": "shell", "command": %q}
+			},
+			"profiles": {
+				"default": {
+					"filters": [
+						{"filter": "count_bp", "inputs": ["BP/**"]},
+						{"filter": "count_rp", "inputs": ["RP/**/*.txt"]}
+					],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`,
+		fmt.Sprintf("echo bp >> '%s'", runsPath),
+		fmt.Sprintf("echo rp >> '%s'", runsPath)))
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- regolith.Watch("default", []string{}, true, "", false, false, false, false, "")
+	}()
+	waitForFile(runsPath, "bp\nrp\n", t)
+
+	// Only the second filter uses the changed file
+	newFile := filepath.Join(tmpDir, "packs", "RP", "new.txt")
+	if err := os.WriteFile(newFile, []byte("new"), 0644); err != nil {
+		t.Fatal("Unable to write the new file:", err)
+	}
+	waitForFile(runsPath, "bp\nrp\nrp\n", t)
+	waitForFile(filepath.Join(
+		tmpDir, "build", "regolith_test_project_rp", "new.txt"), "new", t)
+
+	stopWatch(watchErr, t)
+}
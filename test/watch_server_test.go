@@ -0,0 +1,176 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Bedrock-OSS/regolith/regolith"
+	"github.com/coder/websocket"
+)
+
+// getFreeAddress returns a local address with a free port.
+func getFreeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Unable to find a free port:", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// getBuildStatus waits until the build event server returns the status of a
+// finished build.
+func getBuildStatus(address string, t *testing.T) regolith.BuildStatus {
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		var status regolith.BuildStatus
+		response, err := http.Get("http://" + address + "/status")
+		if err == nil {
+			err = json.NewDecoder(response.Body).Decode(&status)
+			response.Body.Close()
+			if err == nil && status.LastBuild != nil && !status.Running {
+				return status
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the build status")
+	return regolith.BuildStatus{}
+}
+
+// TestWatchServe tests if "regolith watch --serve" sends the build events
+// through the WebSocket and returns the result of the last build from the
+// status endpoint.
+func TestWatchServe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test stops the watch mode with the interrupt signal")
+	}
+	defer os.Chdir(getWdOrFatal(t))
+
+	tmpDir := prepareTestDirectory("TestWatchServe", t)
+	copyFilesOrFatal(minimalProjectPath, tmpDir, t)
+	config := []byte(`{
+		"name": "regolith_test_project",
+		"author": "Bedrock-OSS",
+		"packs": {
+			"behaviorPack": "./packs/BP",
+			"resourcePack": "./packs/RP"
+		},
+		"regolith": {
+			"formatVersion": "1.4.0",
+			"filterDefinitions": {
+				"hello": {"runWith": "shell", "command": "echo hello"}
+			},
+			"profiles": {
+				"default": {
+					"filters": [{"filter": "hello"}],
+					"export": {
+						"target": "local"
+					}
+				}
+			},
+			"dataPath": "./packs/data"
+		}
+	}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.json"), config, 0644); err != nil {
+		t.Fatal("Unable to write config:", err)
+	}
+	os.Chdir(tmpDir)
+
+	address := getFreeAddress(t)
+	watchErr := make(chan error)
+	go func() {
+		watchErr <- regolith.Watch(
+			"default", []string{}, true, "", false, false, false, false, address)
+	}()
+	status := getBuildStatus(address, t)
+	if status.LastBuild.Status != "succeeded" || !status.LastBuild.Exported {
+		t.Fatalf("Unexpected result of the first build: %+v", status.LastBuild)
+	}
+	if len(status.LastBuild.Filters) != 1 || status.LastBuild.Filters[0].Filter != "hello" {
+		t.Fatalf("Unexpected filters of the first build: %+v", status.LastBuild.Filters)
+	}
+
+	// The requests for other hosts (DNS rebinding), the connections from
+	// other websites and with unsupported versions of the protocol are
+	// rejected
+	for _, test := range []struct {
+		path, host, origin, version string
+		status                      int
+	}{
+		{"/status", "rebind.example.com", "", "", http.StatusForbidden},
+		{"/events", "rebind.example.com", "", "13", http.StatusForbidden},
+		{"/events", "", "https://example.com", "13", http.StatusForbidden},
+		{"/events", "", "http://localhost.example.com:8080", "13", http.StatusForbidden},
+		{"/events", "", "http://localhost:8080", "8", http.StatusBadRequest},
+	} {
+		request, err := http.NewRequest("GET", "http://"+address+test.path, nil)
+		if err != nil {
+			t.Fatal("Unable to create the request:", err)
+		}
+		if test.host != "" {
+			request.Host = test.host
+		}
+		if test.version != "" {
+			request.Header.Set("Upgrade", "websocket")
+			request.Header.Set("Connection", "Upgrade")
+			request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			request.Header.Set("Sec-WebSocket-Version", test.version)
+		}
+		if test.origin != "" {
+			request.Header.Set("Origin", test.origin)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("Unable to send the request:", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.status {
+			t.Fatalf("Unexpected response to %s with host %q, origin %q and version %q: %s",
+				test.path, test.host, test.origin, test.version, response.Status)
+		}
+	}
+
+	// Connect to the WebSocket
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws://"+address+"/events", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"http://127.0.0.1:8080"}},
+	})
+	if err != nil {
+		t.Fatal("Unable to connect to the build event server:", err)
+	}
+	defer conn.CloseNow()
+
+	// Trigger a build
+	newFile := filepath.Join(tmpDir, "packs", "BP", "new.txt")
+	if err := os.WriteFile(newFile, []byte("new"), 0644); err != nil {
+		t.Fatal("Unable to write the new file:", err)
+	}
+	var types []string
+	for !slices.Contains(types, "finished") {
+		_, message, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Unable to read the build events %v: %s", types, err)
+		}
+		var event regolith.BuildEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			t.Fatalf("Invalid build event %s: %s", message, err)
+		}
+		types = append(types, event.Type)
+	}
+	expected := []string{"started", "filterStarted", "filterFinished", "exported", "finished"}
+	if !slices.Equal(types, expected) {
+		t.Fatalf("Unexpected build events.\nExpected: %v\nActual: %v", expected, types)
+	}
+
+	stopWatch(watchErr, t)
+}